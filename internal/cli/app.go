@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/efureev/parallel/internal/buildinfo"
@@ -63,6 +64,23 @@ type runPlan struct {
 	flow        flow.Flow
 	keepGoing   bool
 	maxParallel int
+	timestamp   flow.TimestampMode
+
+	// socketKey опознаёт запуск для `parallel ctl`: из него выводится путь
+	// к управляющему сокету.
+	socketKey string
+
+	// configPath — файл, из которого собран план; пуст в режиме ad-hoc.
+	configPath string
+
+	// configFiles — configPath вместе с файлами из include и extends. За
+	// ними следит перечитывание конфигурации.
+	configFiles []string
+
+	// chains и except — отбор цепочек: аргументы командной строки, сведённые
+	// с профилем.
+	chains []string
+	except []string
 }
 
 // loadFlow собирает план: либо из команд, переданных после `--`, либо из файла
@@ -72,7 +90,15 @@ func loadFlow(flags *Config, logger ui.Logger) (runPlan, error) {
 		adHoc, err := config.AdHoc(flags.AdHoc)
 
 		// Файла нет, значит и ключа failFast быть не может — решает только флаг.
-		return runPlan{flow: adHoc, keepGoing: resolveKeepGoing(flags, nil), maxParallel: flags.Jobs}, err
+		return runPlan{
+			flow:        adHoc,
+			keepGoing:   resolveKeepGoing(flags, nil),
+			maxParallel: flags.Jobs,
+			timestamp:   flags.Timestamp,
+			socketKey:   socketKey(""),
+			chains:      flags.Chains,
+			except:      flags.Except,
+		}, err
 	}
 
 	resolved, err := resolveConfigPath(flags.ConfigFilePath, logger)
@@ -93,15 +119,47 @@ func loadFlow(flags *Config, logger ui.Logger) (runPlan, error) {
 		logger.Warn(hint)
 	}
 
+	configData, profile, err := configData.WithProfile(flags.Profile)
+	if err != nil {
+		logger.Error(err, "Failed to apply profile")
+
+		return runPlan{}, err
+	}
+
+	if flags.Profile != "" {
+		logger.Debug("Using profile", ui.F("profile", flags.Profile))
+	}
+
 	built, err := config.NewFlowBuilder().Build(configData)
+	chains, except := selection(flags, profile)
 
 	return runPlan{
 		flow:        built,
 		keepGoing:   resolveKeepGoing(flags, configData.FailFast),
 		maxParallel: resolveJobs(flags, configData.MaxParallel),
+		timestamp:   flags.Timestamp.Or(configData.Timestamp),
+		socketKey:   socketKey(resolved),
+		configPath:  resolved,
+		configFiles: configData.Files,
+		chains:      chains,
+		except:      except,
 	}, err
 }
 
+// selection сводит отбор цепочек командной строки с профилем.
+//
+// Позиционные имена заменяют список профиля, а не дополняют его: `parallel
+// -profile ci lint` значит «из CI-варианта — только lint». -except, наоборот,
+// добавляется к исключениям профиля: исключение всегда сужает запуск.
+func selection(flags *Config, profile config.Profile) (chains, except []string) {
+	chains = flags.Chains
+	if len(chains) == 0 {
+		chains = profile.Chains
+	}
+
+	return chains, append(slices.Clone(profile.Except), flags.Except...)
+}
+
 // resolveJobs сводит флаг -jobs и ключ maxParallel: явный флаг сильнее файла,
 // как и у остальных настроек запуска.
 func resolveJobs(flags *Config, maxParallel int) int {
@@ -150,7 +208,7 @@ func initializeApp(flags *Config, logger ui.Logger) (*runPlan, error) {
 
 	// Отбор идёт до валидации команд: она обязана относиться к тому, что
 	// реально запустится. Предшественники подтягиваются самим Select.
-	result, err = flow.Select(result, flags.Chains, flags.Except)
+	result, err = flow.Select(result, plan.chains, plan.except)
 	if err != nil {
 		logger.Error(err, "Invalid chain selection")
 
@@ -217,9 +275,40 @@ func managerOptions(flags *Config, plan *runPlan) []runner.Option {
 		opts = append(opts, runner.WithMaxParallel(plan.maxParallel))
 	}
 
+	if plan.timestamp.Enabled() {
+		opts = append(opts, runner.WithTimestamp(plan.timestamp))
+	}
+
+	if len(plan.flow.Before) > 0 {
+		opts = append(opts, runner.WithBefore(plan.flow.Before))
+	}
+
+	if len(plan.flow.OnExit) > 0 {
+		opts = append(opts, runner.WithOnExit(plan.flow.OnExit))
+	}
+
 	return opts
 }
 
+// openEventStream открывает поток событий, если он запрошен флагом -events.
+//
+// Неоткрываемый поток — ошибка запуска, а не предупреждение: обёртка, которая
+// просила события, без них работать не сможет, и узнать об этом лучше сразу.
+func openEventStream(flags *Config, logger ui.Logger) (*eventStream, error) {
+	if flags.Events == "" {
+		return nil, nil //nolint:nilnil // отсутствие потока — не ошибка
+	}
+
+	events, err := openEvents(flags.Events, logger)
+	if err != nil {
+		logger.Error(err, "Failed to open event stream")
+
+		return nil, err
+	}
+
+	return events, nil
+}
+
 // runApplication поднимает конфигурацию, запускает выполнение и обслуживает
 // сигналы завершения.
 func runApplication(
@@ -240,14 +329,90 @@ func runApplication(
 		return nil
 	}
 
-	manager := runner.NewManager(logger, formatter, managerOptions(flags, plan)...)
+	opts := managerOptions(flags, plan)
+
+	events, err := openEventStream(flags, logger)
+	if err != nil {
+		return err
+	}
+
+	var sinks eventSinks
+
+	if events != nil {
+		defer func() { _ = events.Close() }()
+
+		sinks = append(sinks, events)
+	}
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	// С полноэкранным видом запуск пишет в него, а сводка и отчёты — снова в
+	// обычный логгер: к их печати экран уже освобождён.
+	view, runLogger, err := startTUI(flags, plan, logger, cancel)
+	if err != nil {
+		return err
+	}
+
+	if view != nil {
+		defer func() { _ = view.Close() }()
+
+		sinks = append(sinks, newTUIEvents(view))
+	}
+
+	if len(sinks) > 0 {
+		opts = append(opts, runner.WithEvents(sinks))
+	}
+
+	manager := runner.NewManager(runLogger, formatter, opts...)
+
+	stopControl := serveControl(ctx, flags, plan, manager, runLogger)
+	defer stopControl()
+
+	// Клавиши полноэкранного вида читает сам вид; построчный режим на том же
+	// вводе отнимал бы у него нажатия.
+	if view == nil {
+		startKeyboard(ctx, plan, manager, runLogger)
+	}
+
+	watchConfig(ctx, flags, plan, manager, runLogger)
+
 	// Лестница реакций на сигналы: вежливо → жёстко → немедленно.
 	// Наблюдатель завершается вместе с ctx, а не живёт до конца процесса.
-	go watchSignals(ctx, sigCh, signalHandler{
+	go watchSignals(ctx, sigCh, shutdownLadder(manager, cancel, runLogger, view))
+
+	done := execute(ctx, manager, plan, view)
+	waitErr := waitForCompletion(ctx, done, runLogger, shutdownGrace(plan.flow))
+
+	// Запуск, не уложившийся в срок остановки, экран ещё держит.
+	_ = view.Close()
+
+	// Сводка печатается и при отказе, и при остановке по сигналу: именно тогда
+	// она и нужна — понять, какая из цепочек не доехала.
+	results := manager.Results()
+	ui.PrintSummary(logger, summaryRows(results, ctx.Err() != nil))
+
+	// Отчёт пишется при любом исходе: в CI он нужнее всего как раз после отказа.
+	// Его собственная ошибка не должна заслонять отказ запуска — код возврата
+	// обязан говорить о командах, а не о файле отчёта.
+	reportErr := writeReports(flags.Reports, reportChains(results, ctx.Err() != nil), logger)
+
+	if waitErr != nil {
+		return waitErr
+	}
+
+	if reportErr != nil {
+		return reportErr
+	}
+
+	logger.Debug("App Finished")
+
+	return nil
+}
+
+// shutdownLadder собирает реакции на первый, второй и третий сигнал.
+func shutdownLadder(manager *runner.Manager, cancel func(), logger ui.Logger, view *ui.TUI) signalHandler {
+	return signalHandler{
 		onFirst: func(sig os.Signal) {
 			logger.Info("Shutdown signal received", ui.F("signal", sig.String()))
 			manager.SetShutdownSignal(sig)
@@ -258,30 +423,31 @@ func runApplication(
 			manager.KillAll()
 		},
 		exit: func(code int) {
+			// os.Exit не выполняет defer: терминал, оставленный в посимвольном
+			// режиме и на альтернативном экране, пришлось бы чинить руками.
+			_ = view.Close()
+
 			logger.Warn("Third signal received, exiting immediately")
 			os.Exit(code)
 		},
-	})
+	}
+}
 
+// execute запускает цепочки и возвращает канал с итогом запуска.
+func execute(ctx context.Context, manager *runner.Manager, plan *runPlan, view *ui.TUI) <-chan error {
 	done := make(chan error, 1)
 
 	go func() {
-		done <- manager.ExecuteParallel(ctx, plan.flow.Chains)
-	}()
+		err := manager.ExecuteParallel(ctx, plan.flow.Chains)
 
-	waitErr := waitForCompletion(ctx, done, logger)
-
-	// Сводка печатается и при отказе, и при остановке по сигналу: именно тогда
-	// она и нужна — понять, какая из цепочек не доехала.
-	ui.PrintSummary(logger, summaryRows(manager.Results(), ctx.Err() != nil))
-
-	if waitErr != nil {
-		return waitErr
-	}
+		// Экран освобождается до итоговых сообщений: отказ запуска, оставшийся
+		// на альтернативном экране, пропал бы вместе с ним.
+		_ = view.Close()
 
-	logger.Debug("App Finished")
+		done <- err
+	}()
 
-	return nil
+	return done
 }
 
 // summaryRows переводит исход цепочек в строки сводки.
@@ -292,35 +458,64 @@ func summaryRows(results []runner.ChainResult, interrupted bool) []ui.SummaryRow
 	rows := make([]ui.SummaryRow, 0, len(results))
 
 	for _, res := range results {
-		row := ui.SummaryRow{Name: res.Name, Status: ui.StatusOK, Duration: res.Duration}
-
-		switch {
-		case res.Skipped:
-			// «Не начинали» и «оборвали» — разные вещи, и в сводке их надо
-			// различать: первое означает невыполненное условие, а не сбой.
-			row.Status = ui.StatusSkipped
-			if res.Err != nil {
-				row.Reason = res.Err.Error()
-			}
-		case errors.Is(res.Err, runner.ErrCommandTimeout):
-			// Проверяется раньше общего отказа: таймаут это тоже отказ, но
-			// причина у него своя, и в сводке она важнее самого факта.
-			row.Status, row.Reason = ui.StatusTimedOut, res.Err.Error()
-		case res.Failed():
-			row.Status, row.Reason = ui.StatusFailed, res.Err.Error()
-		case res.Stopped || interrupted:
-			// Цепочка не упала, но и до конца не дошла: её остановил сигнал.
-			row.Status = ui.StatusStopped
+		rows = append(rows, summaryRow(res.Name, res.Duration, res.Err, res.Stopped || interrupted, res.Skipped))
+	}
+
+	return rows
+}
+
+// summaryRow решает статус и причину одной строки — цепочки или команды.
+func summaryRow(name string, d time.Duration, err error, stopped, skipped bool) ui.SummaryRow {
+	row := ui.SummaryRow{Name: name, Status: ui.StatusOK, Duration: d}
+
+	switch {
+	case skipped:
+		// «Не начинали» и «оборвали» — разные вещи, и в сводке их надо
+		// различать: первое означает невыполненное условие, а не сбой.
+		row.Status = ui.StatusSkipped
+		if err != nil {
+			row.Reason = err.Error()
 		}
+	case errors.Is(err, runner.ErrCommandTimeout):
+		// Проверяется раньше общего отказа: таймаут это тоже отказ, но
+		// причина у него своя, и в сводке она важнее самого факта.
+		row.Status, row.Reason = ui.StatusTimedOut, err.Error()
+	case err != nil:
+		row.Status, row.Reason = ui.StatusFailed, err.Error()
+	case stopped:
+		// Цепочка не упала, но и до конца не дошла: её остановил сигнал.
+		row.Status = ui.StatusStopped
+	}
 
-		rows = append(rows, row)
+	return row
+}
+
+// shutdownGrace — сколько ждать запуск после сигнала.
+//
+// Уборка идёт уже после остановки: сначала finally цепочек, все разом, затем
+// onExit. Срок остановки к ним добавляется, иначе выход по его истечении
+// обрывал бы `docker compose down` на полуслове — ровно то, ради чего
+// уборку переносили из shell-обёртки с trap.
+func shutdownGrace(f flow.Flow) time.Duration {
+	grace := shutdownGraceTimeout
+
+	for _, chain := range f.Chains {
+		if len(chain.Finally) > 0 {
+			grace += flow.DefaultTeardownTimeout
+
+			break
+		}
 	}
 
-	return rows
+	if len(f.OnExit) > 0 {
+		grace += flow.DefaultTeardownTimeout
+	}
+
+	return grace
 }
 
 // waitForCompletion ждёт окончания выполнения либо отмены по сигналу.
-func waitForCompletion(ctx context.Context, done <-chan error, logger ui.Logger) error {
+func waitForCompletion(ctx context.Context, done <-chan error, logger ui.Logger, grace time.Duration) error {
 	select {
 	case err := <-done:
 		if err != nil {
@@ -336,7 +531,7 @@ func waitForCompletion(ctx context.Context, done <-chan error, logger ui.Logger)
 	case <-ctx.Done():
 		logger.Info("Shutdown signal received, waiting for commands to stop...")
 
-		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownGraceTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), grace)
 		defer cancel()
 
 		select {
@@ -356,9 +551,35 @@ func waitForCompletion(ctx context.Context, done <-chan error, logger ui.Logger)
 	}
 }
 
+// runCtlCommand выполняет `parallel ctl` и возвращает код выхода.
+func runCtlCommand(args []string) int {
+	cfg, err := ParseCtl(args)
+	if err != nil {
+		if errors.Is(err, ErrHelpRequested) {
+			return exitSuccess
+		}
+
+		log.Print(err)
+
+		return exitFailure
+	}
+
+	if err := runCtl(context.Background(), cfg, os.Stdout); err != nil {
+		log.Printf("parallel ctl: %v", err)
+
+		return exitFailure
+	}
+
+	return exitSuccess
+}
+
 // Run содержит основную логику и возвращает код выхода процесса,
 // чтобы defer-ы отработали до os.Exit.
 func Run() int {
+	if isCtlCommand(os.Args[1:]) {
+		return runCtlCommand(os.Args[2:])
+	}
+
 	flags, err := ParseFlags()
 	if err != nil {
 		// Справка уже напечатана разборщиком: это не сбой, а выполненная просьба.
@@ -381,7 +602,7 @@ func Run() int {
 	sigCh, stopNotify := notifyShutdown()
 	defer stopNotify()
 
-	outOpts := []ui.Option{ui.WithLevel(flags.LogLevel)}
+	outOpts := []ui.Option{ui.WithLevel(flags.LogLevel), ui.WithOutputMode(flags.Output)}
 	if flags.NoColor {
 		outOpts = append(outOpts, ui.WithoutColor())
 	}
@@ -5,6 +5,9 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
 )
 
 // parseArgs прогоняет ParseFlags с подменёнными аргументами командной строки.
@@ -87,10 +90,12 @@ func TestParseFlags_Except(t *testing.T) {
 		want string
 	}{
 		{name: "одна", raw: "worker", want: "worker"},
-		{name: "несколько", raw: "worker,cron", want: "worker,cron"},
-		{name: "с пробелами", raw: " worker , cron ", want: "worker,cron"},
+		{name: "несколько", raw: "worker,cron", want: "worker|cron"},
+		{name: "с пробелами", raw: " worker , cron ", want: "worker|cron"},
 		{name: "пусто", raw: "", want: ""},
 		{name: "только запятые", raw: ",,", want: ""},
+		{name: "вариант матрицы", raw: "test[go=1.20,db=pg],api", want: "test[go=1.20,db=pg]|api"},
+		{name: "вариант и тег", raw: "@slow, test[go=1.21,db=mysql] ", want: "@slow|test[go=1.21,db=mysql]"},
 	}
 
 	for _, tt := range tests {
@@ -100,7 +105,7 @@ func TestParseFlags_Except(t *testing.T) {
 				t.Fatalf("parse: %v", err)
 			}
 
-			if got := strings.Join(cfg.Except, ","); got != tt.want {
+			if got := strings.Join(cfg.Except, "|"); got != tt.want {
 				t.Errorf("except = %q, ожидалось %q", got, tt.want)
 			}
 		})
@@ -117,3 +122,61 @@ func TestParseFlags_Modes(t *testing.T) {
 		t.Errorf("режимы не разобраны: %+v", cfg)
 	}
 }
+
+// TestParseFlags_Output — режим вывода по умолчанию текстовый, неизвестный — ошибка.
+func TestParseFlags_Output(t *testing.T) {
+	cfg, err := parseArgs(t)
+	if err != nil || cfg.Output != ui.OutputText {
+		t.Fatalf("по умолчанию ожидался text, получено %q, %v", cfg.Output, err)
+	}
+
+	cfg, err = parseArgs(t, "-output", "json")
+	if err != nil || cfg.Output != ui.OutputJSON {
+		t.Fatalf("ожидался json, получено %v, %v", cfg, err)
+	}
+
+	if _, err := parseArgs(t, "-output", "yaml"); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного режима")
+	}
+}
+
+// TestParseFlags_Timestamp — без флага режим пуст и решение за конфигурацией,
+// неизвестное значение отвергается при разборе.
+func TestParseFlags_Timestamp(t *testing.T) {
+	cfg, err := parseArgs(t)
+	if err != nil || cfg.Timestamp != "" {
+		t.Fatalf("без флага режим должен быть пуст, получено %q, %v", cfg.Timestamp, err)
+	}
+
+	cfg, err = parseArgs(t, "-timestamp", "elapsed")
+	if err != nil || cfg.Timestamp != flow.TimestampElapsed {
+		t.Fatalf("ожидался elapsed, получено %v, %v", cfg, err)
+	}
+
+	// Пакет flag оборачивает ошибку значения через %v, поэтому только текст.
+	if _, err := parseArgs(t, "-timestamp", "unix"); err == nil || !strings.Contains(err.Error(), "allowed") {
+		t.Fatalf("ожидалась ошибка со списком допустимых режимов, получено %v", err)
+	}
+}
+
+// TestParseFlags_UI — полноэкранный вид сам показывает вывод команд, и
+// JSON-строки или группы поверх него — ошибка разбора, а не тихий выбор.
+func TestParseFlags_UI(t *testing.T) {
+	cfg, err := parseArgs(t)
+	if err != nil || cfg.UI != ui.ViewPlain {
+		t.Fatalf("по умолчанию ожидался plain, получено %q, %v", cfg.UI, err)
+	}
+
+	cfg, err = parseArgs(t, "-ui", "tui")
+	if err != nil || cfg.UI != ui.ViewTUI {
+		t.Fatalf("ожидался tui, получено %v, %v", cfg, err)
+	}
+
+	if _, err := parseArgs(t, "-ui", "tui", "-output", "json"); !errors.Is(err, ErrTUIWithOutput) {
+		t.Fatalf("ожидалась ErrTUIWithOutput, получено %v", err)
+	}
+
+	if _, err := parseArgs(t, "-ui", "curses"); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного вида")
+	}
+}
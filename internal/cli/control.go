@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/efureev/parallel/internal/control"
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// controlHandler исполняет запросы управляющего сокета против менеджера.
+//
+// Переходник живёт здесь, а не в runner или control: связывать слои — работа
+// cli, а сами они друг о друге не знают.
+type controlHandler struct {
+	manager *runner.Manager
+}
+
+func (h controlHandler) Status() []control.Chain {
+	statuses := h.manager.Status()
+	out := make([]control.Chain, 0, len(statuses))
+
+	for _, st := range statuses {
+		c := control.Chain{Name: st.Name, State: string(st.State), Since: st.Since, Starts: st.Starts}
+		for _, p := range st.Processes {
+			c.Processes = append(c.Processes, control.Process{Command: p.Command, PID: p.PID})
+		}
+
+		out = append(out, c)
+	}
+
+	return out
+}
+
+func (h controlHandler) Start(chain string) error   { return h.manager.StartChain(chain) }
+func (h controlHandler) Stop(chain string) error    { return h.manager.StopChain(chain) }
+func (h controlHandler) Restart(chain string) error { return h.manager.RestartChain(chain) }
+func (h controlHandler) Mute(chain string) error    { return h.manager.MuteChain(chain) }
+func (h controlHandler) Unmute(chain string) error  { return h.manager.UnmuteChain(chain) }
+func (h controlHandler) Solo(chain string) error    { return h.manager.SoloChain(chain) }
+func (h controlHandler) Unsolo(chain string) error  { return h.manager.UnsoloChain(chain) }
+func (h controlHandler) Include(p string) error     { return h.manager.IncludeLines(p) }
+func (h controlHandler) Exclude(p string) error     { return h.manager.ExcludeLines(p) }
+
+func (h controlHandler) Filter() control.Filter {
+	st := h.manager.OutputFilter()
+
+	return control.Filter{Muted: st.Muted, Solo: st.Solo, Include: st.Include, Exclude: st.Exclude}
+}
+
+// serveControl открывает управляющий сокет запуска и возвращает функцию,
+// которая его закрывает.
+//
+// Сокет — удобство, а не условие запуска: если открыть его не вышло, запуск
+// идёт как раньше, а пользователь видит предупреждение.
+func serveControl(
+	ctx context.Context, flags *Config, plan *runPlan, manager *runner.Manager, logger ui.Logger,
+) func() {
+	path := flags.SocketPath
+	if path == "" {
+		path = control.SocketPath(plan.socketKey)
+	}
+
+	srv, err := control.Listen(path)
+	if err != nil {
+		logger.Warn("Control socket is not available", ui.F("error", err.Error()))
+
+		return func() {}
+	}
+
+	// Сокет открывает каждый запуск, в том числе в CI и с -output json:
+	// строка на Info добавилась бы к выводу всех существующих пайплайнов.
+	logger.Debug("Control socket is listening", ui.F("path", srv.Path()))
+
+	done := make(chan struct{})
+
+	go func() {
+		srv.Serve(ctx, controlHandler{manager: manager})
+		close(done)
+	}()
+
+	return func() {
+		_ = srv.Close()
+		<-done
+	}
+}
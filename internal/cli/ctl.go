@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/efureev/parallel/internal/config"
+	"github.com/efureev/parallel/internal/control"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// ctlCommand — подкоманда управления живым запуском.
+const ctlCommand = "ctl"
+
+// ctlTimeout ограничивает ожидание ответа запуска. Restart отвечает сразу,
+// не дожидаясь остановки процессов, так что долго ждать тут нечего.
+const ctlTimeout = 10 * time.Second
+
+// ErrCtlUsage — у `parallel ctl` не та операция или не то число аргументов.
+var ErrCtlUsage = errors.New("usage: parallel ctl [-f <path>] [-socket <path>] " +
+	"<status|start|stop|restart|mute|unmute|solo|unsolo> [chain] | <include|exclude> [regex]")
+
+// CtlConfig — разобранные аргументы `parallel ctl`.
+type CtlConfig struct {
+	// ConfigFilePath и SocketPath указывают, к какому запуску обращаться; оба
+	// пусты — запуск ищется по конфигурации текущего каталога, как у parallel.
+	ConfigFilePath string
+	SocketPath     string
+
+	Op    string
+	Chain string
+	// Pattern — регулярное выражение include и exclude.
+	Pattern string
+}
+
+// ctlUsage печатает справку подкоманды.
+func ctlUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprint(fs.Output(), `parallel ctl — control a running parallel session.
+
+Usage:
+  parallel ctl [flags] status
+  parallel ctl [flags] <start|stop|restart> <chain>
+  parallel ctl [flags] <mute|solo> <chain>
+  parallel ctl [flags] <unmute|unsolo> [chain]
+  parallel ctl [flags] <include|exclude> [regex]
+
+Flags:
+  -f <path>          configuration file of the run; found the same way as by parallel
+  -socket <path>     control socket of the run (overrides -f)
+
+Examples:
+  parallel ctl status                   # what is running, since when, with which PIDs
+  parallel ctl restart api              # bounce one chain, leave the rest running
+  parallel ctl stop worker              # stop one chain; 'start worker' brings it back
+  parallel ctl mute worker              # hide its output; readiness and log files still see it
+  parallel ctl exclude 'GET /health'    # hide matching lines; 'exclude' alone clears it
+`)
+	}
+}
+
+// isCtlCommand сообщает, просят ли аргументы подкоманду ctl.
+//
+// До v1.0 `parallel ctl` запускал цепочку ctl, и позиционный интерфейс
+// заморожен: цепочка с этим именем в найденной конфигурации побеждает.
+// Подкоманда тогда доступна с явным адресом запуска — `parallel ctl -f
+// <path> status`: флаг после имени цепочки раньше был лишь ошибкой.
+func isCtlCommand(args []string) bool {
+	if len(args) == 0 || args[0] != ctlCommand {
+		return false
+	}
+
+	if len(args) > 1 && strings.HasPrefix(args[1], "-") {
+		return true
+	}
+
+	return !configDefinesChain(ctlCommand)
+}
+
+// configDefinesChain сообщает, есть ли цепочка в конфигурации текущего
+// каталога. Ненайденная или сломанная конфигурация цепочки не определяет:
+// об ошибке в ней скажет уже сама подкоманда или запуск.
+func configDefinesChain(name string) bool {
+	path, err := resolveConfigPath("", ui.NewDiscardLogger())
+	if err != nil {
+		return false
+	}
+
+	data, err := config.NewFileLoader(config.YamlFileMarshaller{}).Load(path)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(data.Chains, func(chain config.ChainConfig) bool { return chain.Name == name })
+}
+
+// ParseCtl разбирает аргументы, идущие после `parallel ctl`.
+func ParseCtl(args []string, opts ...Option) (*CtlConfig, error) {
+	fs := flag.NewFlagSet("parallel ctl", flag.ContinueOnError)
+	fs.Usage = ctlUsage(fs)
+
+	var cfg CtlConfig
+
+	fs.StringVar(&cfg.ConfigFilePath, "f", "", "Configuration file of the run")
+	fs.StringVar(&cfg.SocketPath, "socket", "", "Control socket of the run")
+
+	for _, opt := range opts {
+		opt(fs)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, ErrHelpRequested
+		}
+
+		return nil, fmt.Errorf("parsing flags: %w", err)
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return nil, ErrCtlUsage
+	}
+
+	cfg.Op = rest[0]
+
+	switch {
+	case len(rest) == 1:
+	case cfg.Op == control.OpInclude || cfg.Op == control.OpExclude:
+		cfg.Pattern = rest[1]
+	default:
+		cfg.Chain = rest[1]
+	}
+
+	// Лишний аргумент — скорее всего попытка перезапустить несколько цепочек
+	// разом. Молча взять первую значило бы сделать не то, что просили.
+	if len(rest) > 2 || (cfg.Op == control.OpStatus && cfg.Chain != "") {
+		return nil, ErrCtlUsage
+	}
+
+	if err := cfg.request().Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %q\n%w", err, cfg.Op, ErrCtlUsage)
+	}
+
+	return &cfg, nil
+}
+
+// request — запрос к запуску, который описывает конфигурация.
+func (cfg *CtlConfig) request() control.Request {
+	return control.Request{Op: cfg.Op, Chain: cfg.Chain, Pattern: cfg.Pattern}
+}
+
+// socketKey возвращает ключ, из которого выводится путь к сокету запуска:
+// абсолютный путь к конфигурации, а без неё — текущий каталог.
+//
+// Один и тот же вывод и у запуска, и у `parallel ctl`: только так второй
+// находит первого без флагов.
+func socketKey(configPath string) string {
+	if configPath != "" {
+		if abs, err := filepath.Abs(configPath); err == nil {
+			return abs
+		}
+
+		return configPath
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	return cwd
+}
+
+// ctlSocketPath определяет сокет запуска, к которому обращается ctl.
+func ctlSocketPath(cfg *CtlConfig) (string, error) {
+	if cfg.SocketPath != "" {
+		return cfg.SocketPath, nil
+	}
+
+	if cfg.ConfigFilePath != "" {
+		return control.SocketPath(socketKey(cfg.ConfigFilePath)), nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("determining current directory: %w", err)
+	}
+
+	// Конфигурации нет — значит, это запуск ad-hoc из текущего каталога.
+	found, err := config.Discover(cwd)
+	if err != nil {
+		return control.SocketPath(cwd), nil //nolint:nilerr // отсутствие файла — штатный случай ad-hoc
+	}
+
+	return control.SocketPath(socketKey(found)), nil
+}
+
+// runCtl отправляет запрос запуску и печатает ответ.
+func runCtl(ctx context.Context, cfg *CtlConfig, out io.Writer) error {
+	path, err := ctlSocketPath(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ctlTimeout)
+	defer cancel()
+
+	resp, err := control.Call(ctx, path, cfg.request())
+	if err != nil {
+		return err
+	}
+
+	if err := printChains(out, resp.Chains, time.Now()); err != nil {
+		return err
+	}
+
+	if resp.Filter != nil {
+		fmt.Fprintln(out, "\nOutput filter: "+describeFilter(*resp.Filter))
+	}
+
+	return nil
+}
+
+// describeFilter описывает фильтр вывода одной строкой.
+func describeFilter(f control.Filter) string {
+	var parts []string
+
+	if len(f.Solo) > 0 {
+		parts = append(parts, "solo "+strings.Join(f.Solo, ", "))
+	}
+
+	if len(f.Muted) > 0 {
+		parts = append(parts, "muted "+strings.Join(f.Muted, ", "))
+	}
+
+	if f.Include != "" {
+		parts = append(parts, "include "+strconv.Quote(f.Include))
+	}
+
+	if f.Exclude != "" {
+		parts = append(parts, "exclude "+strconv.Quote(f.Exclude))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// printChains печатает состояние цепочек таблицей.
+func printChains(out io.Writer, chains []control.Chain, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CHAIN\tSTATE\tFOR\tSTARTS\tPROCESSES")
+
+	for _, c := range chains {
+		procs := make([]string, 0, len(c.Processes))
+		for _, p := range c.Processes {
+			procs = append(procs, p.Command+" ("+strconv.Itoa(p.PID)+")")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			c.Name, c.State, now.Sub(c.Since).Round(time.Second), c.Starts, strings.Join(procs, ", "))
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("printing status: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/control"
+)
+
+func TestParseCtl(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    CtlConfig
+		wantErr error
+	}{
+		{name: "status", args: []string{"status"}, want: CtlConfig{Op: control.OpStatus}},
+		{
+			name: "restart с конфигурацией",
+			args: []string{"-f", "dev.yaml", "restart", "api"},
+			want: CtlConfig{ConfigFilePath: "dev.yaml", Op: control.OpRestart, Chain: "api"},
+		},
+		{
+			name: "явный сокет",
+			args: []string{"-socket", "/tmp/p.sock", "stop", "worker"},
+			want: CtlConfig{SocketPath: "/tmp/p.sock", Op: control.OpStop, Chain: "worker"},
+		},
+		{name: "заглушка", args: []string{"mute", "worker"}, want: CtlConfig{Op: control.OpMute, Chain: "worker"}},
+		{name: "заглушка снимается со всех", args: []string{"unmute"}, want: CtlConfig{Op: control.OpUnmute}},
+		{
+			name: "шаблон строк",
+			args: []string{"exclude", "GET /health"},
+			want: CtlConfig{Op: control.OpExclude, Pattern: "GET /health"},
+		},
+		{name: "заглушка без цепочки", args: []string{"mute"}, wantErr: control.ErrChainRequired},
+		{name: "без операции", args: nil, wantErr: ErrCtlUsage},
+		{name: "без цепочки", args: []string{"restart"}, wantErr: control.ErrChainRequired},
+		{name: "неизвестная операция", args: []string{"reload", "api"}, wantErr: control.ErrUnknownOp},
+		{name: "несколько цепочек", args: []string{"restart", "api", "ui"}, wantErr: ErrCtlUsage},
+		{name: "status с цепочкой", args: []string{"status", "api"}, wantErr: ErrCtlUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseCtl(tt.args)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ожидалось %v, получено %v", tt.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseCtl: %v", err)
+			}
+
+			if *cfg != tt.want {
+				t.Fatalf("разобрано %+v, ожидалось %+v", *cfg, tt.want)
+			}
+		})
+	}
+}
+
+// TestSocketKey_SameForRunAndCtl — запуск и ctl обязаны вывести один путь,
+// как бы ни был записан путь к конфигурации.
+func TestSocketKey_SameForRunAndCtl(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if socketKey("dev.yaml") != socketKey(dir+"/dev.yaml") {
+		t.Fatal("относительный и абсолютный путь к одной конфигурации дали разные ключи")
+	}
+
+	cfg := &CtlConfig{ConfigFilePath: "dev.yaml", Op: control.OpStatus}
+
+	path, err := ctlSocketPath(cfg)
+	if err != nil {
+		t.Fatalf("ctlSocketPath: %v", err)
+	}
+
+	if path != control.SocketPath(socketKey(dir+"/dev.yaml")) {
+		t.Fatalf("ctl ищет сокет не там, где его открывает запуск: %s", path)
+	}
+}
+
+// TestIsCtlCommand — цепочка ctl из конфигурации запускается, как до
+// появления подкоманды; подкоманда тогда доступна с явным адресом запуска.
+func TestIsCtlCommand(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	path := filepath.Join(dir, ".parallelrc.yaml")
+	writeConfig(t, path, "commands:\n  api:\n    serve: { cmd: ['go'] }\n")
+
+	if !isCtlCommand([]string{"ctl", "status"}) || isCtlCommand([]string{"api", "ctl"}) {
+		t.Fatal("без цепочки ctl первое слово ctl — подкоманда, и только первое")
+	}
+
+	writeConfig(t, path, "commands:\n  ctl:\n    serve: { cmd: ['go'] }\n")
+
+	if isCtlCommand([]string{"ctl"}) || isCtlCommand([]string{"ctl", "status"}) {
+		t.Fatal("цепочка ctl должна запускаться, а не уступать подкоманде")
+	}
+
+	if !isCtlCommand([]string{"ctl", "-f", path, "status"}) {
+		t.Fatal("с явным адресом запуска ctl — подкоманда")
+	}
+}
+
+func TestPrintChains(t *testing.T) {
+	now := time.Now()
+	chains := []control.Chain{
+		{
+			Name: "api", State: "running", Since: now.Add(-90 * time.Second), Starts: 2,
+			Processes: []control.Process{{Command: "serve", PID: 4242}},
+		},
+		{Name: "migrate", State: "done", Since: now, Starts: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := printChains(&buf, chains, now); err != nil {
+		t.Fatalf("printChains: %v", err)
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{"CHAIN", "api", "running", "1m30s", "serve (4242)", "migrate", "done"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("в выводе нет %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// eventRecord — событие в том виде, в каком оно уходит в поток.
+//
+// Отдельный тип, а не теги на runner.Event: формат потока — обещание внешним
+// потребителям, и переименование поля в runner не должно его ломать.
+type eventRecord struct {
+	TS       string `json:"ts"`
+	Event    string `json:"event"`
+	Chain    string `json:"chain,omitempty"`
+	Command  string `json:"command,omitempty"`
+	PID      int    `json:"pid,omitempty"`
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Attempt  int    `json:"attempt,omitempty"`
+	DelayMS  int64  `json:"delayMs,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	File     string `json:"file,omitempty"`
+	Failures int    `json:"failures,omitempty"`
+	Signal   string `json:"signal,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func recordOf(ev runner.Event) eventRecord {
+	return eventRecord{
+		TS:       ev.Time.UTC().Format(time.RFC3339Nano),
+		Event:    string(ev.Kind),
+		Chain:    ev.Chain,
+		Command:  ev.Command,
+		PID:      ev.PID,
+		ExitCode: ev.ExitCode,
+		Attempt:  ev.Attempt,
+		DelayMS:  ev.Delay.Milliseconds(),
+		Reason:   ev.Reason,
+		File:     ev.File,
+		Failures: ev.Failures,
+		Signal:   ev.Signal,
+		Status:   string(ev.Status),
+		Error:    ev.Error,
+	}
+}
+
+// eventStream пишет события запуска по одному JSON-объекту на строку.
+//
+// Отказ записи запуск не останавливает: потребитель потока мог уйти, а
+// сервисы от этого работать не перестают. О первом отказе говорится
+// предупреждением, дальше поток молчит.
+type eventStream struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	logger ui.Logger
+	failed bool
+}
+
+// Emit кодирует событие одной записью: json.Encoder пишет объект вместе с
+// переводом строки одним вызовом Write, и строки потока не перемешиваются.
+func (s *eventStream) Emit(ev runner.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed {
+		return
+	}
+
+	if err := json.NewEncoder(s.w).Encode(recordOf(ev)); err != nil {
+		s.failed = true
+
+		s.logger.Warn("Event stream is not writable, events are dropped", ui.F("error", err.Error()))
+	}
+}
+
+// Close закрывает файл потока; чужие дескрипторы не закрываются.
+func (s *eventStream) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// openEvents открывает поток событий: число — уже открытый дескриптор
+// (`-events 3` вместе с `3>events.jsonl` в оболочке), иначе путь к файлу.
+//
+// Стандартные потоки не закрываются: за stdout и stderr отвечает не поток
+// событий, и закрыть их значило бы оборвать остальной вывод.
+func openEvents(target string, logger ui.Logger) (*eventStream, error) {
+	if fd, err := strconv.ParseUint(target, 10, 0); err == nil {
+		f := os.NewFile(uintptr(fd), "fd "+target)
+		if f == nil {
+			return nil, fmt.Errorf("events: invalid file descriptor %s", target)
+		}
+
+		s := &eventStream{w: f, logger: logger}
+		if fd > uint64(os.Stderr.Fd()) {
+			s.closer = f
+		}
+
+		return s, nil
+	}
+
+	//nolint:gosec // путь к потоку событий задаёт сам пользователь флагом
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("events: %w", err)
+	}
+
+	return &eventStream{w: f, closer: f, logger: logger}, nil
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+func TestEventStream_WritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	stream, err := openEvents(path, ui.NewDiscardLogger())
+	if err != nil {
+		t.Fatalf("openEvents: %v", err)
+	}
+
+	code := 2
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	stream.Emit(runner.Event{Time: at, Kind: runner.EventCommandStarted, Chain: "api", Command: "serve", PID: 42})
+	stream.Emit(runner.Event{Time: at, Kind: runner.EventCommandExited, Chain: "api", PID: 42, ExitCode: &code})
+
+	if err := stream.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	var records []map[string]any
+
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var rec map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("строка не JSON: %q: %v", sc.Text(), err)
+		}
+
+		records = append(records, rec)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("строк %d, ожидалось 2", len(records))
+	}
+
+	first := records[0]
+	if first["event"] != "command.started" || first["ts"] != "2026-01-02T03:04:05Z" || first["pid"] != 42.0 {
+		t.Errorf("первое событие: %v", first)
+	}
+
+	if _, ok := first["exitCode"]; ok {
+		t.Errorf("у запуска не должно быть кода выхода: %v", first)
+	}
+
+	if records[1]["exitCode"] != 2.0 {
+		t.Errorf("код выхода потерян: %v", records[1])
+	}
+}
+
+func TestOpenEvents_Descriptor(t *testing.T) {
+	stream, err := openEvents("1", ui.NewDiscardLogger())
+	if err != nil {
+		t.Fatalf("openEvents: %v", err)
+	}
+
+	// Стандартный поток принадлежит не потоку событий и не закрывается.
+	if stream.closer != nil {
+		t.Fatal("stdout будет закрыт вместе с потоком событий")
+	}
+}
+
+func TestParseFlags_Events(t *testing.T) {
+	cfg, err := parseArgs(t, "-events", "3")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if cfg.Events != "3" {
+		t.Errorf("events = %q", cfg.Events)
+	}
+}
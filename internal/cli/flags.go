@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/efureev/parallel/internal/flow"
 	"github.com/efureev/parallel/internal/ui"
 )
 
@@ -19,6 +20,11 @@ const defaultLogLevel = "info"
 // doubleDash отделяет команды ad-hoc от флагов и имён цепочек.
 const doubleDash = "--"
 
+// profileEnv — переменная окружения, выбирающая профиль, если -profile не задан.
+// Нужна CI: профиль выставляется один раз на весь пайплайн, а не в каждом
+// шаге, который вызывает parallel.
+const profileEnv = "PARALLEL_PROFILE"
+
 var (
 	// ErrEmptyConfigPath — флаг -f получил пустое значение.
 	ErrEmptyConfigPath = errors.New("config file path cannot be empty")
@@ -50,6 +56,8 @@ type Config struct {
 	Chains []string
 	// Except — цепочки, которые надо исключить.
 	Except []string
+	// Profile — имя профиля из конфигурации; пусто — профиль не выбран.
+	Profile string
 	// AdHoc — команды, переданные после `--`. Непусто означает запуск без
 	// файла конфигурации.
 	AdHoc []string
@@ -60,6 +68,13 @@ type Config struct {
 	DryRun bool
 	// NoColor принудительно отключает раскраску.
 	NoColor bool
+	// Output — форма вывода: цветные блоки либо JSON-строки.
+	Output ui.OutputMode
+	// UI — обычный поток строк либо полноэкранный вид с вкладками цепочек.
+	UI ui.ViewMode
+	// Timestamp — метка времени строк вывода; перекрывает верхнеуровневый
+	// format.timestamp. Пусто — флаг не передавали.
+	Timestamp flow.TimestampMode
 
 	// CommandTimeout — предел выполнения для всех команд; поле timeout
 	// у самой команды его перекрывает. Ноль означает «без предела».
@@ -76,6 +91,20 @@ type Config struct {
 	// KeepGoingSet различает «флаг не передавали» и «передали -keep-going=false».
 	// Без этого нельзя дать флагу перевесить ключ failFast из конфигурации.
 	KeepGoingSet bool
+
+	// SocketPath — путь к управляющему сокету. Пусто — путь выводится из
+	// конфигурации, и `parallel ctl` из того же проекта находит его сам.
+	SocketPath string
+
+	// NoReload отключает перечитывание конфигурации при её изменении.
+	NoReload bool
+
+	// Events — куда писать поток событий JSONL: путь к файлу либо номер
+	// открытого дескриптора. Пусто — поток не пишется.
+	Events string
+
+	// Reports — отчёты о запуске, которые надо записать по его окончании.
+	Reports []reportTarget
 }
 
 // Option позволяет донастроить разбор флагов.
@@ -93,38 +122,66 @@ func usage(fs *flag.FlagSet) func() {
 
 Usage:
   parallel [flags]
+  parallel ctl <status|start|stop|restart|mute|unmute|solo|unsolo> [chain]
+  parallel ctl <include|exclude> [regex]
 
 Flags:
   -f <path>          path to the YAML configuration file. If omitted, ".parallelrc.yaml"
                      (or ".parallelrc.yml") is looked up in the current directory and
                      every parent directory, the way git finds its config
-  -except <names>    comma-separated chains to skip
+  -except <names>    comma-separated chains or @tags to skip
+  -profile <name>    apply a profile from the configuration: its chains, env, maxParallel,
+                     failFast and command overrides (default: $PARALLEL_PROFILE)
   -list              list the chains defined in the configuration and exit
   -dry-run           show what would run and exit without starting anything
   -no-color          disable colored output (NO_COLOR is respected too)
+  -output <mode>     text (default) prints colored blocks; json prints a JSON object per line,
+                     {"ts","chain","command","stream","seq","line"} for command output;
+                     grouped prints each chain's output in one piece when the chain ends,
+                     inside GitHub Actions or GitLab CI collapsible groups when run there
+  -ui <mode>         plain (default) streams lines; tui shows a full-screen tab per chain with
+                     a status line: state, PID, uptime and restarts (q quits, arrows navigate)
+  -timestamp <mode>  prefix every output line with the time: wall, rfc3339, elapsed (since
+                     the chain started) or none (overrides format.timestamp at the top level)
   -keep-going        do not stop the other chains when one of them fails
                      (overrides failFast from the configuration file)
   -timeout <dur>     stop any command that runs longer than this, e.g. 30s or 5m
                      (a command's own 'timeout' field wins over this)
   -jobs <n>          run at most n chains at a time (overrides maxParallel)
+  -socket <path>     listen for 'parallel ctl' on this socket instead of the default one
+  -no-reload         do not apply changes of the configuration file to a running session
+  -events <path|fd>  write a JSON line per run event (chain and command lifecycle, restarts,
+                     timeouts, signals) to a file or an already open file descriptor
+  -report <fmt=file> after the run, write a report of every chain and command to a file;
+                     fmt is json or junit (JUnit XML for CI test views); may be repeated
   -log-level <level> debug, info, warn or error (default "info")
   -v, --version      show version information and exit
   -h, --help         show this help and exit
 
 Arguments:
-  [chain...]         run only the named chains; the default is all of them
+  [chain|@tag...]    run only the named chains, or every chain with the tag; the default is
+                     all of them. The chains they need are started too
   -- <cmd>...        run the given shell commands in parallel, with no config file
 
 Examples:
   parallel                              # find .parallelrc.yaml here or in a parent directory
   parallel api ui                       # run only these two chains
   parallel -except worker               # run everything but this one
+  parallel @frontend                    # chains tagged frontend and whatever they need
+  parallel -profile ci                  # the CI variant of the same configuration
   parallel -list                        # what does this configuration define?
   parallel -dry-run api                 # what exactly would 'parallel api' run?
   parallel -keep-going                  # report every failure, not just the first
   parallel -timeout 5m                  # no command may run longer than five minutes
   parallel -jobs 2                      # at most two chains running at a time
   parallel -- 'go run ./cmd/api' 'yarn dev'   # no configuration file at all
+  parallel ctl restart api              # bounce one chain of a running session
+  parallel -events 3 3>events.jsonl     # machine-readable events for a wrapper or an IDE
+  parallel -keep-going -report junit=report.xml   # lint, test and build as CI test cases
+  parallel -output json | vector        # feed a log shipper that reads JSON lines
+  parallel -timestamp wall              # which chain printed what, and when
+  parallel -output grouped lint test    # CI: one readable block per chain
+  parallel -ui tui                      # six services, six tabs instead of one blur
 
 Documentation: https://github.com/efureev/parallel
 `)
@@ -132,21 +189,54 @@ Documentation: https://github.com/efureev/parallel
 }
 
 // bindFlags объявляет все флаги утилиты.
-func bindFlags(fs *flag.FlagSet, cfg *Config, logLevel, except *string) {
+func bindFlags(fs *flag.FlagSet, cfg *Config, logLevel, except, output, view *string) {
 	fs.StringVar(&cfg.ConfigFilePath, "f", "", "Path to YAML configuration file")
 	fs.StringVar(except, "except", "", "Comma-separated chains to skip")
+	fs.StringVar(&cfg.Profile, "profile", "", "Profile from the configuration to apply")
 	fs.BoolVar(&cfg.List, "list", false, "List chains and exit")
 	fs.BoolVar(&cfg.DryRun, "dry-run", false, "Show what would run and exit")
 	fs.BoolVar(&cfg.NoColor, "no-color", false, "Disable colored output")
+	fs.StringVar(output, "output", string(ui.OutputText), "Output mode: text, json or grouped")
+	fs.StringVar(view, "ui", string(ui.ViewPlain), "Interface: plain or tui (full-screen panes)")
+	fs.Var(timestampFlag{mode: &cfg.Timestamp}, "timestamp", "Time prefix: wall, rfc3339, elapsed or none")
 	fs.BoolVar(&cfg.KeepGoing, "keep-going", false, "Do not stop other chains when one fails")
 	fs.DurationVar(&cfg.CommandTimeout, "timeout", 0, "Stop any command running longer than this")
 	fs.IntVar(&cfg.Jobs, "jobs", 0, "Run at most n chains at a time")
+	fs.StringVar(&cfg.SocketPath, "socket", "", "Control socket path")
+	fs.BoolVar(&cfg.NoReload, "no-reload", false, "Do not reload the configuration file on change")
+	fs.StringVar(&cfg.Events, "events", "", "Write JSON lines of run events to a file or descriptor")
+	fs.Var(reportFlag{targets: &cfg.Reports}, "report", "Write a json=<file> or junit=<file> run report")
 	fs.StringVar(logLevel, "log-level", defaultLogLevel, "Log level: debug, info, warn, error")
 	// Support both -v and -version flags.
 	fs.BoolVar(&cfg.VersionRequested, "v", false, "Show version information and exit")
 	fs.BoolVar(&cfg.VersionRequested, "version", false, "Show version information and exit")
 }
 
+// timestampFlag разбирает -timestamp сразу в режим: неизвестное значение
+// отвергается разборщиком флагов вместе с их справкой.
+type timestampFlag struct {
+	mode *flow.TimestampMode
+}
+
+func (f timestampFlag) String() string {
+	if f.mode == nil {
+		return ""
+	}
+
+	return string(*f.mode)
+}
+
+func (f timestampFlag) Set(value string) error {
+	mode, err := flow.ParseTimestampMode(value)
+	if err != nil {
+		return err
+	}
+
+	*f.mode = mode
+
+	return nil
+}
+
 // explicitlySet сообщает, присутствовал ли флаг в аргументах.
 func explicitlySet(fs *flag.FlagSet, name string) bool {
 	found := false
@@ -173,9 +263,9 @@ func ParseFlags(opts ...Option) (*Config, error) {
 		logLevel string
 	)
 
-	var except string
+	var except, output, view string
 
-	bindFlags(fs, &cfg, &logLevel, &except)
+	bindFlags(fs, &cfg, &logLevel, &except, &output, &view)
 
 	// Apply any custom options
 	for _, opt := range opts {
@@ -221,6 +311,20 @@ func ParseFlags(opts ...Option) (*Config, error) {
 	}
 
 	cfg.LogLevel = level
+
+	mode, err := ui.ParseOutputMode(output)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Output = mode
+
+	viewMode, err := ui.ParseViewMode(view)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.UI = viewMode
 	cfg.Chains = fs.Args()
 	cfg.Except = splitList(except)
 	cfg.KeepGoingSet = explicitlySet(fs, "keep-going")
@@ -230,15 +334,25 @@ func ParseFlags(opts ...Option) (*Config, error) {
 		return nil, err
 	}
 
+	// Переменная окружения проверяется после validate: выставленная на весь
+	// пайплайн, она не должна ломать шаг, который запускает команды после `--`.
+	if !explicitlySet(fs, "profile") && len(cfg.AdHoc) == 0 {
+		cfg.Profile = os.Getenv(profileEnv)
+	}
+
 	return &cfg, nil
 }
 
 // validate ловит взаимоисключающие сочетания аргументов.
 func (c *Config) validate() error {
-	if len(c.AdHoc) > 0 && (len(c.Chains) > 0 || len(c.Except) > 0) {
+	if len(c.AdHoc) > 0 && (len(c.Chains) > 0 || len(c.Except) > 0 || c.Profile != "") {
 		return ErrAdHocWithSelection
 	}
 
+	if c.UI == ui.ViewTUI && c.Output != ui.OutputText {
+		return ErrTUIWithOutput
+	}
+
 	return nil
 }
 
@@ -258,20 +372,39 @@ func splitAtDoubleDash(args []string) (head, tail []string) {
 }
 
 // splitList разбирает список имён, перечисленных через запятую.
+//
+// Запятая внутри квадратных скобок имя не делит: в имени варианта матрицы
+// значения осей перечисляются через неё же — `test[go=1.20,db=pg]`.
 func splitList(raw string) []string {
 	if strings.TrimSpace(raw) == "" {
 		return nil
 	}
 
-	parts := strings.Split(raw, ",")
-
-	out := make([]string, 0, len(parts))
+	var (
+		out   []string
+		depth int
+		start int
+	)
 
-	for _, p := range parts {
-		if trimmed := strings.TrimSpace(p); trimmed != "" {
+	add := func(part string) {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
 			out = append(out, trimmed)
 		}
 	}
 
+	for i, r := range raw {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case r == ',' && depth == 0:
+			add(raw[start:i])
+			start = i + 1
+		}
+	}
+
+	add(raw[start:])
+
 	return out
 }
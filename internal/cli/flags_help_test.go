@@ -1,11 +1,14 @@
 package cli
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/efureev/parallel/internal/control"
 	"github.com/efureev/parallel/internal/ui"
 )
 
@@ -81,3 +84,24 @@ func TestParseFlags_LogLevel(t *testing.T) {
 		})
 	}
 }
+
+// TestUsage_ListsCtlOperations — справка называет каждую операцию ctl, а не
+// только те, что были в первой версии.
+func TestUsage_ListsCtlOperations(t *testing.T) {
+	var buf bytes.Buffer
+
+	fs := flag.NewFlagSet("parallel", flag.ContinueOnError)
+	fs.SetOutput(&buf)
+	usage(fs)()
+
+	ops := []string{
+		control.OpStatus, control.OpStart, control.OpStop, control.OpRestart, control.OpMute,
+		control.OpUnmute, control.OpSolo, control.OpUnsolo, control.OpInclude, control.OpExclude,
+	}
+
+	for _, op := range ops {
+		if !strings.Contains(buf.String(), op+"|") && !strings.Contains(buf.String(), "|"+op) {
+			t.Errorf("справка не называет операцию %q", op)
+		}
+	}
+}
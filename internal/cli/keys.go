@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/efureev/parallel/internal/control"
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+var (
+	// ErrUnknownKey — строка с клавиатуры не похожа ни на одну команду.
+	ErrUnknownKey = errors.New("unknown keyboard command, type 'h' for help")
+	// ErrNoLastChain — `r` без имени, а перезапускать ещё ничего не просили.
+	ErrNoLastChain = errors.New("no chain to repeat, name one: r <chain>")
+)
+
+// keysHelp — подсказка, которую печатает `h`.
+const keysHelp = "r <chain> restart · s <chain> stop · start <chain> · l status · " +
+	"m <chain> mute/unmute · o <chain> solo/unsolo · i <regex> include lines · e <regex> exclude lines · " +
+	"r alone repeats the last restart; m, o, i, e alone clear · a chain is a name or its number from the preview"
+
+// keyCommand — разобранная команда с клавиатуры.
+type keyCommand struct {
+	op      string
+	chain   string
+	pattern string
+}
+
+// keyboard переводит строки, набранные в терминале, в управляющие операции.
+//
+// Режим строчный, а не посимвольный: сырой режим терминала отключил бы
+// обработку Ctrl+C ядром, и лестница сигналов перестала бы работать ровно
+// тогда, когда на неё рассчитывают. Цена — Enter после команды.
+type keyboard struct {
+	handler control.Handler
+	chains  []string
+	logger  ui.Logger
+
+	// last — цепочка последнего перезапуска: `r` без имени повторяет его.
+	last string
+}
+
+// stdinIsTerminal сообщает, набирает ли команды человек. В CI и при
+// перенаправлении ввода клавиатурный режим не включается вовсе.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// watchKeyboard читает команды из in, пока не отменён ctx или не кончился ввод.
+func (k *keyboard) watchKeyboard(ctx context.Context, in io.Reader) {
+	lines := make(chan string)
+
+	// Чтение из терминала не прерывается отменой контекста, поэтому живёт в
+	// своей горутине. Она остаётся заблокированной на чтении до выхода
+	// процесса — это дешевле, чем переводить stdin в неблокирующий режим.
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+
+			k.handle(line)
+		}
+	}
+}
+
+// handle исполняет одну строку.
+func (k *keyboard) handle(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	cmd, err := k.parse(line)
+	if err != nil {
+		k.logger.Warn("Keyboard command ignored", ui.F("input", line), ui.F("error", err.Error()))
+
+		return
+	}
+
+	switch cmd.op {
+	case "help":
+		k.logger.Info("Keyboard commands: " + keysHelp)
+	case control.OpStatus:
+		k.status()
+	default:
+		if err := k.apply(cmd); err != nil {
+			k.logger.Warn("Keyboard command failed", ui.F("chain", cmd.chain), ui.F("error", err.Error()))
+		}
+	}
+}
+
+// apply исполняет операцию над цепочкой.
+func (k *keyboard) apply(cmd keyCommand) error {
+	switch cmd.op {
+	case control.OpRestart:
+		k.last = cmd.chain
+
+		return k.handler.Restart(cmd.chain)
+	case control.OpStop:
+		return k.handler.Stop(cmd.chain)
+	case control.OpMute:
+		if cmd.chain == "" || slices.Contains(k.handler.Filter().Muted, cmd.chain) {
+			return k.handler.Unmute(cmd.chain)
+		}
+
+		return k.handler.Mute(cmd.chain)
+	case control.OpSolo:
+		if cmd.chain == "" || slices.Contains(k.handler.Filter().Solo, cmd.chain) {
+			return k.handler.Unsolo(cmd.chain)
+		}
+
+		return k.handler.Solo(cmd.chain)
+	case control.OpInclude:
+		return k.handler.Include(cmd.pattern)
+	case control.OpExclude:
+		return k.handler.Exclude(cmd.pattern)
+	default:
+		return k.handler.Start(cmd.chain)
+	}
+}
+
+// status печатает состояние цепочек в журнал: во время запуска таблица
+// разорвалась бы выводом команд, а строка журнала — нет.
+func (k *keyboard) status() {
+	for _, c := range k.handler.Status() {
+		pids := make([]string, 0, len(c.Processes))
+		for _, p := range c.Processes {
+			pids = append(pids, strconv.Itoa(p.PID))
+		}
+
+		k.logger.Info("Chain status",
+			ui.F("chain", c.Name), ui.F("state", c.State), ui.F("starts", c.Starts),
+			ui.F("pids", strings.Join(pids, ",")))
+	}
+
+	if filter := k.handler.Filter(); filter.Active() {
+		k.logger.Info("Output filter: " + describeFilter(filter))
+	}
+}
+
+// parse разбирает строку с клавиатуры.
+func (k *keyboard) parse(line string) (keyCommand, error) {
+	fields := strings.Fields(line)
+
+	var op string
+
+	switch fields[0] {
+	case "i", "include", "e", "exclude":
+		return parsePattern(line, fields[0]), nil
+	case "m", "mute":
+		op = control.OpMute
+	case "o", "solo":
+		op = control.OpSolo
+	case "r", "restart":
+		op = control.OpRestart
+	case "s", "stop":
+		op = control.OpStop
+	case "start":
+		op = control.OpStart
+	case "l", "status":
+		return keyCommand{op: control.OpStatus}, nil
+	case "h", "?", "help":
+		return keyCommand{op: "help"}, nil
+	default:
+		return keyCommand{}, ErrUnknownKey
+	}
+
+	switch len(fields) {
+	case 1:
+		// Заглушка и выбор без имени снимаются со всех цепочек разом.
+		if op == control.OpMute || op == control.OpSolo {
+			return keyCommand{op: op}, nil
+		}
+
+		if op != control.OpRestart {
+			return keyCommand{}, control.ErrChainRequired
+		}
+
+		if k.last == "" {
+			return keyCommand{}, ErrNoLastChain
+		}
+
+		return keyCommand{op: op, chain: k.last}, nil
+	case 2: //nolint:mnd // операция и цепочка
+		return keyCommand{op: op, chain: k.chainOf(fields[1])}, nil
+	default:
+		return keyCommand{}, ErrUnknownKey
+	}
+}
+
+// parsePattern разбирает команду фильтра строк. Шаблон — весь остаток
+// строки как есть: в регулярном выражении пробелы значимы.
+func parsePattern(line, word string) keyCommand {
+	op := control.OpInclude
+	if word == "e" || word == "exclude" {
+		op = control.OpExclude
+	}
+
+	_, pattern, _ := strings.Cut(strings.TrimLeft(line, " \t"), word)
+
+	return keyCommand{op: op, pattern: strings.TrimSpace(pattern)}
+}
+
+// chainOf переводит номер цепочки из предпросмотра в её имя.
+//
+// Номер удобнее имени, когда имена длинные, а предпросмотр с номерами уже
+// на экране. Имя, похожее на число, побеждает: цепочка может называться «1».
+func (k *keyboard) chainOf(arg string) string {
+	for _, name := range k.chains {
+		if name == arg {
+			return name
+		}
+	}
+
+	if n, err := strconv.Atoi(arg); err == nil && n >= 1 && n <= len(k.chains) {
+		return k.chains[n-1]
+	}
+
+	// Незнакомое имя уходит как есть: менеджер ответит ошибкой со списком
+	// доступных цепочек, и повторять эту логику здесь незачем.
+	return arg
+}
+
+// startKeyboard включает клавиатурный режим, если ввод — терминал.
+func startKeyboard(ctx context.Context, plan *runPlan, manager *runner.Manager, logger ui.Logger) {
+	if !stdinIsTerminal() {
+		return
+	}
+
+	chains := make([]string, 0, len(plan.flow.Chains))
+	for _, chain := range plan.flow.Chains {
+		chains = append(chains, chain.Name)
+	}
+
+	k := &keyboard{handler: controlHandler{manager: manager}, chains: chains, logger: logger}
+
+	logger.Info("Keyboard control is on: type 'h' and Enter for help")
+
+	go k.watchKeyboard(ctx, os.Stdin)
+}
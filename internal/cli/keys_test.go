@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/efureev/parallel/internal/control"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// recordingHandler записывает управляющие вызовы.
+type recordingHandler struct {
+	calls  []string
+	filter control.Filter
+}
+
+func (r *recordingHandler) Status() []control.Chain { return nil }
+
+func (r *recordingHandler) Start(chain string) error {
+	r.calls = append(r.calls, "start "+chain)
+
+	return nil
+}
+
+func (r *recordingHandler) Stop(chain string) error {
+	r.calls = append(r.calls, "stop "+chain)
+
+	return nil
+}
+
+func (r *recordingHandler) Restart(chain string) error {
+	r.calls = append(r.calls, "restart "+chain)
+
+	return nil
+}
+
+func (r *recordingHandler) Mute(chain string) error {
+	r.calls = append(r.calls, "mute "+chain)
+	r.filter.Muted = append(r.filter.Muted, chain)
+
+	return nil
+}
+
+func (r *recordingHandler) Unmute(chain string) error {
+	r.calls = append(r.calls, "unmute "+chain)
+	r.filter.Muted = nil
+
+	return nil
+}
+
+func (r *recordingHandler) Solo(chain string) error {
+	r.calls = append(r.calls, "solo "+chain)
+
+	return nil
+}
+
+func (r *recordingHandler) Unsolo(chain string) error {
+	r.calls = append(r.calls, "unsolo "+chain)
+
+	return nil
+}
+
+func (r *recordingHandler) Include(pattern string) error {
+	r.calls = append(r.calls, "include "+pattern)
+
+	return nil
+}
+
+func (r *recordingHandler) Exclude(pattern string) error {
+	r.calls = append(r.calls, "exclude "+pattern)
+
+	return nil
+}
+
+func (r *recordingHandler) Filter() control.Filter { return r.filter }
+
+func TestKeyboard_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		last    string
+		line    string
+		want    keyCommand
+		wantErr error
+	}{
+		{name: "перезапуск по имени", line: "r api", want: keyCommand{op: control.OpRestart, chain: "api"}},
+		{name: "перезапуск по номеру", line: "restart 2", want: keyCommand{op: control.OpRestart, chain: "db"}},
+		{name: "повтор последнего", last: "api", line: "r", want: keyCommand{op: control.OpRestart, chain: "api"}},
+		{name: "повторять нечего", line: "r", wantErr: ErrNoLastChain},
+		{name: "остановка", line: "  s   db ", want: keyCommand{op: control.OpStop, chain: "db"}},
+		{name: "остановка без цепочки", line: "s", wantErr: control.ErrChainRequired},
+		{name: "запуск", line: "start api", want: keyCommand{op: control.OpStart, chain: "api"}},
+		{name: "номер вне списка уходит как имя", line: "r 7", want: keyCommand{op: control.OpRestart, chain: "7"}},
+		{name: "состояние", line: "l", want: keyCommand{op: control.OpStatus}},
+		{name: "неизвестная команда", line: "x api", wantErr: ErrUnknownKey},
+		{name: "лишние аргументы", line: "r api db", wantErr: ErrUnknownKey},
+		{name: "заглушка по номеру", line: "m 2", want: keyCommand{op: control.OpMute, chain: "db"}},
+		{name: "выбор снимается целиком", line: "o", want: keyCommand{op: control.OpSolo}},
+		{
+			name: "шаблон с пробелами",
+			line: " e  GET /health ",
+			want: keyCommand{op: control.OpExclude, pattern: "GET /health"},
+		},
+		{name: "шаблон снимается", line: "include", want: keyCommand{op: control.OpInclude}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &keyboard{chains: []string{"api", "db"}, last: tt.last}
+
+			got, err := k.parse(tt.line)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ошибка = %v, ожидалась %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("разобрано %+v, ожидалось %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestKeyboard_WatchDispatches — строки из ввода доходят до обработчика по
+// порядку, а ошибочные не обрывают чтение.
+func TestKeyboard_WatchDispatches(t *testing.T) {
+	h := &recordingHandler{}
+	k := &keyboard{handler: h, chains: []string{"api", "db"}, logger: ui.NewDiscardLogger()}
+
+	k.watchKeyboard(t.Context(), strings.NewReader("r api\n\nbogus\ns 2\nr\nstart db\nl\nh\n"))
+
+	want := "restart api,stop db,restart api,start db"
+	if got := strings.Join(h.calls, ","); got != want {
+		t.Fatalf("вызовы = %s, ожидалось %s", got, want)
+	}
+}
+
+// TestKeyboard_MuteToggles — повторная m по той же цепочке снимает заглушку.
+func TestKeyboard_MuteToggles(t *testing.T) {
+	h := &recordingHandler{}
+	k := &keyboard{handler: h, chains: []string{"api", "worker"}, logger: ui.NewDiscardLogger()}
+
+	k.watchKeyboard(t.Context(), strings.NewReader("m worker\nm 2\no api\ni ^ERR\n"))
+
+	want := "mute worker,unmute worker,solo api,include ^ERR"
+	if got := strings.Join(h.calls, ","); got != want {
+		t.Fatalf("вызовы = %s, ожидалось %s", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/efureev/parallel/internal/config"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+const profileConfig = `failFast: true
+profiles:
+  ci:
+    chains: [ test, lint ]
+    except: [ lint ]
+    maxParallel: 2
+    failFast: false
+commands:
+  api:
+    serve: { cmd: [ 'go', 'run', './cmd/api' ] }
+  test:
+    unit: { cmd: [ 'go', 'test', './...' ] }
+  lint:
+    run: { cmd: [ 'golangci-lint', 'run' ] }
+`
+
+func TestParseFlags_Profile(t *testing.T) {
+	t.Setenv(profileEnv, "demo")
+
+	cfg, err := parseArgs(t)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if cfg.Profile != "demo" {
+		t.Errorf("профиль из окружения = %q", cfg.Profile)
+	}
+
+	if cfg, err = parseArgs(t, "-profile", "ci"); err != nil || cfg.Profile != "ci" {
+		t.Errorf("флаг сильнее окружения: %v, %v", cfg, err)
+	}
+
+	// Переменная на весь пайплайн не ломает шаг с командами после `--`, а
+	// явный флаг рядом с ними — та же ошибка, что и прочий отбор.
+	if cfg, err = parseArgs(t, doubleDash, "echo hi"); err != nil || cfg.Profile != "" {
+		t.Errorf("ad-hoc с PARALLEL_PROFILE: %v, %v", cfg, err)
+	}
+
+	if _, err = parseArgs(t, "-profile", "ci", doubleDash, "echo hi"); !errors.Is(err, ErrAdHocWithSelection) {
+		t.Errorf("ожидалась ErrAdHocWithSelection, получено %v", err)
+	}
+}
+
+// TestInitializeApp_Profile — профиль задаёт отбор и политику запуска, а
+// аргументы командной строки сильнее его.
+func TestInitializeApp_Profile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".parallelrc.yaml")
+	writeConfig(t, path, profileConfig)
+
+	tests := []struct {
+		name  string
+		flags Config
+		want  []string
+	}{
+		{name: "без профиля", flags: Config{}, want: []string{"api", "test", "lint"}},
+		{name: "профиль", flags: Config{Profile: "ci"}, want: []string{"test"}},
+		{name: "имена вместо списка", flags: Config{Profile: "ci", Chains: []string{"api"}}, want: []string{"api"}},
+		{name: "except дополняет", flags: Config{Profile: "ci", Except: []string{"test"}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := tt.flags
+			flags.ConfigFilePath = path
+
+			plan, err := initializeApp(&flags, ui.NewDiscardLogger())
+			if tt.want == nil {
+				if err == nil {
+					t.Fatal("отбор без цепочек должен быть ошибкой")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("initializeApp: %v", err)
+			}
+
+			var names []string
+			for _, chain := range plan.flow.Chains {
+				names = append(names, chain.Name)
+			}
+
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("цепочки = %v, ожидалось %v", names, tt.want)
+			}
+
+			if flags.Profile != "" && (!plan.keepGoing || plan.maxParallel != 2) {
+				t.Errorf("политика профиля не применена: keepGoing=%v maxParallel=%d", plan.keepGoing, plan.maxParallel)
+			}
+		})
+	}
+}
+
+func TestInitializeApp_UnknownProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".parallelrc.yaml")
+	writeConfig(t, path, profileConfig)
+
+	_, err := initializeApp(&Config{ConfigFilePath: path, Profile: "dmeo"}, ui.NewDiscardLogger())
+	if !errors.Is(err, config.ErrUnknownProfile) {
+		t.Errorf("ожидалась ErrUnknownProfile, получено %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// configPollInterval — как часто проверять файл конфигурации. Правка конфига
+// — действие человека, и секунда задержки на фоне перезапуска сервиса не видна.
+const configPollInterval = time.Second
+
+// configStamp — то, по чему замечается правка файла. Содержимое не читается:
+// опрос идёт всё время запуска, и разбирать YAML каждую секунду незачем.
+type configStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func stampOf(path string) (configStamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}, false
+	}
+
+	return configStamp{mod: info.ModTime(), size: info.Size()}, true
+}
+
+// stampsOf снимает отметки всех файлов конфигурации. Пропавший файл делает
+// набор неполным: сравнивать его с прежним рано.
+func stampsOf(paths []string) ([]configStamp, bool) {
+	stamps := make([]configStamp, 0, len(paths))
+
+	for _, path := range paths {
+		stamp, ok := stampOf(path)
+		if !ok {
+			return nil, false
+		}
+
+		stamps = append(stamps, stamp)
+	}
+
+	return stamps, true
+}
+
+// chainApplier — то, что умеет менять состав идущего запуска. Интерфейс, а
+// не *runner.Manager, чтобы перечитывание проверялось без живых процессов.
+type chainApplier interface {
+	AddChain(chain *flow.CommandChain) error
+	ReplaceChain(chain *flow.CommandChain) error
+	RemoveChain(name string) error
+}
+
+// configReloader применяет к идущему запуску изменения файла конфигурации.
+type configReloader struct {
+	flags   Config
+	applier chainApplier
+	logger  ui.Logger
+
+	// current — версия, с которой работает запуск; с ней сравнивается новая.
+	current flow.Flow
+	// files — файлы, из которых собрана current. Правка фрагмента из include
+	// или предка из extends меняет запуск так же, как правка корневого файла,
+	// а список меняется вместе с include.
+	files []string
+	// keepGoing и maxParallel — политика, с которой запуск начался. Её на ходу
+	// не поменять, и об изменении стоит сказать, а не молчать.
+	keepGoing   bool
+	maxParallel int
+}
+
+// watchConfig следит за файлами конфигурации и применяет их изменения к
+// идущему запуску. В режиме ad-hoc и с -no-reload не делает ничего.
+func watchConfig(ctx context.Context, flags *Config, plan *runPlan, manager *runner.Manager, logger ui.Logger) {
+	if flags.NoReload || plan.configPath == "" {
+		return
+	}
+
+	stamps, ok := stampsOf(plan.configFiles)
+	if !ok {
+		return
+	}
+
+	// Перечитывать надо ровно тот файл, с которым начали: поиск заново мог бы
+	// найти другой, если каталог успели поменять.
+	reloadFlags := *flags
+	reloadFlags.ConfigFilePath = plan.configPath
+
+	r := &configReloader{
+		flags:       reloadFlags,
+		applier:     manager,
+		logger:      logger,
+		current:     plan.flow,
+		files:       plan.configFiles,
+		keepGoing:   plan.keepGoing,
+		maxParallel: plan.maxParallel,
+	}
+
+	go r.watch(ctx, plan.configPath, stamps)
+}
+
+// watch опрашивает файлы и перечитывает конфигурацию после правки.
+//
+// Перечитывание ждёт, пока файлы перестанут меняться: редактор может писать
+// файл в несколько приёмов, и разбор половины файла дал бы ложную ошибку.
+func (r *configReloader) watch(ctx context.Context, path string, last []configStamp) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	pending := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Пропавший файл — обычно сохранение через переименование; ждём,
+		// пока он появится снова.
+		stamps, ok := stampsOf(r.files)
+		if !ok {
+			continue
+		}
+
+		if !slices.Equal(stamps, last) {
+			last, pending = stamps, true
+
+			continue
+		}
+
+		if pending {
+			pending = false
+
+			r.reload(path)
+
+			// Список файлов мог измениться вместе с include: новые файлы
+			// сравниваются уже со своими отметками, а не считаются правкой.
+			if stamps, ok := stampsOf(r.files); ok {
+				last = stamps
+			}
+		}
+	}
+}
+
+// reload перечитывает конфигурацию и применяет разницу с работающей версией.
+//
+// Ошибочная конфигурация запуск не трогает: незакрытая кавычка посреди
+// правки не должна гасить весь стек.
+func (r *configReloader) reload(path string) {
+	r.logger.Info("Configuration file changed, reloading", ui.F("path", path))
+
+	plan, err := initializeApp(&r.flags, r.logger)
+	if err != nil {
+		r.logger.Warn("Configuration was not reloaded, keeping the running one")
+
+		return
+	}
+
+	if plan.keepGoing != r.keepGoing || plan.maxParallel != r.maxParallel {
+		r.logger.Warn("failFast and maxParallel changes apply on the next run only")
+	}
+
+	hooksChanged := !reflect.DeepEqual(r.current.Before, plan.flow.Before) ||
+		!reflect.DeepEqual(r.current.OnExit, plan.flow.OnExit)
+	if hooksChanged {
+		r.logger.Warn("before and onExit changes apply on the next run only")
+	}
+
+	diff := flow.Compare(r.current, plan.flow)
+	r.current, r.files = plan.flow, plan.configFiles
+
+	if diff.Empty() {
+		r.logger.Info("Configuration reloaded, no chain changed")
+
+		return
+	}
+
+	r.apply(diff)
+
+	r.logger.Info("Configuration reloaded",
+		ui.F("added", len(diff.Added)), ui.F("changed", len(diff.Changed)), ui.F("removed", len(diff.Removed)))
+}
+
+// apply меняет состав запуска по разнице версий.
+//
+// Добавление идёт первым: изменённая цепочка может зависеть от новой, и её
+// гейт должен существовать раньше, чем цепочку начнут ждать, — иначе ожидание
+// незнакомого имени завершилось бы мгновенно. Удаление идёт последним, когда
+// зависимые уже получили версию без него.
+func (r *configReloader) apply(diff flow.Diff) {
+	for _, chain := range diff.Added {
+		r.warnIfFailed(chain.Name, r.applier.AddChain(chain))
+	}
+
+	for _, chain := range diff.Changed {
+		r.warnIfFailed(chain.Name, r.applier.ReplaceChain(chain))
+	}
+
+	for _, name := range diff.Removed {
+		r.warnIfFailed(name, r.applier.RemoveChain(name))
+	}
+}
+
+func (r *configReloader) warnIfFailed(chain string, err error) {
+	if err != nil {
+		r.logger.Warn("Reloaded chain was not applied", ui.F("chain", chain), ui.F("error", err.Error()))
+	}
+}
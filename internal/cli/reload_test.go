@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// recordingApplier записывает изменения состава запуска.
+type recordingApplier struct {
+	calls []string
+}
+
+func (r *recordingApplier) AddChain(chain *flow.CommandChain) error {
+	r.calls = append(r.calls, "add "+chain.Name)
+
+	return nil
+}
+
+func (r *recordingApplier) ReplaceChain(chain *flow.CommandChain) error {
+	r.calls = append(r.calls, "replace "+chain.Name)
+
+	return nil
+}
+
+func (r *recordingApplier) RemoveChain(name string) error {
+	r.calls = append(r.calls, "remove "+name)
+
+	return nil
+}
+
+const reloadBefore = `commands:
+  api:
+    serve: { cmd: ['go', 'run', './cmd/api'] }
+  db:
+    up: { cmd: ['docker', 'compose', 'up'] }
+  worker:
+    run: { cmd: ['go', 'run', './cmd/worker'] }
+`
+
+// Цепочка db не тронута, api поменяла окружение, worker убран, ui добавлен.
+const reloadAfter = `commands:
+  ui:
+    dev: { cmd: ['yarn', 'dev'] }
+  api:
+    serve: { cmd: ['go', 'run', './cmd/api'], env: { PORT: '8081' } }
+  db:
+    up: { cmd: ['docker', 'compose', 'up'] }
+`
+
+func newTestReloader(t *testing.T, content string) (*configReloader, *recordingApplier, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), ".parallelrc.yaml")
+	writeConfig(t, path, content)
+
+	flags := &Config{ConfigFilePath: path}
+
+	plan, err := initializeApp(flags, ui.NewDiscardLogger())
+	if err != nil {
+		t.Fatalf("initializeApp: %v", err)
+	}
+
+	applier := &recordingApplier{}
+
+	return &configReloader{
+		flags:   *flags,
+		applier: applier,
+		logger:  ui.NewDiscardLogger(),
+		current: plan.flow,
+		files:   plan.configFiles,
+	}, applier, path
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+}
+
+func TestConfigReloader_AppliesOnlyChanges(t *testing.T) {
+	r, applier, path := newTestReloader(t, reloadBefore)
+
+	writeConfig(t, path, reloadAfter)
+	r.reload(path)
+
+	want := []string{"add ui", "replace api", "remove worker"}
+	if !slices.Equal(applier.calls, want) {
+		t.Fatalf("изменения: %v, ожидалось %v", applier.calls, want)
+	}
+
+	// Повторное перечитывание того же файла ничего не меняет: сравнение
+	// идёт с уже применённой версией.
+	r.reload(path)
+
+	if len(applier.calls) != len(want) {
+		t.Fatalf("повторное перечитывание дало изменения: %v", applier.calls[len(want):])
+	}
+}
+
+// TestConfigReloader_KeepsRunningOnInvalidConfig — ошибка посреди правки не
+// должна трогать идущий запуск.
+func TestConfigReloader_KeepsRunningOnInvalidConfig(t *testing.T) {
+	r, applier, path := newTestReloader(t, reloadBefore)
+
+	writeConfig(t, path, "commands:\n  api: [\n")
+	r.reload(path)
+
+	if len(applier.calls) != 0 {
+		t.Fatalf("ошибочная конфигурация применена: %v", applier.calls)
+	}
+
+	if names := r.current.Names(); len(names) != 3 {
+		t.Fatalf("рабочая версия подменена ошибочной: %v", names)
+	}
+}
+
+// TestConfigReloader_RespectsSelection — отбор цепочек действует и на
+// перечитанную конфигурацию: новая цепочка вне отбора не запускается.
+func TestConfigReloader_RespectsSelection(t *testing.T) {
+	r, applier, path := newTestReloader(t, reloadBefore)
+	r.flags.Except = []string{"ui"}
+
+	writeConfig(t, path, reloadAfter)
+	r.reload(path)
+
+	if slices.Contains(applier.calls, "add ui") {
+		t.Fatalf("исключённая цепочка добавлена: %v", applier.calls)
+	}
+}
+
+// TestConfigReloader_WatchesIncludedFiles — следить надо за всеми файлами
+// конфигурации: правка фрагмента из include меняет запуск так же, как правка
+// корневого файла, а новый include добавляет файл к наблюдаемым.
+func TestConfigReloader_WatchesIncludedFiles(t *testing.T) {
+	r, applier, path := newTestReloader(t, reloadBefore)
+
+	dir := filepath.Dir(path)
+	api, web := filepath.Join(dir, "api.yaml"), filepath.Join(dir, "web.yaml")
+
+	if !slices.Equal(r.files, []string{path}) {
+		t.Fatalf("files = %v, ожидался один корневой файл", r.files)
+	}
+
+	writeConfig(t, api, "commands:\n  search:\n    up: { cmd: ['meilisearch'] }\n")
+	writeConfig(t, web, "commands:\n  ui:\n    dev: { cmd: ['yarn', 'dev'] }\n")
+	writeConfig(t, path, "include: [ '*.yaml' ]\n"+reloadBefore)
+	r.reload(path)
+
+	if want := []string{path, api, web}; !slices.Equal(r.files, want) {
+		t.Fatalf("files = %v, ожидалось %v", r.files, want)
+	}
+
+	writeConfig(t, api, "commands:\n  search:\n    up: { cmd: ['meilisearch', '--dev'] }\n")
+	r.reload(path)
+
+	want := []string{"add search", "add ui", "replace search"}
+	if !slices.Equal(applier.calls, want) {
+		t.Fatalf("изменения: %v, ожидалось %v", applier.calls, want)
+	}
+}
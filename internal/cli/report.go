@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// Форматы отчёта о запуске.
+const (
+	reportJSON  = "json"
+	reportJUnit = "junit"
+)
+
+// ErrReportFormat — значение -report не вида `json=<file>` или `junit=<file>`.
+var ErrReportFormat = errors.New("report must be json=<file> or junit=<file>")
+
+// reportTarget — один запрошенный отчёт: формат и файл.
+type reportTarget struct {
+	Format string
+	Path   string
+}
+
+// reportFlag накапливает повторённый флаг -report: CI обычно хочет JUnit для
+// своего интерфейса и JSON для собственных скриптов сразу.
+type reportFlag struct {
+	targets *[]reportTarget
+}
+
+func (f reportFlag) String() string {
+	if f.targets == nil {
+		return ""
+	}
+
+	parts := make([]string, 0, len(*f.targets))
+	for _, t := range *f.targets {
+		parts = append(parts, t.Format+"="+t.Path)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (f reportFlag) Set(value string) error {
+	format, path, ok := strings.Cut(value, "=")
+	if !ok || path == "" || (format != reportJSON && format != reportJUnit) {
+		return fmt.Errorf("%w, got %q", ErrReportFormat, value)
+	}
+
+	*f.targets = append(*f.targets, reportTarget{Format: format, Path: path})
+
+	return nil
+}
+
+// reportChains переводит исход цепочек в строки отчёта: строка цепочки — та
+// же, что в сводке, и к ней строки её команд.
+func reportChains(results []runner.ChainResult, interrupted bool) []ui.ReportChain {
+	rows := summaryRows(results, interrupted)
+	chains := make([]ui.ReportChain, 0, len(results))
+
+	for i, res := range results {
+		chain := ui.ReportChain{SummaryRow: rows[i], Commands: make([]ui.ReportCommand, 0, len(res.Commands))}
+
+		for _, cmd := range res.Commands {
+			chain.Commands = append(chain.Commands, ui.ReportCommand{
+				SummaryRow: summaryRow(cmd.Name, cmd.Duration, cmd.Err, cmd.Stopped, cmd.Skipped),
+				Output:     stderrOf(cmd.Err),
+			})
+		}
+
+		chains = append(chains, chain)
+	}
+
+	return chains
+}
+
+// stderrOf достаёт сохранённый хвост stderr упавшей команды.
+func stderrOf(err error) string {
+	var exitErr *runner.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Stderr
+	}
+
+	var timeoutErr *runner.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.Stderr
+	}
+
+	return ""
+}
+
+// writeReports пишет все запрошенные отчёты.
+//
+// Отказ одного отчёта не мешает остальным: JUnit, не записанный из-за
+// каталога, не повод терять JSON рядом. Возвращается первая ошибка.
+func writeReports(targets []reportTarget, chains []ui.ReportChain, logger ui.Logger) error {
+	var first error
+
+	for _, t := range targets {
+		if err := writeReport(t, chains); err != nil {
+			logger.Error(err, "Failed to write report", ui.F("path", t.Path))
+
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+func writeReport(t reportTarget, chains []ui.ReportChain) error {
+	write := ui.WriteJSONReport
+	if t.Format == reportJUnit {
+		write = ui.WriteJUnitReport
+	}
+
+	// Отчёт собирается в памяти целиком: недописанный файл читатель CI
+	// принял бы за битый и не показал бы вовсе.
+	var buf bytes.Buffer
+	if err := write(&buf, chains); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(t.Path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+func TestParseFlags_Report(t *testing.T) {
+	cfg, err := parseArgs(t, "-report", "junit=out/report.xml", "-report", "json=report.json")
+	if err != nil {
+		t.Fatalf("неожиданная ошибка: %v", err)
+	}
+
+	want := []reportTarget{{Format: reportJUnit, Path: "out/report.xml"}, {Format: reportJSON, Path: "report.json"}}
+	if len(cfg.Reports) != len(want) || cfg.Reports[0] != want[0] || cfg.Reports[1] != want[1] {
+		t.Fatalf("ожидалось %+v, получено %+v", want, cfg.Reports)
+	}
+}
+
+func TestReportFlag_RejectsBadValue(t *testing.T) {
+	var targets []reportTarget
+
+	for _, value := range []string{"report.xml", "xml=report.xml", "junit="} {
+		if err := (reportFlag{targets: &targets}).Set(value); !errors.Is(err, ErrReportFormat) {
+			t.Fatalf("%q: ожидалась ErrReportFormat, получено %v", value, err)
+		}
+	}
+}
+
+func TestReportChains(t *testing.T) {
+	failure := &runner.ExitError{Chain: "test", Command: "unit", Code: 1, Stderr: "FAIL\n"}
+	results := []runner.ChainResult{{
+		Name: "test", Err: failure, Duration: time.Second,
+		Commands: []runner.CommandResult{
+			{Name: "unit", Err: failure, Duration: time.Second},
+			{Name: "integ", Skipped: true},
+		},
+	}}
+
+	chains := reportChains(results, false)
+	if len(chains) != 1 || chains[0].Status != ui.StatusFailed || len(chains[0].Commands) != 2 {
+		t.Fatalf("неверный отчёт: %+v", chains)
+	}
+
+	unit, integ := chains[0].Commands[0], chains[0].Commands[1]
+	if unit.Status != ui.StatusFailed || unit.Output != "FAIL\n" {
+		t.Fatalf("упавшая команда должна нести свой stderr: %+v", unit)
+	}
+
+	if integ.Status != ui.StatusSkipped {
+		t.Fatalf("не запускавшаяся команда должна быть пропущенной: %+v", integ)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	dir := t.TempDir()
+	targets := []reportTarget{
+		{Format: reportJUnit, Path: filepath.Join(dir, "missing", "report.xml")},
+		{Format: reportJSON, Path: filepath.Join(dir, "report.json")},
+	}
+	chains := []ui.ReportChain{{SummaryRow: ui.SummaryRow{Name: "lint", Status: ui.StatusOK}}}
+
+	if err := writeReports(targets, chains, ui.NewDiscardLogger()); err == nil {
+		t.Fatal("ожидалась ошибка записи в несуществующий каталог")
+	}
+
+	// Отказ первого отчёта не должен помешать второму.
+	data, err := os.ReadFile(targets[1].Path)
+	if err != nil || !strings.Contains(string(data), `"name": "lint"`) {
+		t.Fatalf("JSON-отчёт должен быть записан: %v\n%s", err, data)
+	}
+}
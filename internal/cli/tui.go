@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// ErrTUIWithOutput — -ui tui вместе с -output json или grouped.
+//
+// Полноэкранный вид сам решает, как показать вывод команд; JSON-строки или
+// отложенные группы на его экране — это два вывода, спорящие за один
+// терминал.
+var ErrTUIWithOutput = errors.New("-ui tui shows command output itself and cannot be combined with -output json " +
+	"or grouped")
+
+// tuiEvents переводит события запуска в строку статуса полноэкранного вида.
+//
+// Источник тот же, что у потока -events: гейты chainExecutor и цикл
+// перезапусков сообщают о себе событиями, и второй канал для экрана не нужен.
+type tuiEvents struct {
+	view *ui.TUI
+
+	// resume — состояние цепочки до перезапуска команды. Гейт готовности
+	// открывается однажды, и перезапущенный сервис снова «ready», а не
+	// «running», когда его процесс поднялся.
+	mu     sync.Mutex
+	resume map[string]ui.PaneState
+}
+
+func newTUIEvents(view *ui.TUI) *tuiEvents {
+	return &tuiEvents{view: view, resume: make(map[string]ui.PaneState)}
+}
+
+func (e *tuiEvents) Emit(ev runner.Event) {
+	switch ev.Kind {
+	case runner.EventChainStarted:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) {
+			s.State, s.PID, s.Since = ui.PaneRunning, 0, ev.Time
+		})
+	case runner.EventChainReady:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) { s.State = ui.PaneReady })
+	case runner.EventChainFinished:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) { s.State, s.PID = paneStateOf(ev.Status), 0 })
+	case runner.EventChainSkipped:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) { s.State = ui.PaneSkipped })
+	case runner.EventCommandStarted:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) {
+			s.PID = ev.PID
+
+			if s.State == ui.PaneRestarting {
+				s.State = e.resumeOf(ev.Chain)
+			}
+		})
+	case runner.EventCommandRestarted:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) {
+			if s.State != ui.PaneRestarting {
+				e.remember(ev.Chain, s.State)
+			}
+
+			s.State, s.PID = ui.PaneRestarting, 0
+			s.Restarts++
+		})
+	case runner.EventCommandExited:
+		e.view.Update(ev.Chain, func(s *ui.PaneStatus) { s.PID = 0 })
+	case runner.EventRunStarted, runner.EventRunFinished, runner.EventCommandTimeout,
+		runner.EventCommandHealthy, runner.EventCommandUnhealthy, runner.EventSignalSent, runner.EventForceKilled:
+	default:
+	}
+}
+
+func (e *tuiEvents) remember(chain string, state ui.PaneState) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.resume[chain] = state
+}
+
+func (e *tuiEvents) resumeOf(chain string) ui.PaneState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if state, ok := e.resume[chain]; ok {
+		return state
+	}
+
+	return ui.PaneRunning
+}
+
+// paneStateOf переводит итог цепочки в состояние панели.
+func paneStateOf(state runner.ChainState) ui.PaneState {
+	switch state {
+	case runner.ChainDone:
+		return ui.PaneDone
+	case runner.ChainFailed:
+		return ui.PaneFailed
+	case runner.ChainStopped:
+		return ui.PaneStopped
+	case runner.ChainSkipped:
+		return ui.PaneSkipped
+	case runner.ChainRunning:
+		return ui.PaneRunning
+	case runner.ChainWaiting:
+		return ui.PaneWaiting
+	default:
+		return ui.PaneDone
+	}
+}
+
+// eventSinks раздаёт событие нескольким приёмникам: экрану и потоку -events.
+type eventSinks []runner.EventSink
+
+func (s eventSinks) Emit(ev runner.Event) {
+	for _, sink := range s {
+		sink.Emit(ev)
+	}
+}
+
+// paneSpecs описывает панели цепочек плана в порядке предпросмотра.
+func paneSpecs(chains []*flow.CommandChain) []ui.PaneSpec {
+	specs := make([]ui.PaneSpec, 0, len(chains))
+	for _, chain := range chains {
+		specs = append(specs, ui.PaneSpec{Name: chain.Name, Needs: chain.Needs})
+	}
+
+	return specs
+}
+
+// startTUI занимает экран полноэкранным видом, если он запрошен, и
+// возвращает логгер, которым дальше пользуется запуск.
+//
+// Вид, не сумевший занять терминал, — ошибка запуска: просили экран с
+// панелями, и тихо вернуть вперемешку идущий поток значило бы не то, что
+// просили.
+func startTUI(flags *Config, plan *runPlan, logger ui.Logger, quit func()) (*ui.TUI, ui.Logger, error) {
+	if flags.UI != ui.ViewTUI {
+		return nil, logger, nil
+	}
+
+	view := ui.NewTUI(paneSpecs(plan.flow.Chains), logger,
+		ui.WithTUILevel(flags.LogLevel), ui.WithTUIQuit(quit), ui.WithTUINoColor(flags.NoColor))
+
+	if err := view.Start(); err != nil {
+		logger.Error(err, "Failed to start full-screen ui")
+
+		return nil, logger, err
+	}
+
+	return view, view, nil
+}
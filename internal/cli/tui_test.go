@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/runner"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// TestTUIEvents_Status — события гейтов и перезапусков доходят до строки
+// статуса, а перезапущенный готовый сервис снова готов, когда поднялся.
+func TestTUIEvents_Status(t *testing.T) {
+	view := ui.NewTUI([]ui.PaneSpec{{Name: "api", Needs: []string{"db"}}, {Name: "db"}}, ui.NewDiscardLogger())
+	events := newTUIEvents(view)
+	start := time.Now()
+
+	status := func(chain string) ui.PaneStatus {
+		t.Helper()
+
+		s, ok := view.Status(chain)
+		if !ok {
+			t.Fatalf("нет панели %s", chain)
+		}
+
+		return s
+	}
+
+	if s := status("api"); s.State != ui.PaneWaiting {
+		t.Fatalf("до старта цепочка должна ждать, получено %+v", s)
+	}
+
+	events.Emit(runner.Event{Kind: runner.EventChainStarted, Chain: "db", Time: start})
+	events.Emit(runner.Event{Kind: runner.EventCommandStarted, Chain: "db", PID: 42})
+	events.Emit(runner.Event{Kind: runner.EventChainReady, Chain: "db"})
+
+	if s := status("db"); s.State != ui.PaneReady || s.PID != 42 || !s.Since.Equal(start) {
+		t.Fatalf("ожидалась готовая цепочка с pid 42, получено %+v", s)
+	}
+
+	events.Emit(runner.Event{Kind: runner.EventCommandRestarted, Chain: "db"})
+
+	if s := status("db"); s.State != ui.PaneRestarting || s.PID != 0 || s.Restarts != 1 {
+		t.Fatalf("ожидался перезапуск без процесса, получено %+v", s)
+	}
+
+	events.Emit(runner.Event{Kind: runner.EventCommandStarted, Chain: "db", PID: 43})
+
+	if s := status("db"); s.State != ui.PaneReady || s.PID != 43 {
+		t.Fatalf("поднявшийся сервис должен снова быть готов, получено %+v", s)
+	}
+
+	events.Emit(runner.Event{Kind: runner.EventChainFinished, Chain: "db", Status: runner.ChainFailed})
+
+	if s := status("db"); s.State != ui.PaneFailed || s.PID != 0 || s.Restarts != 1 {
+		t.Fatalf("ожидался отказ, получено %+v", s)
+	}
+
+	events.Emit(runner.Event{Kind: runner.EventChainSkipped, Chain: "api"})
+
+	if s := status("api"); s.State != ui.PaneSkipped {
+		t.Fatalf("ожидался пропуск, получено %+v", s)
+	}
+}
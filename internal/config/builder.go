@@ -1,10 +1,12 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -94,50 +96,162 @@ func (b *FlowBuilder) Build(data Data) (flow.Flow, error) {
 		return flow.Flow{}, err
 	}
 
+	// Переменные профиля сильнее общих файлов: профиль и нужен, чтобы тот же
+	// проект запускать с другими настройками, не трогая .env.
+	maps.Copy(baseEnv, data.Env)
+
 	result := &flow.Flow{}
+	groups := matrixGroups(data.Chains)
 
-	for idx, chainCfg := range data.Chains {
-		chain := &flow.CommandChain{
-			Name:     chainCfg.Name,
-			ColorIdx: idx,
-			Needs:    chainCfg.Needs,
+	// Цвет — по порядку собранных цепочек, а не объявленных: сочетаниям одной
+	// матрицы нужны разные цвета, иначе их вывод не различить.
+	for _, chainCfg := range data.Chains {
+		for _, cell := range matrixCells(chainCfg.Matrix) {
+			chain, err := b.buildChain(data, chainCfg, cell, baseEnv, groups)
+			if err != nil {
+				return flow.Flow{}, err
+			}
+
+			chain.ColorIdx = len(result.Chains)
+			result.AddChain(chain)
 		}
+	}
 
-		for _, namedCmd := range chainCfg.Commands {
-			var cmd flow.Command
+	if result.Before, err = b.buildCommands(beforeKey, data.Before, data.BaseDir, baseEnv, nil); err != nil {
+		return flow.Flow{}, err
+	}
 
-			env, lookup, err := commandEnv(namedCmd.Spec, baseEnv, resolve)
-			if err != nil {
-				return flow.Flow{}, fmt.Errorf("chain %q, command %q: %w", chainCfg.Name, namedCmd.Name, err)
-			}
+	if result.OnExit, err = b.buildCommands(onExitKey, data.OnExit, data.BaseDir, baseEnv, nil); err != nil {
+		return flow.Flow{}, err
+	}
 
-			if namedCmd.Spec.Docker != nil {
-				cmd, err = b.createDockerCommand(namedCmd.Name, namedCmd.Spec, env, lookup, resolve)
-			} else {
-				cmd, err = b.createRegularCommand(namedCmd.Name, namedCmd.Spec, env, lookup)
-			}
+	return *result, nil
+}
 
-			if err != nil {
-				return flow.Flow{}, fmt.Errorf("chain %q, command %q: %w", chainCfg.Name, namedCmd.Name, err)
-			}
+// buildChain собирает цепочку для одного сочетания матрицы; у цепочки без
+// матрицы сочетание одно и пустое.
+func (b *FlowBuilder) buildChain(
+	data Data, chainCfg ChainConfig, cell matrixCell, baseEnv map[string]string, groups map[string][]string,
+) (*flow.CommandChain, error) {
+	name := cell.chainName(chainCfg.Name)
+	needs, conditions := expandNeeds(chainCfg.Needs, chainCfg.NeedConditions, groups)
 
-			if cmd.Dir, err = expand(cmd.Dir, lookup); err != nil {
-				return flow.Flow{}, fmt.Errorf("chain %q, command %q, dir: %w", chainCfg.Name, namedCmd.Name, err)
-			}
+	chain := &flow.CommandChain{
+		Name:  name,
+		Needs: needs,
+		Tags:  chainCfg.Tags,
 
-			if err = expandReady(cmd.Ready, lookup); err != nil {
-				return flow.Flow{}, fmt.Errorf("chain %q, command %q, ready: %w", chainCfg.Name, namedCmd.Name, err)
-			}
+		NeedConditions: needConditionsOf(conditions),
+	}
 
-			cmd.Dir = resolve(cmd.Dir)
+	chainDir := chainBaseDir(chainCfg, data.BaseDir)
+	chainResolve := logResolver(chainCfg, dirResolver(data.BaseDir), chainDir)
+
+	var err error
+
+	if chain.Log, err = logOf(data.Log, chainCfg.Log, name, chainResolve); err != nil {
+		return nil, fmt.Errorf("chain %q, log: %w", name, err)
+	}
+
+	cmds, err := b.buildCommands(name, chainCfg.Commands, chainDir, baseEnv, cell.vars())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		chain.Add(cmd)
+	}
+
+	if chain.Finally, err = b.buildCommands(name, chainCfg.Finally, chainDir, baseEnv, cell.vars()); err != nil {
+		return nil, err
+	}
+
+	return chain, nil
+}
+
+// matrixGroups сопоставляет имени цепочки с матрицей имена всех её сочетаний.
+func matrixGroups(chains []ChainConfig) map[string][]string {
+	var groups map[string][]string
+
+	for _, chainCfg := range chains {
+		if len(chainCfg.Matrix) == 0 {
+			continue
+		}
 
-			chain.Add(cmd)
+		if groups == nil {
+			groups = make(map[string][]string)
 		}
 
-		result.AddChain(chain)
+		for _, cell := range matrixCells(chainCfg.Matrix) {
+			groups[chainCfg.Name] = append(groups[chainCfg.Name], cell.chainName(chainCfg.Name))
+		}
 	}
 
-	return *result, nil
+	return groups
+}
+
+// chainBaseDir — каталог, от которого разрешаются относительные пути цепочки:
+// каталог её файла, если он известен, иначе общий.
+func chainBaseDir(chainCfg ChainConfig, baseDir string) string {
+	if chainCfg.Source == "" {
+		return baseDir
+	}
+
+	return filepath.Dir(chainCfg.Source)
+}
+
+// logResolver выбирает, от чего разрешать файл журнала цепочки. Свой файл
+// цепочки лежит рядом с ней, как и её dir; файл из умолчаний верхнего уровня
+// — рядом с корневой конфигурацией, где эти умолчания и написаны.
+func logResolver(chainCfg ChainConfig, rootResolve func(string) string, chainDir string) func(string) string {
+	if chainCfg.Log != nil && chainCfg.Log.File != "" {
+		return dirResolver(chainDir)
+	}
+
+	return rootResolve
+}
+
+// buildCommands собирает команды одного списка: цепочки, её finally, before
+// или onExit. Путь у всех один, чтобы команда подготовки и уборки понимала
+// те же поля, что и обычная, — env, envFile, dir, docker и подстановку
+// переменных. vars — значения матрицы для подстановки; nil — матрицы нет.
+func (b *FlowBuilder) buildCommands(
+	chainName string, named []NamedCommand, baseDir string, baseEnv, vars map[string]string,
+) ([]flow.Command, error) {
+	resolve := dirResolver(baseDir)
+	cmds := make([]flow.Command, 0, len(named))
+
+	for _, namedCmd := range named {
+		var cmd flow.Command
+
+		env, lookup, err := commandEnv(namedCmd.Spec, baseEnv, vars, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("chain %q, command %q: %w", chainName, namedCmd.Name, err)
+		}
+
+		if namedCmd.Spec.Docker != nil {
+			cmd, err = b.createDockerCommand(namedCmd.Name, namedCmd.Spec, env, lookup, resolve)
+		} else {
+			cmd, err = b.createRegularCommand(namedCmd.Name, namedCmd.Spec, env, lookup)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("chain %q, command %q: %w", chainName, namedCmd.Name, err)
+		}
+
+		if err = expandCommand(&cmd, lookup); err != nil {
+			return nil, fmt.Errorf("chain %q, command %q, %w", chainName, namedCmd.Name, err)
+		}
+
+		cmd.Dir = resolve(cmd.Dir)
+		cmd.Watch = watchOf(namedCmd.Spec, cmd.Dir, baseDir)
+		resolveReady(cmd.Ready, cmp.Or(cmd.Dir, baseDir))
+		resolveHealth(cmd.Health, cmp.Or(cmd.Dir, baseDir))
+
+		cmds = append(cmds, cmd)
+	}
+
+	return cmds, nil
 }
 
 // loadEnvFiles читает и сливает переменные из перечисленных файлов.
@@ -164,20 +278,22 @@ func loadEnvFiles(paths []string, resolve func(string) string) (map[string]strin
 // commandEnv собирает окружение команды и набор значений для подстановки.
 //
 // Приоритет от слабого к сильному: окружение процесса → верхнеуровневые файлы →
-// файлы команды → env. Возвращается два набора, и они намеренно разные:
+// файлы команды → env. Значения матрицы (vars) есть только в подстановке: в
+// окружение команда кладёт их сама, через env, под тем именем, которое ей
+// нужно. Возвращается два набора, и они намеренно разные:
 // в окружение команды уходит всё, а источником подстановки служит всё, КРОМЕ
 // самого env. Причина не в эстетике: env декодируется в Go-мапу, порядок
 // записей теряется, и разрешать ссылки внутри неё пришлось бы в произвольном
 // порядке.
 func commandEnv(
-	cmdRaw command, baseEnv map[string]string, resolve func(string) string,
+	cmdRaw command, baseEnv, vars map[string]string, resolve func(string) string,
 ) (env, lookup map[string]string, err error) {
 	own, err := loadEnvFiles(cmdRaw.EnvFile, resolve)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	lookup = make(map[string]string, len(baseEnv)+len(own))
+	lookup = make(map[string]string, len(baseEnv)+len(own)+len(vars))
 
 	for _, pair := range os.Environ() {
 		if key, value, found := strings.Cut(pair, "="); found {
@@ -187,6 +303,7 @@ func commandEnv(
 
 	maps.Copy(lookup, baseEnv)
 	maps.Copy(lookup, own)
+	maps.Copy(lookup, vars)
 
 	// Окружение команды строится из файлов, а переменные процесса добавит
 	// раннер: копировать их в каждую команду незачем.
@@ -206,6 +323,26 @@ func commandEnv(
 	return env, lookup, nil
 }
 
+// expandCommand подставляет переменные в каталог, готовность и здоровье
+// команды. Ошибка называет поле, в котором подстановка не удалась.
+func expandCommand(cmd *flow.Command, lookup map[string]string) error {
+	var err error
+
+	if cmd.Dir, err = expand(cmd.Dir, lookup); err != nil {
+		return fmt.Errorf("dir: %w", err)
+	}
+
+	if err = expandReady(cmd.Ready, lookup); err != nil {
+		return fmt.Errorf("ready: %w", err)
+	}
+
+	if err = expandHealth(cmd.Health, lookup); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+
+	return nil
+}
+
 // expandReady подставляет переменные в условие готовности: адрес и команда
 // проверки приходят из тех же настроек, что и сама команда.
 func expandReady(ready *flow.ReadyCondition, lookup map[string]string) error {
@@ -223,6 +360,22 @@ func expandReady(ready *flow.ReadyCondition, lookup map[string]string) error {
 		return err
 	}
 
+	if err = expandHTTP(ready.HTTP, lookup); err != nil {
+		return err
+	}
+
+	if ready.File != nil {
+		if ready.File.Path, err = expand(ready.File.Path, lookup); err != nil {
+			return err
+		}
+	}
+
+	for _, part := range slices.Concat(ready.All, ready.Any) {
+		if err = expandReady(part, lookup); err != nil {
+			return err
+		}
+	}
+
 	ready.Exec, err = expandAll(ready.Exec, lookup)
 
 	return err
@@ -391,6 +544,11 @@ func (b *FlowBuilder) createDockerCommand(
 		return flow.Command{}, err
 	}
 
+	timestamp, err := flow.ParseTimestampMode(cmdRaw.Format.Timestamp)
+	if err != nil {
+		return flow.Command{}, err
+	}
+
 	// Аргументы docker-команды собраны нами целиком и в префиксе каждой строки
 	// вывода превращаются в шум: с томами и командой контейнера они длиннее
 	// самого вывода. Поэтому по умолчанию показываем только имя — как и
@@ -408,27 +566,148 @@ func (b *FlowBuilder) createDockerCommand(
 		Pipe:    true,
 		Disable: cmdRaw.Disable,
 		// Env намеренно пуст: переменные уже ушли в аргументы флагами -e.
-		Format:          flow.Format{CmdName: format},
+		Format:          flow.Format{CmdName: format, Timestamp: timestamp},
 		Timeout:         cmdRaw.Timeout,
 		Restart:         policy,
 		RestartAttempts: attempts,
 		RestartDelay:    delay,
 		Ready:           readyOf(cmdRaw),
+		Health:          healthOf(cmdRaw.Health),
 	}, nil
 }
 
 // readyOf переводит секцию ready конфигурации в доменное условие.
 // Проверку «ровно одно условие» делает домен: правило принадлежит ему.
 func readyOf(cmdRaw command) *flow.ReadyCondition {
-	if cmdRaw.Ready == nil {
+	return readyFrom(cmdRaw.Ready)
+}
+
+// readyFrom переводит условие вместе с вложенными в all и any.
+func readyFrom(spec *readyCondition) *flow.ReadyCondition {
+	if spec == nil {
+		return nil
+	}
+
+	ready := &flow.ReadyCondition{
+		TCP:     spec.TCP,
+		HTTP:    httpOf(spec.HTTP),
+		File:    fileOf(spec.File),
+		Exec:    spec.Exec,
+		Timeout: spec.Timeout,
+	}
+
+	if spec.LogLine != nil {
+		ready.LogLine, ready.LogRegex = spec.LogLine.Text, spec.LogLine.Regex
+	}
+
+	// nil и пустой список различаются: `all: []` — ошибка, о которой домен
+	// должен узнать, а не отсутствие условия.
+	if spec.All != nil {
+		ready.All = make([]*flow.ReadyCondition, 0, len(spec.All))
+		for _, part := range spec.All {
+			ready.All = append(ready.All, readyFrom(part))
+		}
+	}
+
+	if spec.Any != nil {
+		ready.Any = make([]*flow.ReadyCondition, 0, len(spec.Any))
+		for _, part := range spec.Any {
+			ready.Any = append(ready.Any, readyFrom(part))
+		}
+	}
+
+	return ready
+}
+
+// resolveReady разрешает пути файловых условий от рабочего каталога
+// команды — так же, как шаблоны watch.
+func resolveReady(ready *flow.ReadyCondition, root string) {
+	if ready == nil {
+		return
+	}
+
+	if ready.File != nil && ready.File.Path != "" && !filepath.IsAbs(ready.File.Path) {
+		ready.File.Path = filepath.Join(root, ready.File.Path)
+	}
+
+	for _, part := range slices.Concat(ready.All, ready.Any) {
+		resolveReady(part, root)
+	}
+}
+
+// needConditionsOf переводит условия needs в доменные. Допустимость значений
+// проверяет flow.ValidateDeps: это правило графа, а не формата файла.
+func needConditionsOf(raw map[string]string) map[string]flow.NeedCondition {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	out := make(map[string]flow.NeedCondition, len(raw))
+	for name, cond := range raw {
+		out[name] = flow.NeedCondition(cond)
+	}
+
+	return out
+}
+
+// healthOf переводит секцию health в доменное описание. Проба собирается
+// тем же условием, что и готовность: проверять её умеет один и тот же код.
+func healthOf(spec *healthSpec) *flow.Health {
+	if spec == nil {
+		return nil
+	}
+
+	return &flow.Health{
+		Probe: &flow.ReadyCondition{
+			TCP:  spec.TCP,
+			HTTP: httpOf(spec.HTTP),
+			File: fileOf(spec.File),
+			Exec: spec.Exec,
+		},
+		Interval:  spec.Interval,
+		Threshold: spec.Threshold,
+	}
+}
+
+// expandHealth подставляет переменные в пробу здоровья.
+func expandHealth(health *flow.Health, lookup map[string]string) error {
+	if health == nil {
+		return nil
+	}
+
+	return expandReady(health.Probe, lookup)
+}
+
+// resolveHealth разрешает путь файловой пробы — как у готовности.
+func resolveHealth(health *flow.Health, root string) {
+	if health == nil {
+		return
+	}
+
+	resolveReady(health.Probe, root)
+}
+
+// watchOf переводит секцию watch в доменное описание.
+//
+// Шаблоны отсчитываются от рабочего каталога команды: `**/*.go` у сервера
+// в `dir: api` означает его исходники, а не весь репозиторий. Без dir — от
+// каталога конфигурации, по той же причине, по которой от него разрешается
+// сам dir.
+func watchOf(cmdRaw command, dir, base string) *flow.Watch {
+	if cmdRaw.Watch == nil {
 		return nil
 	}
 
-	return &flow.ReadyCondition{
-		TCP:     cmdRaw.Ready.TCP,
-		Exec:    cmdRaw.Ready.Exec,
-		LogLine: cmdRaw.Ready.LogLine,
-		Timeout: cmdRaw.Ready.Timeout,
+	root := dir
+	if root == "" {
+		root = base
+	}
+
+	return &flow.Watch{
+		Root:     root,
+		Paths:    cmdRaw.Watch.Paths,
+		Ignore:   cmdRaw.Watch.Ignore,
+		Debounce: cmdRaw.Watch.Debounce,
 	}
 }
 
@@ -451,7 +730,12 @@ func (b *FlowBuilder) createRegularCommand(
 		return flow.Command{}, ErrCmdAndRun
 
 	case cmdRaw.Run != "":
-		cmdStr, args = shellCommand(cmdRaw.Run)
+		run, expErr := expandMatrix(cmdRaw.Run, lookup)
+		if expErr != nil {
+			return flow.Command{}, expErr
+		}
+
+		cmdStr, args = shellCommand(run)
 
 		// У shell-формы аргумент ровно один — вся команда целиком, — и в
 		// префиксе каждой строки вывода он превращается в шум вида
@@ -482,6 +766,11 @@ func (b *FlowBuilder) createRegularCommand(
 		return flow.Command{}, err
 	}
 
+	timestamp, err := flow.ParseTimestampMode(cmdRaw.Format.Timestamp)
+	if err != nil {
+		return flow.Command{}, err
+	}
+
 	return flow.Command{
 		Name:            cmdName,
 		Cmd:             cmdStr,
@@ -490,11 +779,12 @@ func (b *FlowBuilder) createRegularCommand(
 		Pipe:            cmdRaw.Pipe,
 		Disable:         cmdRaw.Disable,
 		Env:             envPairs(env),
-		Format:          flow.Format{CmdName: format},
+		Format:          flow.Format{CmdName: format, Timestamp: timestamp},
 		Timeout:         cmdRaw.Timeout,
 		Restart:         policy,
 		RestartAttempts: attempts,
 		RestartDelay:    delay,
 		Ready:           readyOf(cmdRaw),
+		Health:          healthOf(cmdRaw.Health),
 	}, nil
 }
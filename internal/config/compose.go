@@ -0,0 +1,398 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// composer собирает конфигурацию из нескольких файлов: цепочки из include и
+// поля команд из extends.
+//
+// Сборка идёт здесь, на уровне Data, а не во Flow: extends наследует поля
+// ДО сборки — с подстановкой переменных, разрешением путей и проверками,
+// которые наследник проходит уже целиком, как если бы написал всё сам.
+type composer struct {
+	loader *FileLoader
+
+	// chains — собственные цепочки каждого прочитанного файла по абсолютному
+	// пути. Цель extends ищется в названном файле, а не во всём собранном
+	// запуске: ссылка обязана значить одно и то же, откуда её ни подключи.
+	chains map[string][]ChainConfig
+
+	// owners — файл, объявивший цепочку, для сообщения о повторе имени.
+	owners map[string]string
+
+	// templates — шаблоны корневого файла; ими пользуются все фрагменты.
+	templates map[string]commandTemplate
+
+	// files — прочитанные файлы в порядке чтения; chains их порядка не помнит.
+	files []string
+}
+
+// compose подключает include корневого файла и разрешает extends.
+//
+// Каждый файл читается один раз: повторное упоминание — общий фрагмент в
+// двух шаблонах или взаимное подключение — пропускается, а не даёт ни
+// повтора цепочек, ни бесконечной рекурсии.
+func compose(loader *FileLoader, root Data, rootPath string) (Data, error) {
+	path := absPath(rootPath)
+
+	c := &composer{
+		loader:    loader,
+		chains:    map[string][]ChainConfig{},
+		owners:    map[string]string{},
+		templates: root.Templates,
+	}
+	if err := c.register(&root, path); err != nil {
+		return Data{}, err
+	}
+
+	if err := c.include(&root, root.Include, path); err != nil {
+		return Data{}, err
+	}
+
+	if err := c.extend(&root, path); err != nil {
+		return Data{}, err
+	}
+
+	root.Files = c.files
+
+	return root, nil
+}
+
+// register запоминает цепочки файла и проверяет, что их имена ещё не заняты.
+func (c *composer) register(data *Data, path string) error {
+	for i := range data.Chains {
+		chain := &data.Chains[i]
+		chain.Source = path
+
+		if owner, taken := c.owners[chain.Name]; taken {
+			return fmt.Errorf("%w: %q in %s, first defined in %s", ErrDuplicateChain, chain.Name, path, owner)
+		}
+
+		c.owners[chain.Name] = path
+	}
+
+	c.chains[path] = data.Chains
+	c.files = append(c.files, path)
+
+	return nil
+}
+
+// include добавляет к root цепочки файлов, подходящих под шаблоны, в порядке
+// шаблонов и, внутри шаблона, в порядке имён — он не должен зависеть от ФС.
+func (c *composer) include(root *Data, patterns []string, from string) error {
+	for _, pattern := range patterns {
+		paths, err := includePaths(filepath.Dir(from), pattern)
+		if err != nil {
+			return fmt.Errorf("%s: %q %q: %w", from, includeKey, pattern, err)
+		}
+
+		for _, path := range paths {
+			if _, seen := c.chains[path]; seen {
+				continue
+			}
+
+			frag, err := c.loader.loadData(path)
+			if err != nil {
+				return err
+			}
+
+			if key := runWideKey(frag); key != "" {
+				return fmt.Errorf("%w: %s sets %q", ErrIncludedKey, path, key)
+			}
+
+			if err := c.register(&frag, path); err != nil {
+				return err
+			}
+
+			root.Chains = append(root.Chains, frag.Chains...)
+
+			for _, hint := range frag.TopLevelHints {
+				root.TopLevelHints = append(root.TopLevelHints, path+": "+hint)
+			}
+
+			if err := c.include(root, frag.Include, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// includePaths разворачивает шаблон include в абсолютные пути.
+//
+// Шаблон, который ничего не нашёл, — не ошибка: каталог сервисов может быть
+// пуст. Путь без метасимволов — другое дело, он называет файл явно, и его
+// отсутствие сообщит загрузка.
+func includePaths(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	if !strings.ContainsAny(pattern, `*?[`) {
+		return []string{absPath(pattern)}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, match := range matches {
+		matches[i] = absPath(match)
+	}
+
+	slices.Sort(matches)
+
+	return matches, nil
+}
+
+// runWideKey называет ключ включённого файла, действующий на весь запуск.
+//
+// Такие ключи принимаются только в корневом файле: фрагмент одной команды
+// не должен молча менять failFast или maxParallel всем остальным. Шаблоны —
+// туда же: их имена общие для всех файлов, и у каждого должно быть одно место.
+func runWideKey(d Data) string {
+	switch {
+	case d.FailFast != nil:
+		return failFastKey
+	case len(d.EnvFiles) > 0:
+		return envFileKey
+	case d.MaxParallel != 0:
+		return maxParallelKey
+	case d.Log != nil:
+		return logKey
+	case d.Timestamp != "":
+		return formatKey
+	case len(d.Before) > 0:
+		return beforeKey
+	case len(d.OnExit) > 0:
+		return onExitKey
+	case len(d.Profiles) > 0:
+		return profilesKey
+	case len(d.Templates) > 0:
+		return templatesKey
+	default:
+		return ""
+	}
+}
+
+// extend разрешает extends у всех команд собранной конфигурации.
+//
+// Новые спецификации применяются только после того, как разрешены все:
+// предок, уже переписанный на месте, потерял бы свой extends, и цепочка
+// наследования обрывалась бы в зависимости от порядка обхода.
+func (c *composer) extend(data *Data, rootPath string) error {
+	type update struct {
+		cmd   *NamedCommand
+		spec  command
+		nodes []ast.Node
+	}
+
+	var updates []update
+
+	visit := func(cmds []NamedCommand, file, owner string) error {
+		for i := range cmds {
+			if !cmds[i].Spec.composed() {
+				continue
+			}
+
+			nodes, err := c.lineage(cmds[i], file, nil)
+			if err != nil {
+				return fmt.Errorf("chain %q, command %q: %w", owner, cmds[i].Name, err)
+			}
+
+			// Поле шаблона проверяется только здесь, уже с подставленными
+			// параметрами.
+			spec, err := overlay(nodes)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrConfigDecode, decodeError(cmds[i].Name, owner, err))
+			}
+
+			updates = append(updates, update{cmd: &cmds[i], spec: spec, nodes: nodes})
+		}
+
+		return nil
+	}
+
+	for _, chain := range data.Chains {
+		file := cmp.Or(chain.Source, rootPath)
+		if err := visit(chain.Commands, file, chain.Name); err != nil {
+			return err
+		}
+
+		if err := visit(chain.Finally, file, chain.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := visit(data.Before, rootPath, beforeKey); err != nil {
+		return err
+	}
+
+	if err := visit(data.OnExit, rootPath, onExitKey); err != nil {
+		return err
+	}
+
+	for _, u := range updates {
+		u.cmd.Spec, u.cmd.layers = u.spec, u.nodes
+	}
+
+	return nil
+}
+
+// overlay собирает спецификацию команды из узлов, от дальнего предка к
+// ближнему, — каждый разбирается поверх предыдущего.
+//
+// Поле, заданное наследником, заменяет унаследованное; вложенные секции
+// (docker, ready, health, watch, format) сливаются по полям; списки и env
+// заменяются целиком. cmd и run — два способа записать одно и то же, и
+// заданный наследником сбрасывает другой.
+func overlay(nodes []ast.Node) (command, error) {
+	var spec command
+
+	for _, node := range nodes {
+		fields := mappingValues(node)
+		if lookup(fields, "run") != nil {
+			spec.Cmd = nil
+		}
+
+		if lookup(fields, "cmd") != nil {
+			spec.Run = ""
+		}
+
+		if err := yaml.NodeToValue(node, &spec, yaml.Strict()); err != nil {
+			return command{}, err
+		}
+	}
+
+	spec.Extends, spec.Use, spec.With = "", "", nil
+
+	return spec, nil
+}
+
+// lineage возвращает узлы команды и всех её предков, начиная с дальнего.
+// Предок — команда из extends либо развёрнутый шаблон из use.
+func (c *composer) lineage(cmd NamedCommand, file string, seen []string) ([]ast.Node, error) {
+	if cmd.Spec.Use != "" || cmd.Spec.With != nil {
+		base, err := c.instantiate(cmd.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		return []ast.Node{base, cmd.node}, nil
+	}
+
+	ref := cmd.Spec.Extends
+	if ref == "" {
+		return []ast.Node{cmd.node}, nil
+	}
+
+	cut := strings.LastIndex(ref, "#")
+	if cut < 0 {
+		return nil, fmt.Errorf("%w %q: expected file#chain.command or #chain.command", ErrExtendsTarget, ref)
+	}
+
+	target, name := file, ref[cut+1:]
+	if ref[:cut] != "" {
+		target = ref[:cut]
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(file), target)
+		}
+
+		target = absPath(target)
+	}
+
+	key := target + "#" + name
+	if slices.Contains(seen, key) {
+		return nil, fmt.Errorf("%w: %s", ErrExtendsCycle, strings.Join(append(seen, key), " -> "))
+	}
+
+	chains, err := c.fileChains(target)
+	if err != nil {
+		return nil, err
+	}
+
+	base, ok := findCommand(chains, name)
+	if !ok {
+		return nil, fmt.Errorf("%w %q: no command %q in %s", ErrExtendsTarget, ref, name, target)
+	}
+
+	parents, err := c.lineage(base, target, append(seen, key))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(parents, cmd.node), nil
+}
+
+// instantiate разворачивает шаблон, названный в use.
+func (c *composer) instantiate(spec command) (ast.Node, error) {
+	switch {
+	case spec.Use == "":
+		return nil, fmt.Errorf("%w: 'with' requires 'use'", ErrTemplateUse)
+	case spec.Extends != "":
+		return nil, fmt.Errorf("%w: a command takes either 'use' or 'extends'", ErrTemplateUse)
+	}
+
+	tpl, ok := c.templates[spec.Use]
+	if !ok {
+		return nil, unknownTemplate(spec.Use, c.templates)
+	}
+
+	node, err := tpl.instantiate(spec.With)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: %w", spec.Use, err)
+	}
+
+	return node, nil
+}
+
+// fileChains возвращает цепочки файла, читая его при первом обращении.
+// Файл, прочитанный только ради extends, в запуск своих цепочек не добавляет.
+func (c *composer) fileChains(path string) ([]ChainConfig, error) {
+	if chains, ok := c.chains[path]; ok {
+		return chains, nil
+	}
+
+	data, err := c.loader.loadData(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.chains[path] = data.Chains
+	c.files = append(c.files, path)
+
+	return data.Chains, nil
+}
+
+// findCommand ищет команду цепочки по имени chain.command.
+func findCommand(chains []ChainConfig, name string) (NamedCommand, bool) {
+	for _, chain := range chains {
+		for _, cmd := range chain.Commands {
+			if chain.Name+"."+cmd.Name == name {
+				return cmd, true
+			}
+		}
+	}
+
+	return NamedCommand{}, false
+}
+
+// absPath приводит путь к абсолютному, чтобы один файл под разными
+// относительными именами считался одним.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(path)
+}
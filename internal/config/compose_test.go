@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// writeFiles раскладывает файлы конфигурации по каталогу и возвращает его.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, body := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+// loadFlow загружает и собирает конфигурацию из файла.
+func loadFlow(t *testing.T, path string) (flow.Flow, error) {
+	t.Helper()
+
+	data, err := NewFileLoader(YamlFileMarshaller{}).Load(path)
+	if err != nil {
+		return flow.Flow{}, err
+	}
+
+	return NewFlowBuilder().Build(data)
+}
+
+// TestLoad_Include — ради этого задача и делалась: у каждого сервиса свой
+// фрагмент, и пути в нём отсчитываются от него самого.
+func TestLoad_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"parallel.yaml": "include: [ 'services/*/parallel.yaml', 'tools.yaml' ]\n" +
+			"commands:\n  db:\n    up: { cmd: [ 'docker' ] }\n",
+		"services/web/parallel.yaml": "commands:\n  web:\n    serve: { cmd: [ 'npm' ], dir: '.' }\n",
+		"services/api/parallel.yaml": "include: [ '../../tools.yaml' ]\n" +
+			"commands:\n  api:\n    serve: { cmd: [ 'go' ] }\n",
+		"services/api/notes/readme.md": "not a config",
+		"tools.yaml":                   "commands:\n  lint:\n    run: { cmd: [ 'golangci-lint' ] }\n",
+	})
+
+	result, err := loadFlow(t, filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	var names []string
+	for _, chain := range result.Chains {
+		names = append(names, chain.Name)
+	}
+
+	// Корневые цепочки первыми, затем фрагменты в порядке шаблонов, внутри
+	// шаблона — по именам; tools.yaml подключён дважды, но читается один раз.
+	if want := []string{"db", "api", "lint", "web"}; !slices.Equal(names, want) {
+		t.Fatalf("цепочки = %v, ожидалось %v", names, want)
+	}
+
+	if got, want := result.Chains[3].Commands()[0].Dir, filepath.Join(dir, "services", "web"); got != want {
+		t.Errorf("dir фрагмента = %q, ожидалось %q", got, want)
+	}
+}
+
+// TestLoad_Files — загрузчик называет все файлы конфигурации, включая
+// прочитанные только ради extends: правка любого из них меняет запуск.
+func TestLoad_Files(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"parallel.yaml": "include: [ 'api.yaml' ]\ncommands:\n  db:\n    up: { cmd: [ 'docker' ] }\n",
+		"api.yaml":      "commands:\n  api:\n    build: { extends: 'base.yaml#go.build' }\n",
+		"base.yaml":     "commands:\n  go:\n    build: { cmd: [ 'go', 'build' ] }\n",
+	})
+
+	data, err := NewFileLoader(YamlFileMarshaller{}).Load(filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "parallel.yaml"), filepath.Join(dir, "api.yaml"), filepath.Join(dir, "base.yaml"),
+	}
+	if !slices.Equal(data.Files, want) {
+		t.Errorf("Files = %v, ожидалось %v", data.Files, want)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := map[string]struct {
+		files map[string]string
+		want  error
+	}{
+		"повтор имени": {
+			files: map[string]string{"b.yaml": "commands:\n  db:\n    up: { cmd: [ 'echo' ] }\n"},
+			want:  ErrDuplicateChain,
+		},
+		"ключ запуска во фрагменте": {
+			files: map[string]string{"b.yaml": "failFast: false\ncommands:\n  x:\n    up: { cmd: [ 'echo' ] }\n"},
+			want:  ErrIncludedKey,
+		},
+		"нет файла": {
+			files: map[string]string{},
+			want:  ErrConfigNotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tt.files["a.yaml"] = "include: b.yaml\ncommands:\n  db:\n    up: { cmd: [ 'echo' ] }\n"
+			dir := writeFiles(t, tt.files)
+
+			if _, err := loadFlow(t, filepath.Join(dir, "a.yaml")); !errors.Is(err, tt.want) {
+				t.Errorf("ожидалась %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestLoad_Extends — наследник перекрывает заданные поля, остальные берёт у
+// предка — в том числе через файл и через ступень.
+func TestLoad_Extends(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "commands:\n  go:\n" +
+			"    build: { cmd: [ 'go', 'build' ], pipe: true, timeout: 1m, env: { CGO_ENABLED: '0' } }\n",
+		"parallel.yaml": "commands:\n  api:\n" +
+			"    build: { extends: 'base.yaml#go.build', pipe: false, env: { GOOS: linux } }\n" +
+			"    test: { extends: '#api.build', run: 'go test ./...' }\n",
+	})
+
+	result, err := loadFlow(t, filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if len(result.Chains) != 1 {
+		t.Fatalf("цепочки файла, прочитанного ради extends, попали в запуск: %d", len(result.Chains))
+	}
+
+	build, test := result.Chains[0].Commands()[0], result.Chains[0].Commands()[1]
+
+	if build.Pipe || build.Timeout.Minutes() != 1 || build.Cmd != "go" {
+		t.Errorf("build = %+v: pipe перекрыт, timeout и cmd унаследованы", build)
+	}
+
+	if slices.Contains(build.Env, "CGO_ENABLED=0") || !slices.Contains(build.Env, "GOOS=linux") {
+		t.Errorf("env заменяется целиком: %v", build.Env)
+	}
+
+	if !strings.Contains(strings.Join(test.Args, " "), "go test") || test.Timeout.Minutes() != 1 {
+		t.Errorf("test = %+v: run заменяет унаследованный cmd", test)
+	}
+}
+
+func TestLoad_ExtendsErrors(t *testing.T) {
+	tests := map[string]struct {
+		commands string
+		want     error
+	}{
+		"нет команды":   {"    a: { extends: '#x.missing' }\n", ErrExtendsTarget},
+		"без решётки":   {"    a: { extends: 'x.a' }\n", ErrExtendsTarget},
+		"нет файла":     {"    a: { extends: 'other.yaml#x.a' }\n", ErrConfigNotFound},
+		"по кругу":      {"    a: { extends: '#x.b' }\n    b: { extends: '#x.a' }\n", ErrExtendsCycle},
+		"сам от себя":   {"    a: { extends: '#x.a' }\n", ErrExtendsCycle},
+		"ошибка поля":   {"    a: { extends: '#x.b' }\n    b: { cmd: [ 'echo' ], pipe: 'maybe' }\n", ErrConfigDecode},
+		"лишнее в node": {"    a: { extends: '#x.b', bogus: 1 }\n    b: { cmd: [ 'echo' ] }\n", ErrConfigDecode},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"parallel.yaml": "commands:\n  x:\n" + tt.commands})
+
+			if _, err := loadFlow(t, filepath.Join(dir, "parallel.yaml")); !errors.Is(err, tt.want) {
+				t.Errorf("ожидалась %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"errors"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/efureev/parallel/internal/flow"
 )
 
 // TestUnmarshal_Needs — needs единственный ключ внутри цепочки, который не
@@ -43,6 +48,43 @@ func TestUnmarshal_NeedsScalar(t *testing.T) {
 	}
 }
 
+// TestBuild_NeedsConditions — форма-отображение сохраняет порядок ключей и
+// доводит условия до домена, а неизвестное условие ловит валидация графа.
+func TestBuild_NeedsConditions(t *testing.T) {
+	raw := []byte("commands:\n  db:\n    x: { cmd: [ 'echo' ] }\n  seed:\n    x: { cmd: [ 'echo' ] }\n" +
+		"  api:\n    needs: { seed: completed_successfully, db: ready }\n    serve: { cmd: [ 'echo' ] }\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	result, err := NewFlowBuilder().Build(cfg)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	api := result.Chains[2]
+	if !slices.Equal(api.Needs, []string{"seed", "db"}) || api.NeedOf("seed") != flow.NeedCompletedSuccessfully {
+		t.Fatalf("needs = %v, условия %v", api.Needs, api.NeedConditions)
+	}
+
+	bad := []byte("commands:\n  db:\n    x: { cmd: [ 'echo' ] }\n" +
+		"  api:\n    needs: { db: healthy }\n    serve: { cmd: [ 'echo' ] }\n")
+
+	if cfg, err = (YamlFileMarshaller{}).Unmarshal(bad); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if result, err = NewFlowBuilder().Build(cfg); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if err := flow.ValidateDeps(result); !errors.Is(err, flow.ErrUnknownNeedCondition) {
+		t.Errorf("ожидалась ErrUnknownNeedCondition, получено %v", err)
+	}
+}
+
 // TestUnmarshal_NeedsReservedMessage — автор конфигурации, назвавший так
 // команду, должен получить объяснение, а не жалобу на тип значения.
 func TestUnmarshal_NeedsReservedMessage(t *testing.T) {
@@ -119,6 +161,127 @@ commands:
 	}
 }
 
+// TestBuild_ReadyHTTP — проба http пишется и адресом, и секцией; коды
+// разбираются из всех трёх форм, а в адрес и заголовки подставляются
+// переменные.
+func TestBuild_ReadyHTTP(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "PORT=8080\nTOKEN=secret\n")
+
+	path := writeFile(t, dir, "flow.yaml", `
+envFile: .env
+commands:
+  api:
+    serve:
+      cmd: [ 'echo' ]
+      ready:
+        http:
+          url: 'http://127.0.0.1:${PORT}/healthz'
+          status: 2xx
+          body: ok
+          headers: { Authorization: 'Bearer ${TOKEN}' }
+          insecure: true
+  web:
+    serve:
+      cmd: [ 'echo' ]
+      ready:
+        http: 'https://localhost:${PORT}/'
+`)
+
+	data, err := NewFileLoader(YamlFileMarshaller{}).Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	result, err := NewFlowBuilder().Build(data)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	api := result.Chains[0].Commands()[0].Ready.HTTP
+	if api == nil || api.URL != "http://127.0.0.1:8080/healthz" || api.Headers["Authorization"] != "Bearer secret" {
+		t.Fatalf("проба api = %+v", api)
+	}
+
+	if api.StatusMin != 200 || api.StatusMax != 299 || api.Body != "ok" || !api.Insecure {
+		t.Errorf("поля пробы потеряны: %+v", api)
+	}
+
+	if web := result.Chains[1].Commands()[0].Ready.HTTP; web == nil || web.URL != "https://localhost:8080/" {
+		t.Errorf("краткая форма не разобрана: %+v", web)
+	}
+}
+
+// TestBuild_ReadyComposite — all и any разбираются вложенными, а путь file
+// отсчитывается от рабочего каталога команды.
+func TestBuild_ReadyComposite(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeFile(t, dir, "flow.yaml", `
+commands:
+  web:
+    bundle:
+      cmd: [ 'echo' ]
+      dir: web
+      ready:
+        all:
+          - tcp: '127.0.0.1:5173'
+          - any:
+              - file: { path: 'dist/manifest.json', nonEmpty: true }
+              - logLine: 'built in'
+        timeout: 1m
+`)
+
+	data, err := NewFileLoader(YamlFileMarshaller{}).Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	result, err := NewFlowBuilder().Build(data)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	ready := result.Chains[0].Commands()[0].Ready
+	if err := ready.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if len(ready.All) != 2 || len(ready.All[1].Any) != 2 || ready.Timeout != time.Minute {
+		t.Fatalf("составное условие разобрано неверно: %s", ready.Describe())
+	}
+
+	file := ready.All[1].Any[0].File
+	if want := filepath.Join(dir, "web", "dist", "manifest.json"); file.Path != want || !file.NonEmpty {
+		t.Errorf("file = %+v, ожидался путь %s", file, want)
+	}
+}
+
+func TestStatusRange(t *testing.T) {
+	cases := map[string][2]int{"200": {200, 200}, "200-399": {200, 399}, "5XX": {500, 599}}
+
+	for status, want := range cases {
+		raw := []byte("commands:\n  api:\n    serve:\n      cmd: [ 'echo' ]\n" +
+			"      ready: { http: { url: 'http://x', status: '" + status + "' } }\n")
+
+		cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+		if err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+
+		got := cfg.Chains[0].Commands[0].Spec.Ready.HTTP.Status
+		if got.low != want[0] || got.high != want[1] {
+			t.Errorf("%s: получено %d-%d", status, got.low, got.high)
+		}
+	}
+
+	bad := []byte("commands:\n  api:\n    serve:\n      cmd: [ 'echo' ]\n" +
+		"      ready: { http: { url: 'http://x', status: 'ok' } }\n")
+	if _, err := (YamlFileMarshaller{}).Unmarshal(bad); err == nil {
+		t.Error("неразборчивый status принят")
+	}
+}
+
 // TestBuild_ReadyValidated — правило «ровно одно условие» принадлежит домену,
 // но должно срабатывать на пути сборки.
 func TestBuild_ReadyValidated(t *testing.T) {
@@ -126,7 +289,7 @@ func TestBuild_ReadyValidated(t *testing.T) {
 		Name: "c",
 		Commands: []NamedCommand{{Name: "x", Spec: command{
 			Cmd:   []string{"echo"},
-			Ready: &readyCondition{TCP: "127.0.0.1:1", LogLine: "up"},
+			Ready: &readyCondition{TCP: "127.0.0.1:1", LogLine: &logLineSpec{Text: "up"}},
 		}}},
 	}}}
 
@@ -166,3 +329,79 @@ func TestUnmarshal_TaggedNodeDoesNotPanic(t *testing.T) {
 		})
 	}
 }
+
+func TestBuild_Health(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "PORT=9100\n")
+
+	path := writeFile(t, dir, "flow.yaml", `
+envFile: .env
+commands:
+  queue:
+    consumer:
+      cmd: [ 'echo' ]
+      health:
+        http: 'http://127.0.0.1:${PORT}/healthz'
+        interval: 5s
+        threshold: 2
+`)
+
+	data, err := NewFileLoader(YamlFileMarshaller{}).Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	result, err := NewFlowBuilder().Build(data)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	health := result.Chains[0].Commands()[0].Health
+	if err := health.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if health.Probe.HTTP.URL != "http://127.0.0.1:9100/healthz" || health.Every() != 5*time.Second ||
+		health.Failures() != 2 {
+		t.Fatalf("health разобран неверно: %s", health.Describe())
+	}
+}
+
+// TestLoad_HealthLogLine — logLine в health не бывает, и строгий разбор
+// говорит об этом сразу, а не молча оставляет пробу пустой.
+func TestLoad_HealthLogLine(t *testing.T) {
+	raw := []byte("commands:\n  queue:\n    consumer:\n      cmd: [ 'echo' ]\n      health: { logLine: 'up' }\n")
+
+	if _, err := (YamlFileMarshaller{}).Unmarshal(raw); err == nil {
+		t.Fatal("ожидалась ошибка разбора")
+	}
+}
+
+func TestLoad_LogLineRegex(t *testing.T) {
+	raw := []byte("commands:\n  web:\n    dev:\n      cmd: [ 'vite' ]\n" +
+		"      ready: { logLine: { regex: 'Local: .*:(?P<port>\\d+)' } }\n" +
+		"  e2e:\n    needs: web\n    run:\n      cmd: [ 'npx', '--base=http://localhost:${web.port}' ]\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	result, err := NewFlowBuilder().Build(cfg)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if err := result.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if got := result.Chains[0].Commands()[0].Ready.Exports(); !slices.Equal(got, []string{"port"}) {
+		t.Errorf("exports = %q", got)
+	}
+
+	// Ссылка на переменную цепочки доживает до запуска нетронутой.
+	if got := result.Chains[1].Commands()[0].Args[0]; got != "--base=http://localhost:${web.port}" {
+		t.Errorf("args[0] = %q", got)
+	}
+}
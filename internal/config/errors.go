@@ -36,4 +36,34 @@ var (
 	ErrCmdAndRun = errors.New("command cannot use both 'cmd' and 'run'")
 	// ErrMissingCommands — в конфигурации нет верхнеуровневого ключа commands.
 	ErrMissingCommands = errors.New("config must contain the 'commands' key")
+	// ErrSharedLogFile — верхнеуровневый log.file без %CHAIN%: все цепочки
+	// писали бы в один файл.
+	ErrSharedLogFile = errors.New("top-level log.file must contain %CHAIN%")
+	// ErrDuplicateChain — цепочка с тем же именем уже пришла из другого файла.
+	ErrDuplicateChain = errors.New("chain is defined more than once")
+	// ErrIncludedKey — во включённом файле ключ, который действует на весь
+	// запуск и потому задаётся только в корневом.
+	ErrIncludedKey = errors.New("included file may only contain 'commands' and 'include'")
+	// ErrExtendsTarget — extends ссылается на несуществующую команду или
+	// записан не в форме file#chain.command.
+	ErrExtendsTarget = errors.New("invalid extends reference")
+	// ErrExtendsCycle — команды наследуют друг от друга по кругу.
+	ErrExtendsCycle = errors.New("extends cycle")
+	// ErrMatrix — секция matrix цепочки записана неверно.
+	ErrMatrix = errors.New("invalid matrix")
+	// ErrTag — тег цепочки нельзя было бы указать при отборе.
+	ErrTag = errors.New("invalid tag")
+	// ErrUnknownTemplate — use называет шаблон, которого нет.
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrTemplateParams — в with неизвестный параметр либо объявленному
+	// параметру не хватило значения.
+	ErrTemplateParams = errors.New("invalid template parameters")
+	// ErrTemplateUse — use или with записаны там, где они не имеют смысла:
+	// with без use, use вместе с extends, use внутри шаблона.
+	ErrTemplateUse = errors.New("invalid template use")
+	// ErrUnknownProfile — выбран профиль, которого в конфигурации нет.
+	ErrUnknownProfile = errors.New("unknown profile")
+	// ErrProfileTarget — профиль перекрывает несуществующую команду либо её
+	// extends.
+	ErrProfileTarget = errors.New("invalid profile command override")
 )
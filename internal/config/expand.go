@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -11,7 +12,11 @@ import (
 // Поддерживается только явная форма со скобками. Голый $VAR не раскрывается
 // намеренно: в аргументах команд доллар встречается сам по себе — `awk '{print $1}'`,
 // `sed 's/$//'`, — и съедать его молча нельзя.
-var placeholderRe = regexp.MustCompile(`\$\$?\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+//
+// Из имён с точкой здесь только ${matrix.ось}: прочие ${цепочка.переменная}
+// — переменные цепочек, их значения появляются лишь при старте команды, и
+// подставляет их раннер.
+var placeholderRe = regexp.MustCompile(`\$\$?\{((?:matrix\.)?[A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
 
 // expand подставляет значения переменных в строку.
 //
@@ -19,6 +24,22 @@ var placeholderRe = regexp.MustCompile(`\$\$?\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*
 // пустая строка тихо ломает пути и адреса, и разбираться приходится уже по
 // странному поведению запущенной команды.
 func expand(s string, lookup map[string]string) (string, error) {
+	return substitute(s, lookup, nil)
+}
+
+// expandParams подставляет в строку параметры шаблона.
+//
+// Ссылки на имена, не объявленные параметрами, остаются как есть: это
+// переменные окружения, и подставит их сборка команды, как в любой другой.
+// По той же причине нетронутой остаётся и $${...} — снимать экранирование
+// второй раз было бы уже ошибкой.
+func expandParams(s string, values map[string]string, params []string) (string, error) {
+	return substitute(s, values, func(name string) bool { return !slices.Contains(params, name) })
+}
+
+// substitute — общий ход expand и expandParams. keep отбирает ссылки, которые
+// надо оставить нетронутыми; nil — подставляются все.
+func substitute(s string, lookup map[string]string, keep func(name string) bool) (string, error) {
 	if !strings.Contains(s, "${") {
 		return s, nil
 	}
@@ -29,12 +50,20 @@ func expand(s string, lookup map[string]string) (string, error) {
 		// $${VAR} — способ написать литеральную ${VAR}: снимаем один доллар
 		// и на этом останавливаемся.
 		if strings.HasPrefix(match, "$$") {
+			if keep != nil {
+				return match
+			}
+
 			return match[1:]
 		}
 
 		groups := placeholderRe.FindStringSubmatch(match)
 		name, hasDefault, fallback := groups[1], groups[2] != "", groups[3]
 
+		if keep != nil && keep(name) {
+			return match
+		}
+
 		// Вложенная форма: умолчание ограничено первой закрывающей скобкой,
 		// поэтому ${A:-${B:-x}} раскрылось бы в «${B:-x}» — строку с недобитой
 		// подстановкой, которая уехала бы в аргумент команды буквально.
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// Ключи подготовки и уборки. finally зарезервирован внутри цепочки, как
+// needs и log; before и onExit — верхнеуровневые ключи.
+const (
+	beforeKey  = "before"
+	finallyKey = "finally"
+	onExitKey  = "onExit"
+)
+
+// parseCommands разбирает отображение «имя → команда» подготовки или
+// уборки в порядке объявления. where называет секцию в ошибке формы,
+// chainName — владельца в ошибке команды: для finally это цепочка, для
+// before и onExit — сам ключ, под которым их команды и выводятся.
+//
+// Форма та же, что у команд цепочки. Не-отображение — отказ, а не пустой
+// список: молча пропущенная подготовка или уборка хуже любой ошибки разбора.
+func parseCommands(node ast.Node, where, chainName string) ([]NamedCommand, error) {
+	if node == nil || node.Type() == ast.NullType {
+		return nil, nil
+	}
+
+	entries := mappingValues(node)
+	if entries == nil {
+		return nil, fmt.Errorf("%w %s: expected a mapping of commands, got %s", ErrConfigDecode, where, node.Type())
+	}
+
+	cmds := make([]NamedCommand, 0, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Key.GetToken().Value
+
+		var spec command
+		if err := yaml.NodeToValue(entry.Value, &spec, yaml.Strict()); err != nil {
+			return nil, decodeError(name, chainName, err)
+		}
+
+		cmds = append(cmds, NamedCommand{Name: name, Spec: spec, node: entry.Value})
+	}
+
+	return cmds, nil
+}
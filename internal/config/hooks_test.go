@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuild_Hooks(t *testing.T) {
+	raw := []byte("before:\n  deps: { cmd: [ 'go', 'mod', 'download' ] }\n" +
+		"  certs: { cmd: [ 'mkcert', 'localhost' ] }\n" +
+		"onExit:\n  deregister: { cmd: [ 'curl', '-X', 'DELETE', 'registry' ] }\n" +
+		"commands:\n  db:\n    up: { cmd: [ 'docker', 'compose', 'up' ] }\n" +
+		"    finally:\n      down: { cmd: [ 'docker', 'compose', 'down' ] }\n" +
+		"      unlock: { cmd: [ 'rm', '-f', 'db.lock' ], dir: var }\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	cfg.BaseDir = "/srv/app"
+
+	result, err := NewFlowBuilder().Build(cfg)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	db := result.Chains[0]
+	if len(db.Commands()) != 1 || len(db.Finally) != 2 {
+		t.Fatalf("команды %d, finally %d: finally не должен попасть в команды", len(db.Commands()), len(db.Finally))
+	}
+
+	if db.Finally[0].Name != "down" || db.Finally[1].Dir != filepath.Join("/srv/app", "var") {
+		t.Errorf("finally = %+v", db.Finally)
+	}
+
+	if len(result.OnExit) != 1 || result.OnExit[0].Name != "deregister" {
+		t.Errorf("onExit = %+v", result.OnExit)
+	}
+
+	if len(result.Before) != 2 || result.Before[1].Name != "certs" {
+		t.Errorf("before = %+v: порядок обязан повторять YAML", result.Before)
+	}
+}
+
+// TestUnmarshal_HooksNotMapping — список строк вместо команд не должен
+// превращаться в молча пропущенную подготовку или уборку.
+func TestUnmarshal_HooksNotMapping(t *testing.T) {
+	for name, raw := range map[string]string{
+		"finally": "commands:\n  db:\n    up: { cmd: [ 'echo' ] }\n    finally: [ 'docker compose down' ]\n",
+		"onExit":  "onExit: [ 'rm -f lock' ]\ncommands:\n  db:\n    up: { cmd: [ 'echo' ] }\n",
+		"before":  "before: 'go mod download'\ncommands:\n  db:\n    up: { cmd: [ 'echo' ] }\n",
+	} {
+		if _, err := (YamlFileMarshaller{}).Unmarshal([]byte(raw)); !errors.Is(err, ErrConfigDecode) {
+			t.Errorf("%s: ожидалась ErrConfigDecode, получено %v", name, err)
+		}
+	}
+}
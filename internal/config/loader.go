@@ -24,6 +24,8 @@ const (
 	failFastKey    = "failFast"
 	envFileKey     = "envFile"
 	maxParallelKey = "maxParallel"
+	formatKey      = "format"
+	includeKey     = "include"
 
 	// needsKey — зарезервированное имя внутри цепочки. Все остальные ключи
 	// там — имена команд, поэтому зависимость приходится обрабатывать
@@ -39,7 +41,10 @@ const (
 // а схема заморожена с v1.0.0.
 //
 //nolint:gochecknoglobals // неизменяемый список, константой объявить нельзя
-var knownTopLevelFields = []string{commandsKey, failFastKey, envFileKey, maxParallelKey}
+var knownTopLevelFields = []string{
+	commandsKey, failFastKey, envFileKey, maxParallelKey, logKey, formatKey, beforeKey, onExitKey, includeKey,
+	profilesKey, templatesKey,
+}
 
 // knownCommandFields — имена полей команды в том виде, в каком их пишут в YAML.
 // Список нужен только для подсказки при опечатке; источник истины — yaml-теги
@@ -48,7 +53,8 @@ var knownTopLevelFields = []string{commandsKey, failFastKey, envFileKey, maxPara
 //nolint:gochecknoglobals // неизменяемый список, массивом объявить нельзя
 var knownCommandFields = []string{
 	"cmd", "run", "docker", "dir", "pipe", "disable", "env", "format", "timeout",
-	"restart", "restartAttempts", "restartDelay", "envFile", "ready",
+	"restart", "restartAttempts", "restartDelay", "envFile", "ready", "watch",
+	"health", "extends", "use", "with",
 }
 
 // FileMarshaller разбирает содержимое файла конфигурации.
@@ -58,6 +64,15 @@ type FileMarshaller interface {
 
 type format struct {
 	CmdName string `yaml:"cmdName"`
+	// Timestamp разбирается строкой по той же причине, что и restart: на
+	// неизвестное значение нужен отказ со списком допустимых.
+	Timestamp string `yaml:"timestamp"`
+}
+
+// topFormat — верхнеуровневая секция format. Шаблон имени у каждой команды
+// свой, поэтому здесь только то, что имеет смысл задать для всех.
+type topFormat struct {
+	Timestamp string `yaml:"timestamp"`
 }
 
 type dockerCommand struct {
@@ -107,14 +122,57 @@ type command struct {
 
 	// Ready — признак готовности команды.
 	Ready *readyCondition `yaml:"ready"`
+
+	// Watch — файлы, изменение которых перезапускает команду.
+	Watch *watchSpec `yaml:"watch"`
+
+	// Health — проверка здоровья работающей команды.
+	Health *healthSpec `yaml:"health"`
+
+	// Extends — ссылка file#chain.command на команду, чьи поля наследуются.
+	// Разрешается загрузчиком и к сборке Flow уже пуста.
+	Extends string `yaml:"extends"`
+
+	// Use — имя шаблона, With — значения его параметров. Как и Extends,
+	// разворачиваются загрузчиком.
+	Use  string            `yaml:"use"`
+	With map[string]string `yaml:"with"`
+}
+
+// composed сообщает, собирается ли команда из чужих полей — предка или
+// шаблона.
+func (c command) composed() bool {
+	return c.Extends != "" || c.Use != "" || c.With != nil
 }
 
 // readyCondition — секция ready в конфигурации.
 type readyCondition struct {
-	TCP     string        `yaml:"tcp"`
-	Exec    []string      `yaml:"exec"`
-	LogLine string        `yaml:"logLine"`
-	Timeout time.Duration `yaml:"timeout"`
+	TCP     string            `yaml:"tcp"`
+	HTTP    *httpProbe        `yaml:"http"`
+	File    *fileProbe        `yaml:"file"`
+	Exec    []string          `yaml:"exec"`
+	LogLine *logLineSpec      `yaml:"logLine"`
+	All     []*readyCondition `yaml:"all"`
+	Any     []*readyCondition `yaml:"any"`
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// watchSpec — секция watch в конфигурации.
+type watchSpec struct {
+	Paths    stringList    `yaml:"paths"`
+	Ignore   stringList    `yaml:"ignore"`
+	Debounce time.Duration `yaml:"debounce"`
+}
+
+// healthSpec — секция health в конфигурации: одна проба в тех же записях,
+// что у ready, и настройки повтора рядом с ней.
+type healthSpec struct {
+	TCP       string        `yaml:"tcp"`
+	HTTP      *httpProbe    `yaml:"http"`
+	File      *fileProbe    `yaml:"file"`
+	Exec      []string      `yaml:"exec"`
+	Interval  time.Duration `yaml:"interval"`
+	Threshold int           `yaml:"threshold"`
 }
 
 // stringList принимает и одиночное значение, и список: envFile и needs пишут
@@ -176,14 +234,50 @@ func scalarString(node ast.Node) (string, error) {
 type NamedCommand struct {
 	Name string
 	Spec command
+
+	// node — исходный узел команды. Нужен extends: наследник накладывается на
+	// предка разбором своего узла поверх, и только так «поле не задано»
+	// отличается от «задано нулём» — pipe: false обязан перекрыть pipe: true.
+	node ast.Node
+
+	// layers — все узлы, из которых собрана Spec, от дальнего предка до
+	// перекрытия профилем; nil — только node. Профиль накладывается поверх
+	// них тем же способом, что и наследник поверх предка.
+	layers []ast.Node
+}
+
+// nodes возвращает узлы, из которых собрана спецификация команды.
+func (n NamedCommand) nodes() []ast.Node {
+	if n.layers != nil {
+		return n.layers
+	}
+
+	return []ast.Node{n.node}
 }
 
 // ChainConfig — упорядоченный набор команд под одним именем цепочки.
 type ChainConfig struct {
 	Name     string
 	Commands []NamedCommand
-	// Needs — имена цепочек, готовности которых надо дождаться.
+	// Needs — имена цепочек, которых надо дождаться, в порядке объявления.
 	Needs []string
+	// NeedConditions — условия из формы-отображения needs: имя → условие.
+	// Краткая форма needs условий не задаёт.
+	NeedConditions map[string]string
+	// Log — собственная секция журнала цепочки; nil — только умолчания.
+	Log *logSpec
+	// Finally — команды уборки цепочки в порядке объявления.
+	Finally []NamedCommand
+	// Matrix — оси матрицы; непусто — сборка разворачивает цепочку в одну
+	// на каждое сочетание значений.
+	Matrix []matrixAxis
+	// Tags — метки цепочки для отбора через @тег.
+	Tags []string
+	// Source — файл, из которого пришла цепочка; пусто — файл не известен, и
+	// пути разрешаются от Data.BaseDir. Цепочке из include относительные
+	// пути нужны от её собственного файла: фрагмент лежит рядом со своим
+	// сервисом, а не рядом с корневой конфигурацией.
+	Source string
 }
 
 // Data — упорядоченное представление разобранной конфигурации.
@@ -205,6 +299,35 @@ type Data struct {
 	// Ноль означает «без ограничения».
 	MaxParallel int
 
+	// Log — умолчания журналов цепочек; nil — секции нет.
+	Log *logSpec
+
+	// Before — команды, выполняемые по очереди до старта цепочек.
+	Before []NamedCommand
+
+	// OnExit — команды, выполняемые один раз в конце запуска.
+	OnExit []NamedCommand
+
+	// Include — шаблоны путей к файлам, чьи цепочки добавляются к этим.
+	// Разрешаются загрузчиком; Unmarshal их только читает.
+	Include []string
+
+	// Templates — заготовки команд с параметрами; разворачиваются загрузчиком.
+	Templates map[string]commandTemplate
+
+	// Profiles — именованные профили запуска. Применяется не больше одного,
+	// и выбирает его вызывающий — через WithProfile.
+	Profiles map[string]Profile
+
+	// Env — переменные выбранного профиля для всех команд; nil — профиль не
+	// выбран или переменных не задаёт.
+	Env map[string]string
+
+	// Timestamp — метка времени для всех команд; пусто — ключа нет. В команды
+	// не переносится: флаг -timestamp сильнее файла, но слабее команды, и
+	// свести их может только вызывающий.
+	Timestamp flow.TimestampMode
+
 	// TopLevelHints — предупреждения о ключах верхнего уровня, похожих на
 	// известные. Возвращаются данными, а не пишутся в лог: слой конфигурации
 	// логгера не имеет, и заводить его ради двух строк незачем.
@@ -215,6 +338,11 @@ type Data struct {
 	// процесса: конфигурация лежит рядом с проектом и коммитится вместе с ним,
 	// поэтому должна работать откуда угодно, а не только из «правильного» места.
 	BaseDir string
+
+	// Files — файлы, из которых собрана конфигурация: корневой, подключённые
+	// через include и прочитанные ради extends, в порядке чтения. Заполняется
+	// загрузчиком; за ними следит перечитывание.
+	Files []string
 }
 
 // FileLoader читает файл конфигурации и передаёт его разборщику.
@@ -226,7 +354,19 @@ func NewFileLoader(marshaller FileMarshaller) *FileLoader {
 	return &FileLoader{marshaller: marshaller}
 }
 
+// Load читает файл конфигурации вместе со всем, что он подключает через
+// include, и разрешает extends у команд.
 func (l *FileLoader) Load(filePath string) (Data, error) {
+	data, err := l.loadData(filePath)
+	if err != nil {
+		return Data{}, err
+	}
+
+	return compose(l, data, filePath)
+}
+
+// loadData читает и разбирает один файл, не трогая include и extends.
+func (l *FileLoader) loadData(filePath string) (Data, error) {
 	fileContent, err := l.loadFile(filePath)
 	if err != nil {
 		return Data{}, err
@@ -335,6 +475,18 @@ func parseData(body ast.Node) (Data, error) {
 
 	cfg.EnvFiles = envFiles
 
+	if cfg.Include, err = parseStringList(root, includeKey); err != nil {
+		return Data{}, err
+	}
+
+	if cfg.Profiles, err = parseProfiles(root); err != nil {
+		return Data{}, err
+	}
+
+	if cfg.Templates, err = parseTemplates(root); err != nil {
+		return Data{}, err
+	}
+
 	maxParallel, err := parseMaxParallel(root)
 	if err != nil {
 		return Data{}, err
@@ -342,6 +494,27 @@ func parseData(body ast.Node) (Data, error) {
 
 	cfg.MaxParallel = maxParallel
 
+	timestamp, err := parseTopFormat(root)
+	if err != nil {
+		return Data{}, err
+	}
+
+	cfg.Timestamp = timestamp
+
+	if node := lookup(root, logKey); node != nil {
+		if cfg.Log, err = parseLog(node, "top level"); err != nil {
+			return Data{}, err
+		}
+	}
+
+	if cfg.Before, err = parseCommands(lookup(root, beforeKey), fmt.Sprintf("%q", beforeKey), beforeKey); err != nil {
+		return Data{}, err
+	}
+
+	if cfg.OnExit, err = parseCommands(lookup(root, onExitKey), fmt.Sprintf("%q", onExitKey), onExitKey); err != nil {
+		return Data{}, err
+	}
+
 	commandsNode := lookup(root, commandsKey)
 	if commandsNode == nil {
 		return cfg, nil
@@ -381,14 +554,20 @@ func parseFailFast(root []*ast.MappingValueNode) (*bool, error) {
 
 // parseEnvFiles читает верхнеуровневый ключ envFile.
 func parseEnvFiles(root []*ast.MappingValueNode) ([]string, error) {
-	node := lookup(root, envFileKey)
+	return parseStringList(root, envFileKey)
+}
+
+// parseStringList читает верхнеуровневый ключ, который пишут и строкой, и
+// списком строк.
+func parseStringList(root []*ast.MappingValueNode, key string) ([]string, error) {
+	node := lookup(root, key)
 	if node == nil {
 		return nil, nil
 	}
 
 	var value stringList
 	if err := yaml.NodeToValue(node, &value, yaml.Strict()); err != nil {
-		return nil, fmt.Errorf("%w %q: %w", ErrConfigDecode, envFileKey, err)
+		return nil, fmt.Errorf("%w %q: %w", ErrConfigDecode, key, err)
 	}
 
 	return value, nil
@@ -401,17 +580,12 @@ func parseChain(entry *ast.MappingValueNode) (ChainConfig, error) {
 	for _, cmdEntry := range mappingValues(entry.Value) {
 		cmdName := cmdEntry.Key.GetToken().Value
 
-		// needs — единственный ключ внутри цепочки, который не является именем
-		// команды. Обрабатывается до общего пути, иначе попал бы в разбор
-		// спецификации и дал бы невнятную ошибку про тип значения.
-		if cmdName == needsKey {
-			needs, err := parseNeeds(cmdEntry.Value, chain.Name)
-			if err != nil {
-				return ChainConfig{}, err
-			}
-
-			chain.Needs = needs
+		reserved, err := parseChainKey(&chain, cmdEntry)
+		if err != nil {
+			return ChainConfig{}, err
+		}
 
+		if reserved {
 			continue
 		}
 
@@ -420,12 +594,64 @@ func parseChain(entry *ast.MappingValueNode) (ChainConfig, error) {
 			return ChainConfig{}, decodeError(cmdName, chain.Name, err)
 		}
 
-		chain.Commands = append(chain.Commands, NamedCommand{Name: cmdName, Spec: spec})
+		chain.Commands = append(chain.Commands, NamedCommand{Name: cmdName, Spec: spec, node: cmdEntry.Value})
 	}
 
 	return chain, nil
 }
 
+// parseChainKey разбирает ключ цепочки, который не является именем команды, —
+// needs, log, finally, matrix или tags — и сообщает, был ли это такой ключ.
+// Обрабатываются они до общего пути, иначе попали бы в разбор спецификации и
+// дали бы невнятную ошибку про тип значения.
+func parseChainKey(chain *ChainConfig, entry *ast.MappingValueNode) (bool, error) {
+	key := entry.Key.GetToken().Value
+
+	switch key {
+	case needsKey:
+	case logKey, finallyKey, matrixKey, tagsKey:
+		// До v1.0 внутри цепочки был зарезервирован один needs, и команды с
+		// именами более поздних ключей в существующей конфигурации обязаны
+		// остаться командами: схема заморожена. Записанное как команда
+		// командой и считается.
+		if isCommand(entry.Value) {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	var err error
+
+	switch key {
+	case needsKey:
+		chain.Needs, chain.NeedConditions, err = parseNeeds(entry.Value, chain.Name)
+	case logKey:
+		chain.Log, err = parseLog(entry.Value, fmt.Sprintf("chain %q", chain.Name))
+	case finallyKey:
+		where := fmt.Sprintf("%q in chain %q", finallyKey, chain.Name)
+		chain.Finally, err = parseCommands(entry.Value, where, chain.Name)
+	case matrixKey:
+		chain.Matrix, err = parseMatrix(entry.Value, chain.Name)
+	case tagsKey:
+		chain.Tags, err = parseTags(entry.Value, chain.Name)
+	}
+
+	return true, err
+}
+
+// isCommand сообщает, записан ли узел командой: строго разбирается в её поля
+// и задаёт, что запускать. Секции цепочки так не разбираются — у log и
+// matrix свои поля, finally и tags — вовсе не поля.
+func isCommand(node ast.Node) bool {
+	var spec command
+	if mappingValues(node) == nil || yaml.NodeToValue(node, &spec, yaml.Strict()) != nil {
+		return false
+	}
+
+	return len(spec.Cmd) > 0 || spec.Run != "" || spec.Docker != nil || spec.composed()
+}
+
 // parseMaxParallel читает верхнеуровневый ключ maxParallel.
 func parseMaxParallel(root []*ast.MappingValueNode) (int, error) {
 	node := lookup(root, maxParallelKey)
@@ -445,19 +671,61 @@ func parseMaxParallel(root []*ast.MappingValueNode) (int, error) {
 	return value, nil
 }
 
-// parseNeeds разбирает зависимости цепочки.
+// parseTopFormat читает верхнеуровневую секцию format.
+func parseTopFormat(root []*ast.MappingValueNode) (flow.TimestampMode, error) {
+	node := lookup(root, formatKey)
+	if node == nil {
+		return "", nil
+	}
+
+	var value topFormat
+	if err := yaml.NodeToValue(node, &value, yaml.Strict()); err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrConfigDecode, formatKey, err)
+	}
+
+	return flow.ParseTimestampMode(value.Timestamp)
+}
+
+// parseNeeds разбирает зависимости цепочки: список имён либо отображение
+// «имя → условие» вроде `{ db: ready, seed: completed_successfully }`.
 //
-// Сообщение об ошибке прямо называет needs зарезервированным: иначе автор
-// конфигурации, назвавший так команду, получил бы жалобу на тип значения
-// и не понял бы, при чём тут это.
-func parseNeeds(node ast.Node, chainName string) ([]string, error) {
+// Порядок ключей отображения сохраняется: он виден в предпросмотре и задаёт
+// порядок ожидания. Значения условий проверяет домен.
+func parseNeeds(node ast.Node, chainName string) ([]string, map[string]string, error) {
+	if values := mappingValues(node); values != nil {
+		needs := make([]string, 0, len(values))
+		conditions := make(map[string]string, len(values))
+
+		for _, entry := range values {
+			name := entry.Key.GetToken().Value
+
+			cond, err := scalarString(entry.Value)
+			if err != nil {
+				return nil, nil, needsError(chainName, fmt.Errorf("%q: %w", name, err))
+			}
+
+			needs = append(needs, name)
+			conditions[name] = cond
+		}
+
+		return needs, conditions, nil
+	}
+
 	var needs stringList
 	if err := yaml.NodeToValue(node, &needs, yaml.Strict()); err != nil {
-		return nil, fmt.Errorf("chain %q: %q is a reserved key for chain dependencies "+
-			"and must be a list of chain names: %w", chainName, needsKey, err)
+		return nil, nil, needsError(chainName, err)
 	}
 
-	return needs, nil
+	return needs, nil, nil
+}
+
+// needsError прямо называет needs зарезервированным: иначе автор
+// конфигурации, назвавший так команду, получил бы жалобу на тип значения
+// и не понял бы, при чём тут это.
+func needsError(chainName string, err error) error {
+	return fmt.Errorf("chain %q: %q is a reserved key for chain dependencies "+
+		"and must be a list of chain names or a mapping of chain names to conditions: %w",
+		chainName, needsKey, err)
 }
 
 // topLevelHints ищет ключи верхнего уровня, похожие на известные.
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// logKey — секция журнала: на верхнем уровне задаёт умолчания, внутри цепочки
+// — её собственный файл. Внутри цепочки ключ зарезервирован, как и needs.
+const logKey = "log"
+
+// chainPlaceholder подставляет имя цепочки в путь верхнеуровневого файла —
+// в той же манере, что %CMD_NAME% в format.cmdName.
+const chainPlaceholder = "%CHAIN%"
+
+// logSpec — секция log в конфигурации.
+//
+// Поля-указатели: секция цепочки наследует незаданное у верхнего уровня, и
+// `timestamps: false` в цепочке должен перекрывать `true` сверху, а не
+// сливаться с отсутствием ключа.
+type logSpec struct {
+	File       string    `yaml:"file"`
+	MaxSize    *byteSize `yaml:"maxSize"`
+	MaxFiles   *int      `yaml:"maxFiles"`
+	Timestamps *bool     `yaml:"timestamps"`
+}
+
+// byteSize — размер в байтах: число либо число с единицей (`10MB`, `512KiB`).
+// Единицы двоичные: KB и KiB означают одно и то же, как у большинства
+// утилит, с которыми размер файла журнала сравнивают.
+type byteSize int64
+
+// sizeUnits — множители единиц размера, от длинных суффиксов к коротким.
+//
+//nolint:gochecknoglobals // неизменяемая таблица, константой объявить нельзя
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+}
+
+// UnmarshalYAML разбирает узел сам: `10MB` для YAML — строка, а `1024` —
+// число, и оба должны давать размер.
+func (s *byteSize) UnmarshalYAML(node ast.Node) error {
+	raw, err := scalarString(node)
+	if err != nil {
+		return err
+	}
+
+	text := strings.ToUpper(strings.TrimSpace(raw))
+	factor := int64(1)
+
+	for _, unit := range sizeUnits {
+		if trimmed, ok := strings.CutSuffix(text, unit.suffix); ok {
+			text, factor = strings.TrimSpace(trimmed), unit.factor
+
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: size %q, expected a number of bytes or e.g. 10MB", ErrConfigDecode, raw)
+	}
+
+	*s = byteSize(n * factor)
+
+	return nil
+}
+
+// parseLog разбирает секцию log; where называет её место для сообщения об ошибке.
+func parseLog(node ast.Node, where string) (*logSpec, error) {
+	var spec logSpec
+	if err := yaml.NodeToValue(node, &spec, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("%s: %q must be a mapping with file, maxSize, maxFiles and timestamps: %w",
+			where, logKey, err)
+	}
+
+	return &spec, nil
+}
+
+// logOf сводит секции верхнего уровня и цепочки в файл журнала цепочки.
+//
+// Файл верхнего уровня обязан содержать %CHAIN%: один файл на все цепочки
+// вернул бы то самое перемешанное полотно, от которого файлы и спасают.
+func logOf(top, own *logSpec, chainName string, resolve func(string) string) (*flow.LogFile, error) {
+	if top == nil && own == nil {
+		return nil, nil //nolint:nilnil // отсутствие журнала — не ошибка
+	}
+
+	var merged logSpec
+
+	for _, spec := range []*logSpec{top, own} {
+		if spec == nil {
+			continue
+		}
+
+		if spec.File != "" {
+			merged.File = spec.File
+		}
+
+		merged.MaxSize = cmpOr(spec.MaxSize, merged.MaxSize)
+		merged.MaxFiles = cmpOr(spec.MaxFiles, merged.MaxFiles)
+		merged.Timestamps = cmpOr(spec.Timestamps, merged.Timestamps)
+	}
+
+	if merged.File == "" {
+		return nil, nil //nolint:nilnil // умолчания без файла журнала не включают
+	}
+
+	if (own == nil || own.File == "") && !strings.Contains(merged.File, chainPlaceholder) {
+		return nil, fmt.Errorf("%w: %q", ErrSharedLogFile, merged.File)
+	}
+
+	log := &flow.LogFile{Path: resolve(strings.ReplaceAll(merged.File, chainPlaceholder, chainName))}
+
+	if merged.MaxSize != nil {
+		log.MaxSize = int64(*merged.MaxSize)
+	}
+
+	if merged.MaxFiles != nil {
+		log.MaxFiles = *merged.MaxFiles
+	}
+
+	if merged.Timestamps != nil {
+		log.Timestamps = *merged.Timestamps
+	}
+
+	return log, nil
+}
+
+// cmpOr возвращает первый заданный указатель.
+func cmpOr[T any](own, inherited *T) *T {
+	if own != nil {
+		return own
+	}
+
+	return inherited
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// TestUnmarshal_Log — верхнеуровневые умолчания и собственная секция цепочки
+// сводятся в один файл, а `timestamps: false` цепочки перекрывает `true` сверху.
+func TestUnmarshal_Log(t *testing.T) {
+	raw := []byte("log:\n  file: 'logs/%CHAIN%.log'\n  maxSize: 10MB\n  timestamps: true\n" +
+		"commands:\n  api:\n    log:\n      maxFiles: 5\n      timestamps: false\n    serve:\n      cmd: [ 'go' ]\n" +
+		"  worker:\n    run:\n      cmd: [ 'go' ]\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	cfg.BaseDir = "/srv/app"
+
+	result, err := NewFlowBuilder().Build(cfg)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	logs := map[string]*flow.LogFile{}
+	for _, chain := range result.Chains {
+		logs[chain.Name] = chain.Log
+	}
+
+	api := logs["api"]
+	if api == nil || api.Path != filepath.Join("/srv/app", "logs", "api.log") ||
+		api.MaxSize != 10<<20 || api.MaxFiles != 5 || api.Timestamps {
+		t.Fatalf("журнал api собран неверно: %+v", api)
+	}
+
+	worker := logs["worker"]
+	if worker == nil || worker.Path != filepath.Join("/srv/app", "logs", "worker.log") ||
+		worker.MaxFiles != 0 || !worker.Timestamps {
+		t.Fatalf("журнал worker собран неверно: %+v", worker)
+	}
+}
+
+// TestUnmarshal_LogSharedFile — общий файл без %CHAIN% смешал бы вывод всех
+// цепочек, поэтому он ошибка; свой файл цепочки плейсхолдера не требует.
+func TestUnmarshal_LogSharedFile(t *testing.T) {
+	raw := []byte("log:\n  file: 'all.log'\ncommands:\n  api:\n    serve:\n      cmd: [ 'go' ]\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if _, err := NewFlowBuilder().Build(cfg); !errors.Is(err, ErrSharedLogFile) {
+		t.Fatalf("ожидалась ErrSharedLogFile, получено %v", err)
+	}
+
+	raw = []byte("log:\n  file: 'all.log'\ncommands:\n  api:\n    log:\n      file: 'api.log'\n" +
+		"    serve:\n      cmd: [ 'go' ]\n")
+
+	cfg, err = YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if _, err := NewFlowBuilder().Build(cfg); err != nil {
+		t.Fatalf("свой файл цепочки не должен требовать %%CHAIN%%: %v", err)
+	}
+}
+
+// TestUnmarshal_LogUnknownField — опечатка в секции не должна тихо выключать
+// ротацию.
+func TestUnmarshal_LogUnknownField(t *testing.T) {
+	raw := []byte("commands:\n  api:\n    log:\n      file: 'api.log'\n      maxsize: 1MB\n" +
+		"    serve:\n      cmd: [ 'go' ]\n")
+
+	_, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err == nil || !strings.Contains(err.Error(), "maxsize") {
+		t.Fatalf("ожидалась ошибка про неизвестное поле, получено %v", err)
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"1024":     1024,
+		"512B":     512,
+		"64k":      64 << 10,
+		"10MB":     10 << 20,
+		"10 MiB":   10 << 20,
+		"1GiB":     1 << 30,
+		"'2048'":   2048,
+		"3 gb":     3 << 30,
+		"'100 kB'": 100 << 10,
+	}
+
+	for in, want := range cases {
+		var spec struct {
+			Size byteSize `yaml:"size"`
+		}
+
+		if err := yaml.Unmarshal([]byte("size: "+in), &spec); err != nil {
+			t.Errorf("%s: %v", in, err)
+
+			continue
+		}
+
+		if int64(spec.Size) != want {
+			t.Errorf("%s: получено %d, ожидалось %d", in, spec.Size, want)
+		}
+	}
+
+	var bad struct {
+		Size byteSize `yaml:"size"`
+	}
+
+	if err := yaml.Unmarshal([]byte("size: ten megabytes"), &bad); !errors.Is(err, ErrConfigDecode) {
+		t.Fatalf("ожидалась ErrConfigDecode, получено %v", err)
+	}
+}
+
+// TestUnmarshal_CommandNamedLikeChainKey — ключи цепочки, появившиеся после
+// v1.0, не отнимают имя у существующих команд: записанное как команда
+// остаётся командой, а секция — секцией.
+func TestUnmarshal_CommandNamedLikeChainKey(t *testing.T) {
+	for _, key := range []string{logKey, finallyKey, matrixKey, tagsKey} {
+		t.Run(key, func(t *testing.T) {
+			raw := []byte("commands:\n  app:\n    " + key + ":\n      run: 'tail -f app.log'\n" +
+				"    serve:\n      cmd: [ 'go' ]\n")
+
+			cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+			if err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			chain := cfg.Chains[0]
+			if len(chain.Commands) != 2 || chain.Commands[0].Name != key || chain.Commands[0].Spec.Run == "" {
+				t.Fatalf("команда %q должна остаться командой: %+v", key, chain.Commands)
+			}
+
+			if chain.Log != nil || chain.Finally != nil || chain.Matrix != nil || chain.Tags != nil {
+				t.Errorf("команда %q разобрана ещё и как секция: %+v", key, chain)
+			}
+		})
+	}
+}
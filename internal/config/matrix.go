@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// matrixKey — зарезервированное имя внутри цепочки, как needs и log.
+const matrixKey = "matrix"
+
+// matrixPrefix — пространство имён значений матрицы в подстановке:
+// ${matrix.go}. Точка в имени переменной окружения невозможна, поэтому
+// значения матрицы не пересекаются ни с одной из них.
+const matrixPrefix = "matrix."
+
+// matrixNameRe — допустимое имя оси: оно становится частью ${matrix.имя}.
+//
+//nolint:gochecknoglobals // скомпилированное выражение, константой объявить нельзя
+var matrixNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// matrixAxis — одна ось матрицы: имя и значения в порядке объявления.
+type matrixAxis struct {
+	name   string
+	values []string
+}
+
+// matrixCell — одно сочетание значений: пары в порядке осей.
+type matrixCell []matrixPair
+
+type matrixPair struct {
+	name, value string
+}
+
+// parseMatrix разбирает секцию matrix цепочки.
+//
+// Оси читаются по узлам, а не в мапу: порядок осей задаёт и порядок
+// сочетаний, и вид имени цепочки, и от перестановки ключей в Go-мапе он
+// меняться не должен. Значения берутся как записаны: 1.20 — версия, а не
+// число 1.2.
+func parseMatrix(node ast.Node, chainName string) ([]matrixAxis, error) {
+	entries := mappingValues(node)
+	if entries == nil {
+		return nil, fmt.Errorf("%w chain %q: %q must be a mapping of axes to values",
+			ErrMatrix, chainName, matrixKey)
+	}
+
+	axes := make([]matrixAxis, 0, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Key.GetToken().Value
+		if !matrixNameRe.MatchString(name) {
+			return nil, fmt.Errorf("%w chain %q: axis name %q must be a letter or _ followed by letters, "+
+				"digits or _", ErrMatrix, chainName, name)
+		}
+
+		var values stringList
+		if err := values.UnmarshalYAML(entry.Value); err != nil {
+			return nil, fmt.Errorf("chain %q, %s %q: %w", chainName, matrixKey, name, err)
+		}
+
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w chain %q: axis %q has no values", ErrMatrix, chainName, name)
+		}
+
+		axes = append(axes, matrixAxis{name: name, values: values})
+	}
+
+	return axes, nil
+}
+
+// matrixCells перечисляет все сочетания значений осей: первая ось меняется
+// медленнее всех, как вложенные циклы в порядке объявления.
+func matrixCells(axes []matrixAxis) []matrixCell {
+	cells := []matrixCell{nil}
+
+	for _, axis := range axes {
+		next := make([]matrixCell, 0, len(cells)*len(axis.values))
+
+		for _, cell := range cells {
+			for _, value := range axis.values {
+				grown := append(append(matrixCell{}, cell...), matrixPair{name: axis.name, value: value})
+				next = append(next, grown)
+			}
+		}
+
+		cells = next
+	}
+
+	return cells
+}
+
+// chainName — имя цепочки сочетания: test[go=1.26,db=pg]. У пустого
+// сочетания — цепочки без матрицы — имя остаётся прежним.
+func (c matrixCell) chainName(base string) string {
+	if len(c) == 0 {
+		return base
+	}
+
+	pairs := make([]string, 0, len(c))
+	for _, p := range c {
+		pairs = append(pairs, p.name+"="+p.value)
+	}
+
+	return base + "[" + strings.Join(pairs, ",") + "]"
+}
+
+// vars — значения сочетания для подстановки: matrix.go → 1.26.
+func (c matrixCell) vars() map[string]string {
+	if len(c) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(c))
+	for _, p := range c {
+		out[matrixPrefix+p.name] = p.value
+	}
+
+	return out
+}
+
+// expandMatrix подставляет в тело run только значения матрицы.
+//
+// Остальное тело run раскрывает оболочка, и трогать его нельзя. ${matrix.go}
+// — другое дело: оболочка такое имя не разберёт вовсе, так что двойного
+// раскрытия здесь быть не может.
+func expandMatrix(s string, lookup map[string]string) (string, error) {
+	return substitute(s, lookup, func(name string) bool { return !strings.HasPrefix(name, matrixPrefix) })
+}
+
+// expandNeeds заменяет в зависимостях имя цепочки с матрицей всеми её
+// сочетаниями: «дождаться test» значит дождаться каждого test[...]. Ссылка
+// на команду — test.unit — разворачивается так же, в test[...].unit.
+func expandNeeds(
+	needs []string, conditions map[string]string, groups map[string][]string,
+) ([]string, map[string]string) {
+	if len(groups) == 0 {
+		return needs, conditions
+	}
+
+	var (
+		outNeeds []string
+		outConds map[string]string
+	)
+
+	if conditions != nil {
+		outConds = make(map[string]string, len(conditions))
+	}
+
+	for _, need := range needs {
+		chain, command, hasCommand := strings.Cut(need, ".")
+
+		members, ok := groups[chain]
+		if !ok {
+			members = []string{chain}
+		}
+
+		for _, member := range members {
+			name := member
+			if hasCommand {
+				name += "." + command
+			}
+
+			outNeeds = append(outNeeds, name)
+
+			if cond, ok := conditions[need]; ok {
+				outConds[name] = cond
+			}
+		}
+	}
+
+	return outNeeds, outConds
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestBuild_Matrix — одна цепочка разворачивается в сочетания: оси в порядке
+// объявления, значения как записаны, ${matrix.*} подставлен в cmd, env и run.
+func TestBuild_Matrix(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"parallel.yaml": "commands:\n" +
+		"  test:\n" +
+		"    matrix: { go: [ 1.25, 1.20 ], db: [ pg, mysql ] }\n" +
+		"    unit: { cmd: [ 'go', 'test', '-tags=${matrix.db}' ], env: { GO: '${matrix.go}' } }\n" +
+		"    lint: { run: 'echo ${matrix.db} $HOME' }\n" +
+		"  report:\n" +
+		"    needs: { test.unit: completed_successfully }\n" +
+		"    all: { cmd: [ 'echo' ] }\n",
+	})
+
+	result, err := loadFlow(t, filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := []string{
+		"test[go=1.25,db=pg]", "test[go=1.25,db=mysql]",
+		"test[go=1.20,db=pg]", "test[go=1.20,db=mysql]", "report",
+	}
+	if names := result.Names(); !slices.Equal(names, want) {
+		t.Fatalf("цепочки = %v, ожидалось %v", names, want)
+	}
+
+	for i, chain := range result.Chains {
+		if chain.ColorIdx != i {
+			t.Errorf("%s: ColorIdx = %d, у каждого сочетания свой цвет", chain.Name, chain.ColorIdx)
+		}
+	}
+
+	unit, lint := result.Chains[3].Commands()[0], result.Chains[3].Commands()[1]
+
+	if got := strings.Join(unit.Args, " "); got != "test -tags=mysql" {
+		t.Errorf("args = %q", got)
+	}
+
+	if !slices.Contains(unit.Env, "GO=1.20") {
+		t.Errorf("env = %v", unit.Env)
+	}
+
+	// В run подставляется только матрица: $HOME раскроет оболочка.
+	if got := strings.Join(lint.Args, " "); !strings.Contains(got, "echo mysql $HOME") {
+		t.Errorf("run = %q", got)
+	}
+
+	report := result.Chains[4]
+	if len(report.Needs) != 4 || report.Needs[0] != "test[go=1.25,db=pg].unit" {
+		t.Errorf("needs = %v: зависимость от test — это зависимость от каждого сочетания", report.Needs)
+	}
+
+	for _, need := range report.Needs {
+		if report.NeedConditions[need] == "" {
+			t.Errorf("у %s потеряно условие: %v", need, report.NeedConditions)
+		}
+	}
+}
+
+func TestBuild_MatrixErrors(t *testing.T) {
+	tests := map[string]struct {
+		chain string
+		want  error
+	}{
+		"не мапа":        {"    matrix: [ pg ]\n    up: { cmd: [ 'echo' ] }\n", ErrMatrix},
+		"пустая ось":     {"    matrix: { db: [] }\n    up: { cmd: [ 'echo' ] }\n", ErrMatrix},
+		"плохое имя оси": {"    matrix: { db-name: [ pg ] }\n    up: { cmd: [ 'echo' ] }\n", ErrMatrix},
+		"нет оси": {
+			"    matrix: { db: [ pg ] }\n    up: { cmd: [ 'echo', '${matrix.go}' ] }\n",
+			ErrUndefinedVariable,
+		},
+		"без матрицы": {"    up: { cmd: [ 'echo', '${matrix.db}' ] }\n", ErrUndefinedVariable},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{"parallel.yaml": "commands:\n  x:\n" + tt.chain})
+
+			if _, err := loadFlow(t, filepath.Join(dir, "parallel.yaml")); !errors.Is(err, tt.want) {
+				t.Errorf("ожидалась %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// profilesKey — верхнеуровневый ключ именованных профилей.
+const profilesKey = "profiles"
+
+// Profile — именованный набор отличий одного вида запуска (dev, CI, демо) от
+// конфигурации как она записана.
+//
+// Профиль живёт в том же файле, а не в соседнем: три почти одинаковых файла
+// расходятся при первой же правке, которую внесли только в один из них.
+type Profile struct {
+	// Chains — цепочки, запускаемые по умолчанию; пусто — все. Позиционные
+	// аргументы сильнее.
+	Chains []string
+	// Except — цепочки, которые профиль не запускает; -except добавляется к ним.
+	Except []string
+	// Env — переменные для всех команд, сильнее верхнеуровневых envFile.
+	Env map[string]string
+	// MaxParallel и FailFast перекрывают одноимённые ключи; nil — не задано.
+	MaxParallel *int
+	FailFast    *bool
+
+	// commands — поля команд, которые профиль перекрывает, в порядке объявления.
+	commands []profileCommand
+}
+
+// profileCommand — перекрытие полей одной команды: chain.command и узел с
+// полями, который накладывается поверх её собственного.
+type profileCommand struct {
+	ref  string
+	node ast.Node
+}
+
+// profileSpec — форма профиля в YAML. commands разбирается отдельно, по
+// узлам, но объявлена и здесь, чтобы строгий разбор её пропустил.
+type profileSpec struct {
+	Chains      stringList        `yaml:"chains"`
+	Except      stringList        `yaml:"except"`
+	Env         map[string]string `yaml:"env"`
+	MaxParallel *int              `yaml:"maxParallel"`
+	FailFast    *bool             `yaml:"failFast"`
+	Commands    map[string]any    `yaml:"commands"`
+}
+
+// parseProfiles читает верхнеуровневый ключ profiles.
+//
+// Перекрытия команд разбираются сразу, у всех профилей, а не только у
+// выбранного: опечатка в профиле CI должна всплыть у того, кто её внёс, а не
+// в пайплайне.
+func parseProfiles(root []*ast.MappingValueNode) (map[string]Profile, error) {
+	node := lookup(root, profilesKey)
+	if node == nil || node.Type() == ast.NullType {
+		return nil, nil
+	}
+
+	entries := mappingValues(node)
+	if entries == nil {
+		return nil, fmt.Errorf("%w %q: expected a mapping of profiles, got %s",
+			ErrConfigDecode, profilesKey, node.Type())
+	}
+
+	profiles := make(map[string]Profile, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Key.GetToken().Value
+
+		profile, err := parseProfile(entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+
+		profiles[name] = profile
+	}
+
+	return profiles, nil
+}
+
+// parseProfile разбирает один профиль.
+func parseProfile(node ast.Node) (Profile, error) {
+	var spec profileSpec
+	if err := yaml.NodeToValue(node, &spec, yaml.Strict()); err != nil {
+		return Profile{}, err
+	}
+
+	if spec.MaxParallel != nil && *spec.MaxParallel < 0 {
+		return Profile{}, fmt.Errorf("%w: %s is %d", ErrNegativeValue, maxParallelKey, *spec.MaxParallel)
+	}
+
+	profile := Profile{
+		Chains:      spec.Chains,
+		Except:      spec.Except,
+		Env:         spec.Env,
+		MaxParallel: spec.MaxParallel,
+		FailFast:    spec.FailFast,
+	}
+
+	for _, entry := range mappingValues(lookup(mappingValues(node), commandsKey)) {
+		ref := entry.Key.GetToken().Value
+		chainName, cmdName, _ := strings.Cut(ref, ".")
+
+		var fields command
+		if err := yaml.NodeToValue(entry.Value, &fields, yaml.Strict()); err != nil {
+			return Profile{}, decodeError(cmdName, chainName, err)
+		}
+
+		// Профиль правит поля, а не родословную: extends или use, подменённые
+		// профилем, меняли бы заодно всё, что команда унаследовала.
+		if fields.composed() {
+			return Profile{}, fmt.Errorf("%w: command %q cannot change 'extends', 'use' or 'with'",
+				ErrProfileTarget, ref)
+		}
+
+		profile.commands = append(profile.commands, profileCommand{ref: ref, node: entry.Value})
+	}
+
+	return profile, nil
+}
+
+// WithProfile возвращает конфигурацию с наложенным профилем name и сам
+// профиль: отбор цепочек из него применяет вызывающий, вместе с аргументами
+// командной строки. Пустое имя — профиль не выбран, конфигурация как есть.
+//
+// Исходная Data не меняется: срезы цепочек и команд, которые профиль правит,
+// копируются.
+func (d Data) WithProfile(name string) (Data, Profile, error) {
+	if name == "" {
+		return d, Profile{}, nil
+	}
+
+	profile, ok := d.Profiles[name]
+	if !ok {
+		return Data{}, Profile{}, unknownProfile(name, d.Profiles)
+	}
+
+	if profile.FailFast != nil {
+		d.FailFast = profile.FailFast
+	}
+
+	if profile.MaxParallel != nil {
+		d.MaxParallel = *profile.MaxParallel
+	}
+
+	d.Env = profile.Env
+	d.Chains = slices.Clone(d.Chains)
+
+	for _, override := range profile.commands {
+		if err := d.overrideCommand(override); err != nil {
+			return Data{}, Profile{}, fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+
+	return d, profile, nil
+}
+
+// overrideCommand накладывает поля профиля на команду chain.command.
+func (d *Data) overrideCommand(override profileCommand) error {
+	for i := range d.Chains {
+		chain := &d.Chains[i]
+
+		for j, cmd := range chain.Commands {
+			if chain.Name+"."+cmd.Name != override.ref {
+				continue
+			}
+
+			nodes := append(slices.Clone(cmd.nodes()), override.node)
+
+			spec, err := overlay(nodes)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrConfigDecode, decodeError(cmd.Name, chain.Name, err))
+			}
+
+			chain.Commands = slices.Clone(chain.Commands)
+			chain.Commands[j].Spec, chain.Commands[j].layers = spec, nodes
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: no command %q", ErrProfileTarget, override.ref)
+}
+
+// unknownProfile перечисляет доступные профили: опечатку в имени проще
+// увидеть рядом с правильным написанием.
+func unknownProfile(name string, profiles map[string]Profile) error {
+	if len(profiles) == 0 {
+		return fmt.Errorf("%w %q: the configuration defines no profiles", ErrUnknownProfile, name)
+	}
+
+	names := slices.Sorted(maps.Keys(profiles))
+
+	return fmt.Errorf("%w %q, available: %s", ErrUnknownProfile, name, strings.Join(names, ", "))
+}
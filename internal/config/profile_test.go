@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const profileYAML = `envFile: .env
+maxParallel: 4
+profiles:
+  ci:
+    env: { CI: 'true', MODE: ci }
+    maxParallel: 1
+    failFast: false
+    commands:
+      api.serve: { pipe: false, timeout: 5m, env: { PORT: '9000' } }
+      api.test: { run: 'go test -race ./...' }
+  demo:
+    chains: api
+commands:
+  api:
+    serve: { cmd: [ 'go', 'run', '.' ], pipe: true, env: { PORT: '8080' } }
+    test: { extends: '#api.serve', cmd: [ 'go', 'test', './...' ] }
+`
+
+// TestWithProfile — профиль перекрывает ключи запуска, добавляет окружение и
+// правит поля команд тем же наложением, что и extends.
+func TestWithProfile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"parallel.yaml": profileYAML, ".env": "MODE=dev\nDB=local\n"})
+
+	data, err := NewFileLoader(YamlFileMarshaller{}).Load(filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	ci, profile, err := data.WithProfile("ci")
+	if err != nil {
+		t.Fatalf("profile: %v", err)
+	}
+
+	if ci.MaxParallel != 1 || ci.FailFast == nil || *ci.FailFast {
+		t.Errorf("maxParallel = %d, failFast = %v", ci.MaxParallel, ci.FailFast)
+	}
+
+	if data.MaxParallel != 4 {
+		t.Errorf("исходная Data изменена: maxParallel = %d", data.MaxParallel)
+	}
+
+	if len(profile.Chains) != 0 {
+		t.Errorf("отбор профиля = %v", profile.Chains)
+	}
+
+	result, err := NewFlowBuilder().Build(ci)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	serve, test := result.Chains[0].Commands()[0], result.Chains[0].Commands()[1]
+
+	if serve.Pipe || serve.Timeout.Minutes() != 5 {
+		t.Errorf("serve = %+v: pipe и timeout из профиля", serve)
+	}
+
+	for _, want := range []string{"PORT=9000", "CI=true", "MODE=ci", "DB=local"} {
+		if !slices.Contains(serve.Env, want) {
+			t.Errorf("env serve = %v, нет %s", serve.Env, want)
+		}
+	}
+
+	// run профиля заменяет cmd, собранный через extends, а остальное
+	// унаследованное остаётся.
+	if test.Cmd == "go" || !test.Pipe {
+		t.Errorf("test = %+v", test)
+	}
+
+	// Соседний профиль не задевает ни команды, ни окружение.
+	plain, err := NewFlowBuilder().Build(data)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if cmd := plain.Chains[0].Commands()[0]; !cmd.Pipe || slices.Contains(cmd.Env, "CI=true") {
+		t.Errorf("без профиля команда изменена: %+v", cmd)
+	}
+}
+
+func TestWithProfile_Errors(t *testing.T) {
+	tests := map[string]struct {
+		profiles string
+		name     string
+		want     error
+	}{
+		"нет профиля":       {"  ci: {}\n", "dmeo", ErrUnknownProfile},
+		"нет команды":       {"  ci: { commands: { api.nope: { pipe: true } } }\n", "ci", ErrProfileTarget},
+		"extends в профиле": {"  ci: { commands: { api.up: { extends: '#api.up' } } }\n", "ci", ErrProfileTarget},
+		"лишнее поле":       {"  ci: { chain: [ api ] }\n", "ci", ErrConfigDecode},
+		"поле команды":      {"  ci: { commands: { api.up: { pipe: maybe } } }\n", "ci", ErrConfigDecode},
+		"отрицательное":     {"  ci: { maxParallel: -1 }\n", "ci", ErrNegativeValue},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"parallel.yaml": "profiles:\n" + tt.profiles + "commands:\n  api:\n    up: { cmd: [ 'echo' ] }\n",
+			})
+
+			data, err := NewFileLoader(YamlFileMarshaller{}).Load(filepath.Join(dir, "parallel.yaml"))
+			if err == nil {
+				_, _, err = data.WithProfile(tt.name)
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ожидалась %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
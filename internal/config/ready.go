@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// httpProbe — проба ready.http в конфигурации.
+//
+// Пишется либо строкой-адресом (`http: 'http://localhost:8080/healthz'`),
+// либо секцией с кодами, телом и заголовками: адреса хватает в большинстве
+// случаев, и требовать ради него секцию было бы придиркой.
+type httpProbe struct {
+	URL       string            `yaml:"url"`
+	Status    *statusRange      `yaml:"status"`
+	Body      string            `yaml:"body"`
+	BodyRegex string            `yaml:"bodyRegex"`
+	Headers   map[string]string `yaml:"headers"`
+	Insecure  bool              `yaml:"insecure"`
+}
+
+// UnmarshalYAML принимает обе формы записи.
+func (p *httpProbe) UnmarshalYAML(node ast.Node) error {
+	if str, ok := node.(*ast.StringNode); ok {
+		*p = httpProbe{URL: str.Value}
+
+		return nil
+	}
+
+	// Отдельный тип без метода, иначе разбор секции вернулся бы сюда же.
+	type plain httpProbe
+
+	var spec plain
+	if err := yaml.NodeToValue(node, &spec, yaml.Strict()); err != nil {
+		return fmt.Errorf("%w: ready.http must be a URL or a mapping with url, status, body, bodyRegex, "+
+			"headers and insecure: %w", ErrConfigDecode, err)
+	}
+
+	*p = httpProbe(spec)
+
+	return nil
+}
+
+// fileProbe — условие ready.file в конфигурации: путь строкой либо секция
+// с path и nonEmpty.
+type fileProbe struct {
+	Path     string `yaml:"path"`
+	NonEmpty bool   `yaml:"nonEmpty"`
+}
+
+// UnmarshalYAML принимает обе формы записи.
+func (p *fileProbe) UnmarshalYAML(node ast.Node) error {
+	if str, ok := node.(*ast.StringNode); ok {
+		*p = fileProbe{Path: str.Value}
+
+		return nil
+	}
+
+	type plain fileProbe
+
+	var spec plain
+	if err := yaml.NodeToValue(node, &spec, yaml.Strict()); err != nil {
+		return fmt.Errorf("%w: ready.file must be a path or a mapping with path and nonEmpty: %w",
+			ErrConfigDecode, err)
+	}
+
+	*p = fileProbe(spec)
+
+	return nil
+}
+
+// logLineSpec — условие ready.logLine: подстрока строкой либо секция с
+// регулярным выражением, чьи именованные группы становятся переменными
+// цепочки.
+type logLineSpec struct {
+	Text  string `yaml:"-"`
+	Regex string `yaml:"regex"`
+}
+
+// UnmarshalYAML принимает обе формы записи.
+func (l *logLineSpec) UnmarshalYAML(node ast.Node) error {
+	if _, ok := node.(*ast.MappingNode); !ok {
+		text, err := scalarString(node)
+		if err != nil {
+			return err
+		}
+
+		*l = logLineSpec{Text: text}
+
+		return nil
+	}
+
+	type plain logLineSpec
+
+	var spec plain
+	if err := yaml.NodeToValue(node, &spec, yaml.Strict()); err != nil {
+		return fmt.Errorf("%w: ready.logLine must be a text or a mapping with regex: %w", ErrConfigDecode, err)
+	}
+
+	*l = logLineSpec(spec)
+
+	return nil
+}
+
+// fileOf переводит условие файла конфигурации в доменное.
+func fileOf(spec *fileProbe) *flow.FileProbe {
+	if spec == nil {
+		return nil
+	}
+
+	return &flow.FileProbe{Path: spec.Path, NonEmpty: spec.NonEmpty}
+}
+
+// statusRange — допустимые коды ответа: `200`, `200-399` или `2xx`.
+type statusRange struct {
+	low, high int
+}
+
+// statusClassWidth — сколько кодов в классе вида 2xx.
+const statusClassWidth = 100
+
+// UnmarshalYAML разбирает узел сам: `200` для YAML — число, а `200-399` и
+// `2xx` — строки, и все три должны давать диапазон.
+func (r *statusRange) UnmarshalYAML(node ast.Node) error {
+	raw, err := scalarString(node)
+	if err != nil {
+		return err
+	}
+
+	text := strings.ToLower(strings.TrimSpace(raw))
+
+	if class, ok := strings.CutSuffix(text, "xx"); ok {
+		n, err := strconv.Atoi(class)
+		if err != nil || len(class) != 1 {
+			return fmt.Errorf("%w: status %q, expected e.g. 200, 200-399 or 2xx", ErrConfigDecode, raw)
+		}
+
+		r.low, r.high = n*statusClassWidth, n*statusClassWidth+statusClassWidth-1
+
+		return nil
+	}
+
+	lowText, highText, isRange := strings.Cut(text, "-")
+	if !isRange {
+		highText = lowText
+	}
+
+	low, errLow := strconv.Atoi(strings.TrimSpace(lowText))
+	high, errHigh := strconv.Atoi(strings.TrimSpace(highText))
+
+	if errLow != nil || errHigh != nil {
+		return fmt.Errorf("%w: status %q, expected e.g. 200, 200-399 or 2xx", ErrConfigDecode, raw)
+	}
+
+	r.low, r.high = low, high
+
+	return nil
+}
+
+// httpOf переводит пробу конфигурации в доменную. Проверку адреса и кодов
+// делает домен: правило принадлежит ему.
+func httpOf(spec *httpProbe) *flow.HTTPProbe {
+	if spec == nil {
+		return nil
+	}
+
+	probe := &flow.HTTPProbe{
+		URL:       spec.URL,
+		Body:      spec.Body,
+		BodyRegex: spec.BodyRegex,
+		Headers:   maps.Clone(spec.Headers),
+		Insecure:  spec.Insecure,
+	}
+
+	if spec.Status != nil {
+		probe.StatusMin, probe.StatusMax = spec.Status.low, spec.Status.high
+	}
+
+	return probe
+}
+
+// expandHTTP подставляет переменные в адрес, заголовки и ожидаемое тело.
+// Регулярное выражение не трогается: `${` в нём — скорее квантификатор, чем
+// переменная.
+func expandHTTP(probe *flow.HTTPProbe, lookup map[string]string) error {
+	if probe == nil {
+		return nil
+	}
+
+	var err error
+
+	if probe.URL, err = expand(probe.URL, lookup); err != nil {
+		return err
+	}
+
+	if probe.Body, err = expand(probe.Body, lookup); err != nil {
+		return err
+	}
+
+	for key, value := range probe.Headers {
+		if probe.Headers[key], err = expand(value, lookup); err != nil {
+			return fmt.Errorf("header %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
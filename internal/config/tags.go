@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// tagsKey — зарезервированное имя внутри цепочки, как needs и matrix.
+const tagsKey = "tags"
+
+// tagNameRe — допустимый тег. Запятая разделяет имена в -except, пробел —
+// аргументы, а @ открывает тег при отборе, поэтому ни одного из них в теге
+// быть не может.
+//
+//nolint:gochecknoglobals // скомпилированное выражение, константой объявить нельзя
+var tagNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// parseTags разбирает теги цепочки: строкой или списком строк.
+func parseTags(node ast.Node, chainName string) ([]string, error) {
+	var tags stringList
+	if err := yaml.NodeToValue(node, &tags, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("%w chain %q, %q: %w", ErrConfigDecode, chainName, tagsKey, err)
+	}
+
+	for _, tag := range tags {
+		if !tagNameRe.MatchString(tag) {
+			return nil, fmt.Errorf("%w chain %q: tag %q may contain only letters, digits, '_', '.' and '-'",
+				ErrTag, chainName, tag)
+		}
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestBuild_Tags — теги пишут строкой или списком, и каждое сочетание
+// матрицы получает теги своей цепочки.
+func TestBuild_Tags(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"parallel.yaml": "commands:\n" +
+		"  db:\n    tags: infra\n    up: { cmd: [ 'echo' ] }\n" +
+		"  test:\n    tags: [ backend, ci ]\n    matrix: { db: [ pg, mysql ] }\n    unit: { cmd: [ 'echo' ] }\n",
+	})
+
+	result, err := loadFlow(t, filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	want := [][]string{{"infra"}, {"backend", "ci"}, {"backend", "ci"}}
+	for i, chain := range result.Chains {
+		if !slices.Equal(chain.Tags, want[i]) {
+			t.Errorf("%s: теги = %v, ожидалось %v", chain.Name, chain.Tags, want[i])
+		}
+	}
+}
+
+func TestBuild_TagsErrors(t *testing.T) {
+	tests := map[string]struct {
+		tags string
+		want error
+	}{
+		"запятая":     {"[ 'a,b' ]", ErrTag},
+		"собака":      {"[ '@a' ]", ErrTag},
+		"пустой":      {"[ '' ]", ErrTag},
+		"не строки":   {"{ a: b }", ErrConfigDecode},
+		"вложенность": {"[ [ a ] ]", ErrConfigDecode},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := writeFiles(t, map[string]string{
+				"parallel.yaml": "commands:\n  x:\n    tags: " + tt.tags + "\n    up: { cmd: [ 'echo' ] }\n",
+			})
+
+			if _, err := loadFlow(t, filepath.Join(dir, "parallel.yaml")); !errors.Is(err, tt.want) {
+				t.Errorf("ожидалась %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Ключи шаблонов: верхнеуровневый templates и объявление параметров внутри
+// шаблона.
+const (
+	templatesKey = "templates"
+	paramsKey    = "params"
+)
+
+// commandTemplate — заготовка команды с параметрами.
+//
+// Шаблон хранится узлом, а не разобранной командой: параметры подставляются
+// в строки YAML до разбора, и `${port}` может стоять где угодно — в cmd, в
+// адресе ready, в значении env. Якоря YAML так не умеют: строку они
+// переиспользуют только целиком.
+type commandTemplate struct {
+	// params — объявленные параметры. Только они и подставляются: прочие
+	// ${...} в шаблоне — переменные окружения.
+	params []string
+	// node — поля команды, без params.
+	node ast.Node
+}
+
+// parseTemplates читает верхнеуровневый ключ templates.
+func parseTemplates(root []*ast.MappingValueNode) (map[string]commandTemplate, error) {
+	node := lookup(root, templatesKey)
+	if node == nil || node.Type() == ast.NullType {
+		return nil, nil
+	}
+
+	entries := mappingValues(node)
+	if entries == nil {
+		return nil, fmt.Errorf("%w %q: expected a mapping of templates, got %s",
+			ErrConfigDecode, templatesKey, node.Type())
+	}
+
+	templates := make(map[string]commandTemplate, len(entries))
+
+	for _, entry := range entries {
+		name := entry.Key.GetToken().Value
+
+		tpl, err := parseTemplate(entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", name, err)
+		}
+
+		templates[name] = tpl
+	}
+
+	return templates, nil
+}
+
+// parseTemplate отделяет объявление параметров от полей команды.
+//
+// Сами поля здесь не проверяются: `restartAttempts: ${n}` станет числом только
+// после подстановки, и проверить шаблон можно лишь вместе с её значениями.
+func parseTemplate(node ast.Node) (commandTemplate, error) {
+	fields := mappingValues(node)
+	if fields == nil {
+		return commandTemplate{}, fmt.Errorf("%w: expected a mapping of command fields, got %s",
+			ErrConfigDecode, node.Type())
+	}
+
+	// Шаблон — начало родословной команды, а не её продолжение: цепочка из
+	// шаблонов и extends читалась бы хуже, чем повторённые поля.
+	for _, key := range []string{"use", "extends"} {
+		if lookup(fields, key) != nil {
+			return commandTemplate{}, fmt.Errorf("%w: a template cannot have %q", ErrTemplateUse, key)
+		}
+	}
+
+	var tpl commandTemplate
+
+	if params := lookup(fields, paramsKey); params != nil {
+		var names stringList
+		if err := yaml.NodeToValue(params, &names, yaml.Strict()); err != nil {
+			return commandTemplate{}, fmt.Errorf("%w %q: %w", ErrConfigDecode, paramsKey, err)
+		}
+
+		tpl.params = names
+	}
+
+	body, err := cloneNode(node)
+	if err != nil {
+		return commandTemplate{}, err
+	}
+
+	switch body := body.(type) {
+	case *ast.MappingNode:
+		body.Values = slices.DeleteFunc(body.Values, func(v *ast.MappingValueNode) bool {
+			return v.Key.GetToken().Value == paramsKey
+		})
+	case *ast.MappingValueNode:
+		if body.Key.GetToken().Value == paramsKey {
+			return commandTemplate{}, fmt.Errorf("%w: a template must define command fields", ErrTemplateUse)
+		}
+	}
+
+	tpl.node = body
+
+	return tpl, nil
+}
+
+// instantiate возвращает узел команды с подставленными параметрами.
+//
+// Подставляется копия: один шаблон разворачивается в одиннадцать сервисов, и
+// значения первого не должны доехать до второго.
+func (t commandTemplate) instantiate(with map[string]string) (ast.Node, error) {
+	for _, name := range slices.Sorted(maps.Keys(with)) {
+		if !slices.Contains(t.params, name) {
+			return nil, fmt.Errorf("%w: unknown parameter %q, declared: %s",
+				ErrTemplateParams, name, strings.Join(t.params, ", "))
+		}
+	}
+
+	node, err := cloneNode(t.node)
+	if err != nil {
+		return nil, err
+	}
+
+	var walkErr error
+
+	ast.Walk(visitFunc(func(n ast.Node) {
+		str, ok := n.(*ast.StringNode)
+		if !ok || walkErr != nil {
+			return
+		}
+
+		expanded, expErr := expandParams(str.Value, with, t.params)
+		if expErr != nil {
+			walkErr = fmt.Errorf("%w: %w", ErrTemplateParams, expErr)
+
+			return
+		}
+
+		str.Value = expanded
+	}), node)
+
+	return node, walkErr
+}
+
+// unknownTemplate перечисляет доступные шаблоны.
+func unknownTemplate(name string, templates map[string]commandTemplate) error {
+	if len(templates) == 0 {
+		return fmt.Errorf("%w %q: the root configuration defines no templates", ErrUnknownTemplate, name)
+	}
+
+	names := slices.Sorted(maps.Keys(templates))
+
+	return fmt.Errorf("%w %q, available: %s", ErrUnknownTemplate, name, strings.Join(names, ", "))
+}
+
+// visitFunc обходит все узлы дерева одной функцией.
+type visitFunc func(ast.Node)
+
+func (f visitFunc) Visit(n ast.Node) ast.Visitor {
+	f(n)
+
+	return f
+}
+
+// cloneNode возвращает независимую копию узла. Своего копирования у goccy
+// нет, поэтому узел печатается и разбирается заново.
+func cloneNode(node ast.Node) (ast.Node, error) {
+	file, err := parser.ParseBytes([]byte(node.String()), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfigParse, err)
+	}
+
+	return file.Docs[0].Body, nil
+}
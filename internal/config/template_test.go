@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const templateYAML = `templates:
+  go-service:
+    params: [ pkg, port, attempts ]
+    cmd: [ 'go', 'run', '${pkg}' ]
+    restart: on-failure
+    restartAttempts: '${attempts:-3}'
+    ready: { tcp: 'localhost:${port}', timeout: 30s }
+    env: { PORT: '${port}', HOME_DIR: '${HOME}', LITERAL: '$${port}' }
+commands:
+  api:
+    serve: { use: go-service, with: { pkg: ./cmd/api, port: 8081 } }
+  billing:
+    serve:
+      use: go-service
+      with: { pkg: ./cmd/billing, port: 8082, attempts: 7 }
+      ready: { timeout: 1m }
+`
+
+// TestTemplates — одиннадцать сервисов различаются пакетом и портом, и только
+// эти два значения у команды и остаются.
+func TestTemplates(t *testing.T) {
+	t.Setenv("HOME", "/home/dev")
+
+	dir := writeFiles(t, map[string]string{"parallel.yaml": templateYAML})
+
+	result, err := loadFlow(t, filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	api, billing := result.Chains[0].Commands()[0], result.Chains[1].Commands()[0]
+
+	if !slices.Equal(api.Args, []string{"run", "./cmd/api"}) || api.Ready.TCP != "localhost:8081" {
+		t.Errorf("api = %v, ready %+v", api.Args, api.Ready)
+	}
+
+	if api.RestartAttempts != 3 || billing.RestartAttempts != 7 {
+		t.Errorf("умолчание параметра: api %d, billing %d", api.RestartAttempts, billing.RestartAttempts)
+	}
+
+	// Не параметры — переменные окружения: их подставляет сборка, а
+	// экранированная ссылка остаётся литералом.
+	for _, want := range []string{"PORT=8081", "HOME_DIR=/home/dev", "LITERAL=${port}"} {
+		if !slices.Contains(api.Env, want) {
+			t.Errorf("env = %v, нет %s", api.Env, want)
+		}
+	}
+
+	// Поля рядом с use перекрывают шаблон так же, как у extends.
+	if billing.Ready.TCP != "localhost:8082" || billing.Ready.Timeout != time.Minute {
+		t.Errorf("billing ready = %+v", billing.Ready)
+	}
+}
+
+func TestTemplates_Errors(t *testing.T) {
+	const tpl = "templates:\n  svc:\n    params: [ port ]\n    cmd: [ 'serve', '${port}' ]\n"
+
+	tests := map[string]struct {
+		templates, commands string
+		want                error
+	}{
+		"нет шаблона":          {"", "x: { use: svx }", ErrUnknownTemplate},
+		"лишний параметр":      {"", "x: { use: svc, with: { prot: 1 } }", ErrTemplateParams},
+		"нет значения":         {"", "x: { use: svc }", ErrTemplateParams},
+		"with без use":         {"", "x: { cmd: [ 'a' ], with: { port: 1 } }", ErrTemplateUse},
+		"use вместе с extends": {"", "x: { use: svc, extends: '#a.x' }", ErrTemplateUse},
+		"use в шаблоне":        {"  t: { use: svc }\n", "x: { cmd: [ 'a' ] }", ErrTemplateUse},
+		"поле шаблона":         {"  bad: { pipe: maybe }\n", "x: { use: bad }", ErrConfigDecode},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := tpl + tt.templates + "commands:\n  a:\n    " + tt.commands + "\n"
+			dir := writeFiles(t, map[string]string{"parallel.yaml": config})
+
+			if _, err := loadFlow(t, filepath.Join(dir, "parallel.yaml")); !errors.Is(err, tt.want) {
+				t.Errorf("ожидалась %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestTemplates_FromFragment — шаблон объявлен в корне, а пользуются им
+// фрагменты сервисов; свои шаблоны фрагменту заводить нельзя.
+func TestTemplates_FromFragment(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"parallel.yaml":     "include: api.yaml\ntemplates:\n  svc: { params: [ p ], cmd: [ 'serve', '${p}' ] }\n",
+		"api.yaml":          "commands:\n  api:\n    serve: { use: svc, with: { p: api } }\n",
+		"bad/parallel.yaml": "include: t.yaml\ncommands:\n  a:\n    x: { cmd: [ 'a' ] }\n",
+		"bad/t.yaml":        "templates:\n  svc: { cmd: [ 'x' ] }\n",
+	})
+
+	result, err := loadFlow(t, filepath.Join(dir, "parallel.yaml"))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if args := result.Chains[0].Commands()[0].Args; !slices.Equal(args, []string{"api"}) {
+		t.Errorf("args = %v", args)
+	}
+
+	if _, err := loadFlow(t, filepath.Join(dir, "bad", "parallel.yaml")); !errors.Is(err, ErrIncludedKey) {
+		t.Errorf("ожидалась ErrIncludedKey, получено %v", err)
+	}
+}
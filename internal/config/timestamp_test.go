@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// TestUnmarshal_Timestamp — верхнеуровневый режим остаётся в Data и в команды
+// не переносится: между ним и командой стоит флаг -timestamp.
+func TestUnmarshal_Timestamp(t *testing.T) {
+	raw := []byte("format:\n  timestamp: elapsed\ncommands:\n  api:\n    serve:\n      cmd: [ 'go' ]\n" +
+		"      format:\n        timestamp: wall\n    plain:\n      cmd: [ 'go' ]\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if cfg.Timestamp != flow.TimestampElapsed {
+		t.Fatalf("верхний уровень: %q", cfg.Timestamp)
+	}
+
+	result, err := NewFlowBuilder().Build(cfg)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	cmds := result.Chains[0].Commands()
+	if cmds[0].Format.Timestamp != flow.TimestampWall || cmds[1].Format.Timestamp != "" {
+		t.Fatalf("режимы команд: %q, %q", cmds[0].Format.Timestamp, cmds[1].Format.Timestamp)
+	}
+}
+
+func TestUnmarshal_TimestampUnknown(t *testing.T) {
+	if _, err := (YamlFileMarshaller{}).Unmarshal([]byte("format:\n  timestamp: unix\n")); !errors.Is(
+		err, flow.ErrUnknownTimestampMode) {
+		t.Fatalf("верхний уровень: ожидалась ErrUnknownTimestampMode, получено %v", err)
+	}
+
+	// Шаблон имени у каждой команды свой, наверху ему не место.
+	if _, err := (YamlFileMarshaller{}).Unmarshal([]byte("format:\n  cmdName: '%CMD_NAME%'\n")); err == nil {
+		t.Fatal("cmdName на верхнем уровне должен быть ошибкой")
+	}
+
+	raw := []byte("commands:\n  api:\n    serve:\n      cmd: [ 'go' ]\n      format:\n        timestamp: unix\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if _, err := NewFlowBuilder().Build(cfg); !errors.Is(err, flow.ErrUnknownTimestampMode) {
+		t.Fatalf("команда: ожидалась ErrUnknownTimestampMode, получено %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUnmarshal_Watch — секция доезжает до Data, одиночный путь — тоже список.
+func TestUnmarshal_Watch(t *testing.T) {
+	raw := []byte("commands:\n  api:\n    serve:\n      cmd: [ 'go', 'run', '.' ]\n" +
+		"      watch:\n        paths: '**/*.go'\n        ignore: [ 'vendor/**', '**/*_test.go' ]\n" +
+		"        debounce: 500ms\n")
+
+	cfg, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	w := cfg.Chains[0].Commands[0].Spec.Watch
+	if w == nil || len(w.Paths) != 1 || len(w.Ignore) != 2 || w.Debounce != 500*time.Millisecond {
+		t.Fatalf("секция разобрана неверно: %+v", w)
+	}
+}
+
+// TestUnmarshal_WatchUnknownField — опечатка внутри watch не должна тихо
+// выключать слежение.
+func TestUnmarshal_WatchUnknownField(t *testing.T) {
+	raw := []byte("commands:\n  api:\n    serve:\n      cmd: [ 'go' ]\n      watch:\n        path: '*.go'\n")
+
+	_, err := YamlFileMarshaller{}.Unmarshal(raw)
+	if err == nil || !strings.Contains(err.Error(), "path") {
+		t.Fatalf("ожидалась ошибка про неизвестное поле, получено %v", err)
+	}
+}
+
+// TestBuild_WatchRoot — шаблоны отсчитываются от dir команды, а без него — от
+// каталога конфигурации.
+func TestBuild_WatchRoot(t *testing.T) {
+	base := t.TempDir()
+	spec := func(dir string) command {
+		return command{Cmd: []string{"go"}, Dir: dir, Watch: &watchSpec{Paths: stringList{"**/*.go"}}}
+	}
+
+	data := Data{BaseDir: base, Chains: []ChainConfig{{
+		Name: "c",
+		Commands: []NamedCommand{
+			{Name: "in-dir", Spec: spec("api")},
+			{Name: "at-base", Spec: spec("")},
+			{Name: "plain", Spec: command{Cmd: []string{"go"}}},
+		},
+	}}}
+
+	result, err := NewFlowBuilder().Build(data)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	cmds := result.Chains[0].Commands()
+
+	if got := cmds[0].Watch.Root; got != filepath.Join(base, "api") {
+		t.Errorf("корень команды с dir = %s", got)
+	}
+
+	if got := cmds[1].Watch.Root; got != base {
+		t.Errorf("корень команды без dir = %s", got)
+	}
+
+	if cmds[2].Watch != nil {
+		t.Errorf("команда без секции watch получила слежение: %+v", cmds[2].Watch)
+	}
+}
@@ -0,0 +1,50 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Call отправляет запрос запуску, слушающему сокет path, и возвращает ответ.
+//
+// Отказ исполнить запрос возвращается ошибкой ErrRequestFailed с текстом,
+// пришедшим от запуска.
+func Call(ctx context.Context, path string, req Request) (Response, error) {
+	if err := req.Validate(); err != nil {
+		return Response{}, fmt.Errorf("%w: %q", err, req.Op)
+	}
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return Response{}, fmt.Errorf("connecting to %s (is parallel running?): %w", path, err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(ioTimeout)
+	}
+
+	_ = conn.SetDeadline(deadline)
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("sending request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.Error != "" {
+		return resp, fmt.Errorf("%w: %s", ErrRequestFailed, resp.Error)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,143 @@
+// Package control описывает управляющий сокет живого запуска: протокол,
+// сервер, который его обслуживает, и клиента для `parallel ctl`.
+//
+// Пакет не знает ни о менеджере, ни о конфигурации: запросы исполняет
+// Handler, а связывает его с runner слой cli.
+package control
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Операции управляющего сокета.
+const (
+	OpStatus  = "status"
+	OpStart   = "start"
+	OpStop    = "stop"
+	OpRestart = "restart"
+
+	// Операции фильтра вывода: меняют только то, что видно в терминале
+	// запуска, сами цепочки не трогают.
+	OpMute    = "mute"
+	OpUnmute  = "unmute"
+	OpSolo    = "solo"
+	OpUnsolo  = "unsolo"
+	OpInclude = "include"
+	OpExclude = "exclude"
+)
+
+// socketHashLen — сколько шестнадцатеричных знаков хеша идёт в имя сокета.
+// Путь к unix-сокету ограничен сотней с небольшим байт, а двенадцати знаков
+// хватает, чтобы два проекта на одной машине не столкнулись.
+const socketHashLen = 12
+
+var (
+	// ErrUnknownOp — операция не из списка поддерживаемых.
+	ErrUnknownOp = errors.New("unknown operation")
+	// ErrChainRequired — операции над цепочкой не передали её имя.
+	ErrChainRequired = errors.New("chain name is required")
+	// ErrSocketInUse — по этому пути уже отвечает другой запуск.
+	ErrSocketInUse = errors.New("control socket is already in use")
+	// ErrRequestFailed — запуск получил запрос, но исполнить его не смог.
+	ErrRequestFailed = errors.New("request failed")
+)
+
+// Request — запрос к запуску. Одна строка JSON на соединение.
+type Request struct {
+	Op    string `json:"op"`
+	Chain string `json:"chain,omitempty"`
+	// Pattern — регулярное выражение для include и exclude; пустое снимает
+	// фильтр.
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Validate проверяет, что запрос можно исполнить.
+func (r Request) Validate() error {
+	switch r.Op {
+	case OpStatus, OpUnmute, OpUnsolo, OpInclude, OpExclude:
+		return nil
+	case OpStart, OpStop, OpRestart, OpMute, OpSolo:
+		if r.Chain == "" {
+			return ErrChainRequired
+		}
+
+		return nil
+	default:
+		return ErrUnknownOp
+	}
+}
+
+// Response — ответ запуска. Error непуст, если запрос не исполнен.
+type Response struct {
+	Error  string  `json:"error,omitempty"`
+	Chains []Chain `json:"chains,omitempty"`
+	// Filter — действующий фильтр вывода; nil, если он ничего не скрывает.
+	Filter *Filter `json:"filter,omitempty"`
+}
+
+// Filter — фильтр вывода запуска.
+type Filter struct {
+	Muted   []string `json:"muted,omitempty"`
+	Solo    []string `json:"solo,omitempty"`
+	Include string   `json:"include,omitempty"`
+	Exclude string   `json:"exclude,omitempty"`
+}
+
+// Active сообщает, скрывает ли фильтр хоть что-нибудь.
+func (f Filter) Active() bool {
+	return len(f.Muted) > 0 || len(f.Solo) > 0 || f.Include != "" || f.Exclude != ""
+}
+
+// Chain — состояние цепочки в ответе на status.
+type Chain struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	Starts    int       `json:"starts"`
+	Processes []Process `json:"processes,omitempty"`
+}
+
+// Process — работающий процесс цепочки.
+type Process struct {
+	Command string `json:"command"`
+	PID     int    `json:"pid"`
+}
+
+// Handler исполняет запросы против живого запуска.
+type Handler interface {
+	Status() []Chain
+	Start(chain string) error
+	Stop(chain string) error
+	Restart(chain string) error
+
+	// Mute и Solo принимают цепочку; пустое имя у Unmute и Unsolo снимает
+	// заглушку или выбор со всех.
+	Mute(chain string) error
+	Unmute(chain string) error
+	Solo(chain string) error
+	Unsolo(chain string) error
+	Include(pattern string) error
+	Exclude(pattern string) error
+	Filter() Filter
+}
+
+// SocketPath возвращает путь к сокету запуска, опознаваемого ключом.
+//
+// Путь выводится из ключа, а не выбирается случайно: тогда `parallel ctl`,
+// запущенный из того же проекта, находит сокет сам, без флагов. Каталог —
+// $XDG_RUNTIME_DIR, он приватен для пользователя; без него — общий временный.
+func SocketPath(key string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(dir, "parallel-"+hex.EncodeToString(sum[:])[:socketHashLen]+".sock")
+}
@@ -0,0 +1,245 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHandler записывает вызовы и отвечает заранее заданной ошибкой.
+type fakeHandler struct {
+	calls  []string
+	err    error
+	filter Filter
+}
+
+func (f *fakeHandler) Status() []Chain {
+	return []Chain{{Name: "api", State: "running", Starts: 1, Processes: []Process{{Command: "serve", PID: 42}}}}
+}
+
+func (f *fakeHandler) Start(chain string) error   { return f.record("start " + chain) }
+func (f *fakeHandler) Stop(chain string) error    { return f.record("stop " + chain) }
+func (f *fakeHandler) Restart(chain string) error { return f.record("restart " + chain) }
+func (f *fakeHandler) Mute(chain string) error    { return f.record("mute " + chain) }
+func (f *fakeHandler) Unmute(chain string) error  { return f.record("unmute " + chain) }
+func (f *fakeHandler) Solo(chain string) error    { return f.record("solo " + chain) }
+func (f *fakeHandler) Unsolo(chain string) error  { return f.record("unsolo " + chain) }
+func (f *fakeHandler) Include(p string) error     { return f.record("include " + p) }
+func (f *fakeHandler) Exclude(p string) error     { return f.record("exclude " + p) }
+func (f *fakeHandler) Filter() Filter             { return f.filter }
+
+func (f *fakeHandler) record(call string) error {
+	f.calls = append(f.calls, call)
+
+	return f.err
+}
+
+// serve поднимает сервер во временном каталоге и возвращает путь к сокету.
+func serve(t *testing.T, h Handler) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "ctl.sock")
+
+	srv, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		srv.Serve(ctx, h)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+
+		if err := srv.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+
+	return path
+}
+
+func TestCall_Roundtrip(t *testing.T) {
+	h := &fakeHandler{}
+	path := serve(t, h)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := Call(ctx, path, Request{Op: OpRestart, Chain: "api"})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+
+	if len(h.calls) != 1 || h.calls[0] != "restart api" {
+		t.Fatalf("вызовы обработчика = %v, ожидался один restart api", h.calls)
+	}
+
+	// Ответ на действие несёт свежее состояние — второй запрос не нужен.
+	if len(resp.Chains) != 1 || resp.Chains[0].Processes[0].PID != 42 {
+		t.Fatalf("ответ = %+v, ожидалось состояние цепочки api", resp)
+	}
+}
+
+func TestCall_Filter(t *testing.T) {
+	h := &fakeHandler{filter: Filter{Muted: []string{"worker"}}}
+	path := serve(t, h)
+
+	reqs := []Request{
+		{Op: OpMute, Chain: "worker"},
+		{Op: OpUnsolo},
+		{Op: OpExclude, Pattern: "GET /health"},
+	}
+
+	var resp Response
+
+	for _, req := range reqs {
+		var err error
+		if resp, err = Call(context.Background(), path, req); err != nil {
+			t.Fatalf("Call %s: %v", req.Op, err)
+		}
+	}
+
+	want := []string{"mute worker", "unsolo ", "exclude GET /health"}
+	if strings.Join(h.calls, "|") != strings.Join(want, "|") {
+		t.Fatalf("вызовы обработчика = %q, ожидалось %q", h.calls, want)
+	}
+
+	// Действующий фильтр приходит вместе с состоянием цепочек.
+	if resp.Filter == nil || len(resp.Filter.Muted) != 1 {
+		t.Fatalf("ответ = %+v, ожидался фильтр с заглушённой worker", resp)
+	}
+}
+
+func TestCall_HandlerError(t *testing.T) {
+	path := serve(t, &fakeHandler{err: errors.New("chain is not running")})
+
+	_, err := Call(context.Background(), path, Request{Op: OpStop, Chain: "api"})
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Fatalf("ожидался ErrRequestFailed, получено %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "chain is not running") {
+		t.Fatalf("текст отказа запуска потерян: %v", err)
+	}
+}
+
+func TestCall_RejectsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want error
+	}{
+		{name: "неизвестная операция", req: Request{Op: "reload"}, want: ErrUnknownOp},
+		{name: "действие без цепочки", req: Request{Op: OpRestart}, want: ErrChainRequired},
+		{name: "заглушка без цепочки", req: Request{Op: OpMute}, want: ErrChainRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Сокета нет: неверный запрос отсекается до соединения.
+			_, err := Call(context.Background(), filepath.Join(t.TempDir(), "none.sock"), tt.req)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ожидалось %v, получено %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ctl.sock")
+
+	first, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	// Закрываем слушателя, не удаляя файл: так выглядит сокет после kill -9.
+	// Сам net удалил бы файл при закрытии, и замена бы не проверялась.
+	if unix, ok := first.ln.(*net.UnixListener); ok {
+		unix.SetUnlinkOnClose(false)
+	}
+
+	_ = first.ln.Close()
+
+	if _, err := os.Lstat(path); err != nil {
+		t.Fatalf("файл сокета должен остаться: %v", err)
+	}
+
+	second, err := Listen(path)
+	if err != nil {
+		t.Fatalf("оставшийся от упавшего запуска сокет должен заменяться: %v", err)
+	}
+
+	if second.Path() != path {
+		t.Errorf("Path() = %q, ожидалось %q", second.Path(), path)
+	}
+
+	_ = second.Close()
+}
+
+func TestListen_MissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "ctl.sock")
+
+	if _, err := Listen(path); err == nil {
+		t.Fatal("сокет в несуществующем каталоге не открывается")
+	}
+}
+
+// TestListen_OwnerOnly — сокетом управляет только владелец запуска, какой бы
+// ни была umask: в общем временном каталоге его видят все.
+func TestListen_OwnerOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("права unix-сокета на Windows не выражаются битами режима")
+	}
+
+	path := serve(t, &fakeHandler{})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != socketMode {
+		t.Fatalf("права сокета %o, ожидалось %o", perm, socketMode)
+	}
+}
+
+func TestListen_RefusesLiveSocket(t *testing.T) {
+	path := serve(t, &fakeHandler{})
+
+	if _, err := Listen(path); !errors.Is(err, ErrSocketInUse) {
+		t.Fatalf("живой сокет чужого запуска отбирать нельзя, получено %v", err)
+	}
+}
+
+func TestSocketPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+
+	a, b := SocketPath("/srv/a/.parallelrc.yaml"), SocketPath("/srv/b/.parallelrc.yaml")
+
+	if filepath.Dir(a) != dir {
+		t.Fatalf("сокет должен лежать в XDG_RUNTIME_DIR, получено %s", a)
+	}
+
+	if a == b {
+		t.Fatal("разные конфигурации получили один сокет")
+	}
+
+	if a != SocketPath("/srv/a/.parallelrc.yaml") {
+		t.Fatal("путь к сокету должен выводиться из ключа детерминированно")
+	}
+}
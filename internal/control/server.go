@@ -0,0 +1,174 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ioTimeout ограничивает обмен с одним клиентом: зависший `parallel ctl`
+// не должен держать соединение вечно.
+const ioTimeout = 5 * time.Second
+
+// Server принимает запросы на unix-сокете.
+type Server struct {
+	ln   net.Listener
+	path string
+
+	wg sync.WaitGroup
+}
+
+// socketMode — права на файл сокета. Без переменной XDG_RUNTIME_DIR сокет
+// лежит в общем временном каталоге под предсказуемым именем, и с правами по
+// umask чужой пользователь того же хоста мог бы останавливать цепочки.
+const socketMode = 0o600
+
+// Listen открывает сокет по пути path, доступный только владельцу.
+//
+// Файл, оставшийся от упавшего запуска, удаляется: иначе после kill -9
+// управление не поднялось бы до ручной уборки. Живой сокет не трогается —
+// это другой запуск того же проекта, и отбирать у него управление нельзя.
+func Listen(path string) (*Server, error) {
+	if _, err := os.Lstat(path); err == nil {
+		if conn, dialErr := net.DialTimeout("unix", path, ioTimeout); dialErr == nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("%w: %s", ErrSocketInUse, path)
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("removing stale control socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening control socket: %w", err)
+	}
+
+	if err := restrict(path); err != nil {
+		_ = ln.Close()
+
+		return nil, fmt.Errorf("restricting control socket: %w", err)
+	}
+
+	return &Server{ln: ln, path: path}, nil
+}
+
+// restrict оставляет сокет владельцу. На Windows биты режима доступ к сокету
+// не описывают, и менять там нечего.
+func restrict(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	return os.Chmod(path, socketMode)
+}
+
+// Path возвращает путь к сокету.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve обслуживает запросы, пока не отменён ctx, и закрывает сокет.
+func (s *Server) Serve(ctx context.Context, h Handler) {
+	go func() {
+		<-ctx.Done()
+		_ = s.ln.Close()
+	}()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			break
+		}
+
+		s.wg.Go(func() { s.handle(conn, h) })
+	}
+
+	s.wg.Wait()
+}
+
+// Close закрывает сокет и удаляет его файл.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	if errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+
+	if rmErr := os.Remove(s.path); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+		return errors.Join(err, rmErr)
+	}
+
+	return err
+}
+
+// handle исполняет один запрос и отвечает на него.
+func (s *Server) handle(conn net.Conn, h Handler) {
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	var req Request
+
+	resp := Response{}
+
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("decoding request: %v", err)
+	} else {
+		resp = dispatch(req, h)
+	}
+
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// dispatch переводит запрос в вызов Handler.
+func dispatch(req Request, h Handler) Response {
+	if err := req.Validate(); err != nil {
+		return Response{Error: fmt.Sprintf("%v: %q", err, req.Op)}
+	}
+
+	var err error
+
+	switch req.Op {
+	case OpStart:
+		err = h.Start(req.Chain)
+	case OpStop:
+		err = h.Stop(req.Chain)
+	case OpRestart:
+		err = h.Restart(req.Chain)
+	case OpMute:
+		err = h.Mute(req.Chain)
+	case OpUnmute:
+		err = h.Unmute(req.Chain)
+	case OpSolo:
+		err = h.Solo(req.Chain)
+	case OpUnsolo:
+		err = h.Unsolo(req.Chain)
+	case OpInclude:
+		err = h.Include(req.Pattern)
+	case OpExclude:
+		err = h.Exclude(req.Pattern)
+	}
+
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	// Ответ на любой запрос несёт свежее состояние: после restart клиенту
+	// не нужен второй запрос, чтобы увидеть, что цепочка снова в работе.
+	resp := Response{Chains: h.Status()}
+	if filter := h.Filter(); filter.Active() {
+		resp.Filter = &filter
+	}
+
+	return resp
+}
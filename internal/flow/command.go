@@ -8,9 +8,12 @@ import (
 // ErrEmptyCommand возвращается Command.Validate, когда не задан исполняемый файл.
 var ErrEmptyCommand = errors.New("command cannot be empty")
 
-// Format задаёт шаблон отображаемого имени команды.
+// Format задаёт оформление строк вывода команды.
 type Format struct {
+	// CmdName — шаблон отображаемого имени команды.
 	CmdName string
+	// Timestamp — метка времени перед строкой; пусто — как решено для всех.
+	Timestamp TimestampMode
 }
 
 // Command — одна команда внутри цепочки.
@@ -42,6 +45,12 @@ type Command struct {
 	// Ready — признак, по которому команда считается готовой к использованию.
 	// Указатель: отсутствие условия — нормальное состояние, а не пустое.
 	Ready *ReadyCondition
+
+	// Watch — файлы, изменение которых перезапускает команду. nil — не следить.
+	Watch *Watch
+
+	// Health — проверка здоровья работающей команды. nil — не проверять.
+	Health *Health
 }
 
 // DisplayName возвращает имя для показа: заданное в конфигурации либо сам исполняемый файл.
@@ -59,5 +68,13 @@ func (cmd Command) Validate() error {
 		return ErrEmptyCommand
 	}
 
-	return cmd.Ready.Validate()
+	if err := cmd.Ready.Validate(); err != nil {
+		return err
+	}
+
+	if err := cmd.Watch.Validate(); err != nil {
+		return err
+	}
+
+	return cmd.Health.Validate()
 }
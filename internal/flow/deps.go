@@ -20,11 +20,11 @@ var (
 // Обе проверки обязаны выполняться до запуска: неизвестное имя иначе означало
 // бы вечное ожидание того, чего нет, а цикл — взаимную блокировку, которую
 // снаружи не отличить от зависшей команды.
+//
+// Узлы графа — цепочки и их именованные команды: needs: [db.migrate] ждёт
+// одну команду, а не всю цепочку db.
 func ValidateDeps(f Flow) error {
-	known := make(map[string]*CommandChain, len(f.Chains))
-	for _, chain := range f.Chains {
-		known[chain.Name] = chain
-	}
+	graph := newDepGraph(f)
 
 	for _, chain := range f.Chains {
 		for _, need := range chain.Needs {
@@ -32,28 +32,115 @@ func ValidateDeps(f Flow) error {
 				return fmt.Errorf("%w: %q", ErrSelfDependency, chain.Name)
 			}
 
-			if _, ok := known[need]; !ok {
+			owner, ok := graph.chainOf(need)
+			if !ok {
 				return fmt.Errorf("%w: chain %q needs %q, available: %s",
-					ErrUnknownDependency, chain.Name, need, strings.Join(names(f), ", "))
+					ErrUnknownDependency, chain.Name, need, strings.Join(needCandidates(f, need), ", "))
+			}
+
+			// Команда своей же цепочки не запустится, пока цепочка ждёт её.
+			if owner == chain {
+				return fmt.Errorf("%w: chain %q needs its own command %q", ErrSelfDependency, chain.Name, need)
+			}
+
+			if _, err := ParseNeedCondition(string(chain.NeedOf(need))); err != nil {
+				return fmt.Errorf("chain %q needs %q: %w", chain.Name, need, err)
+			}
+		}
+	}
+
+	return findCycle(f, graph)
+}
+
+// CommandNode — имя команды в графе зависимостей: chain.command.
+func CommandNode(chain, command string) string {
+	return chain + "." + command
+}
+
+// depGraph — узлы графа зависимостей: цепочки и их именованные команды.
+type depGraph struct {
+	chains   map[string]*CommandChain
+	commands map[string]*CommandChain
+}
+
+func newDepGraph(f Flow) depGraph {
+	graph := depGraph{
+		chains:   make(map[string]*CommandChain, len(f.Chains)),
+		commands: make(map[string]*CommandChain),
+	}
+
+	for _, chain := range f.Chains {
+		graph.chains[chain.Name] = chain
+
+		for _, cmd := range chain.commands {
+			if cmd.Name != "" {
+				graph.commands[CommandNode(chain.Name, cmd.Name)] = chain
 			}
 		}
 	}
 
-	return findCycle(f, known)
+	return graph
+}
+
+// chainOf возвращает цепочку узла: саму цепочку либо ту, чья это команда.
+// Имя цепочки побеждает: цепочка "db.migrate" и команда migrate цепочки db
+// неразличимы в needs, и прежний смысл ссылки не должен меняться.
+func (g depGraph) chainOf(node string) (*CommandChain, bool) {
+	if chain, ok := g.chains[node]; ok {
+		return chain, true
+	}
+
+	chain, ok := g.commands[node]
+
+	return chain, ok
+}
+
+// needCandidates подсказывает, что можно было иметь в виду: команды цепочки,
+// если ссылка начинается с её имени, иначе все цепочки.
+func needCandidates(f Flow, need string) []string {
+	for _, chain := range f.Chains {
+		if !strings.HasPrefix(need, chain.Name+".") {
+			continue
+		}
+
+		var out []string
+
+		for _, cmd := range chain.commands {
+			if cmd.Name != "" {
+				out = append(out, CommandNode(chain.Name, cmd.Name))
+			}
+		}
+
+		if len(out) > 0 {
+			return out
+		}
+	}
+
+	return names(f)
+}
+
+// needsOf возвращает предшественников узла. Команда ждёт того же, что и её
+// цепочка: раньше цепочки она не запустится.
+func (g depGraph) needsOf(node string) []string {
+	if chain, ok := g.chainOf(node); ok {
+		return chain.Needs
+	}
+
+	return nil
 }
 
 // findCycle ищет цикл обходом в глубину и называет его участников.
 //
 // Сообщить только факт цикла недостаточно: в конфигурации из десятка цепочек
 // искать его глазами — отдельная работа, которую инструмент может сделать сам.
-func findCycle(f Flow, known map[string]*CommandChain) error {
+func findCycle(f Flow, graph depGraph) error {
 	const (
 		white = 0 // не посещали
 		grey  = 1 // в текущем пути обхода
 		black = 2 // полностью обработана
 	)
 
-	color := make(map[string]int, len(f.Chains))
+	color := make(map[string]int, len(graph.chains)+len(graph.commands))
 
 	var path []string
 
@@ -63,7 +150,7 @@ func findCycle(f Flow, known map[string]*CommandChain) error {
 		color[name] = grey
 		path = append(path, name)
 
-		for _, need := range known[name].Needs {
+		for _, need := range graph.needsOf(name) {
 			switch color[need] {
 			case grey:
 				// Нашли возврат в текущий путь: цикл — это его хвост.
@@ -117,27 +204,26 @@ func WithDependencies(f Flow, selected []string) []string {
 		return selected
 	}
 
-	known := make(map[string]*CommandChain, len(f.Chains))
-	for _, chain := range f.Chains {
-		known[chain.Name] = chain
-	}
-
+	graph := newDepGraph(f)
 	wanted := make(map[string]bool, len(selected))
 
 	var add func(name string)
 
 	add = func(name string) {
-		if wanted[name] {
+		// Команда тянет за собой всю свою цепочку: запускается цепочка целиком.
+		chain, ok := graph.chainOf(name)
+		if !ok {
+			wanted[name] = true
+
 			return
 		}
 
-		wanted[name] = true
-
-		chain, ok := known[name]
-		if !ok {
+		if wanted[chain.Name] {
 			return
 		}
 
+		wanted[chain.Name] = true
+
 		for _, need := range chain.Needs {
 			add(need)
 		}
@@ -165,27 +251,24 @@ func WithDependencies(f Flow, selected []string) []string {
 // вопроса, когда зависимости заданы.
 func Order(f Flow) [][]string {
 	depth := make(map[string]int, len(f.Chains))
-	known := make(map[string]*CommandChain, len(f.Chains))
-
-	for _, chain := range f.Chains {
-		known[chain.Name] = chain
-	}
+	graph := newDepGraph(f)
 
 	var levelOf func(name string) int
 
 	levelOf = func(name string) int {
-		if d, ok := depth[name]; ok {
+		// Команда стоит на уровне своей цепочки.
+		chain, ok := graph.chainOf(name)
+		if !ok {
+			return 0
+		}
+
+		if d, ok := depth[chain.Name]; ok {
 			return d
 		}
 
 		// Значение ставится до обхода предков: при цикле это не даст уйти
 		// в бесконечную рекурсию. Сам цикл ловит ValidateDeps.
-		depth[name] = 0
-
-		chain, ok := known[name]
-		if !ok {
-			return 0
-		}
+		depth[chain.Name] = 0
 
 		best := 0
 
@@ -195,7 +278,7 @@ func Order(f Flow) [][]string {
 			}
 		}
 
-		depth[name] = best
+		depth[chain.Name] = best
 
 		return best
 	}
@@ -74,6 +74,58 @@ func TestValidateDeps_Valid(t *testing.T) {
 	}
 }
 
+// TestValidateDeps_Commands — ссылка на команду проверяется теми же
+// правилами, что и ссылка на цепочку. depsFlow даёт каждой цепочке команду x.
+func TestValidateDeps_Commands(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    map[string][]string
+		wantErr error
+		want    string
+	}{
+		{name: "команда другой цепочки", spec: map[string][]string{"api": {"db.x"}}},
+		{name: "нет такой команды", spec: map[string][]string{"api": {"db.migrate"}}, wantErr: ErrUnknownDependency},
+		{name: "своя команда", spec: map[string][]string{"api": {"api.x"}}, wantErr: ErrSelfDependency},
+		{
+			name:    "цикл через команду",
+			spec:    map[string][]string{"api": {"db.x"}, "db": {"api"}},
+			wantErr: ErrDependencyCycle,
+			want:    "api -> db.x -> api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDeps(depsFlow(tt.spec, "db", "api"))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ожидалась %v, получено %v", tt.wantErr, err)
+			}
+
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("в сообщении нет %q: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestWithDependencies_Command — команда тянет за собой свою цепочку, и
+// уровень запуска у неё тот же.
+func TestWithDependencies_Command(t *testing.T) {
+	f := depsFlow(map[string][]string{"api": {"db.x"}}, "db", "api", "ui")
+
+	if got := strings.Join(WithDependencies(f, []string{"api"}), ","); got != "db,api" {
+		t.Errorf("отбор = %q", got)
+	}
+
+	if levels := Order(f); len(levels) != 2 || strings.Join(levels[1], ",") != "api" {
+		t.Errorf("уровни = %v", levels)
+	}
+}
+
 // TestWithDependencies — «запусти api» почти всегда означает «и то, без чего
 // он не работает».
 func TestWithDependencies(t *testing.T) {
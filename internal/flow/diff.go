@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Diff — разница между двумя версиями Flow, выраженная в цепочках.
+//
+// Все списки — в порядке объявления: удалённые — как в старой версии,
+// добавленные и изменённые — как в новой.
+type Diff struct {
+	Added   []*CommandChain
+	Changed []*CommandChain
+	Removed []string
+}
+
+// Empty сообщает, что версии не различаются ни одной цепочкой.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// Compare сравнивает цепочки старой и новой версии по имени.
+//
+// Изменённой считается цепочка, у которой различаются команды (включая
+// окружение и условия готовности), needs, файл журнала или уборка. ColorIdx в сравнении не участвует:
+// добавление цепочки в начало файла сдвигает номера всех остальных, и
+// перезапускать из-за этого весь стек было бы ровно тем, от чего спасает
+// перечитывание конфигурации.
+func Compare(old, next Flow) Diff {
+	before := make(map[string]*CommandChain, len(old.Chains))
+	for _, chain := range old.Chains {
+		before[chain.Name] = chain
+	}
+
+	var diff Diff
+
+	seen := make(map[string]bool, len(next.Chains))
+
+	for _, chain := range next.Chains {
+		seen[chain.Name] = true
+
+		prev, ok := before[chain.Name]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, chain)
+		case !sameChain(prev, chain):
+			diff.Changed = append(diff.Changed, chain)
+		}
+	}
+
+	for _, chain := range old.Chains {
+		if !seen[chain.Name] {
+			diff.Removed = append(diff.Removed, chain.Name)
+		}
+	}
+
+	return diff
+}
+
+// sameChain сравнивает то, что определяет поведение цепочки при запуске.
+func sameChain(a, b *CommandChain) bool {
+	return slices.Equal(a.Needs, b.Needs) && sameConditions(a, b) && reflect.DeepEqual(a.Log, b.Log) &&
+		reflect.DeepEqual(a.commands, b.commands) && reflect.DeepEqual(a.Finally, b.Finally)
+}
+
+// sameConditions сравнивает условия needs с учётом умолчания: явное ready и
+// краткая форма needs — одно и то же.
+func sameConditions(a, b *CommandChain) bool {
+	for _, need := range a.Needs {
+		if a.NeedOf(need) != b.NeedOf(need) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,106 @@
+package flow
+
+import (
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	old := sampleFlow()
+
+	next := Flow{}
+
+	// api — без изменений, но с другим ColorIdx: цвет перезапуска не стоит.
+	api := &CommandChain{Name: "api", ColorIdx: 5}
+	api.Add(Command{Name: "run", Cmd: "echo"})
+	next.AddChain(api)
+
+	// ui — изменилось окружение команды.
+	ui := &CommandChain{Name: "ui", ColorIdx: 1}
+	ui.Add(Command{Name: "run", Cmd: "echo", Env: []string{"PORT=3000"}})
+	next.AddChain(ui)
+
+	// db — новая цепочка; worker удалён.
+	db := &CommandChain{Name: "db", ColorIdx: 2}
+	db.Add(Command{Name: "run", Cmd: "postgres"})
+	next.AddChain(db)
+
+	diff := Compare(old, next)
+
+	if len(diff.Added) != 1 || diff.Added[0].Name != "db" {
+		t.Errorf("добавленные: %v", chainNames(diff.Added))
+	}
+
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "ui" {
+		t.Errorf("изменённые: %v", chainNames(diff.Changed))
+	}
+
+	if len(diff.Removed) != 1 || diff.Removed[0] != "worker" {
+		t.Errorf("удалённые: %v", diff.Removed)
+	}
+}
+
+// TestCompare_Needs: смена предшественников меняет порядок запуска, и цепочка
+// должна считаться изменённой, даже если её команды прежние.
+func TestCompare_Needs(t *testing.T) {
+	old := sampleFlow()
+	next := sampleFlow()
+	next.Chains[1].Needs = []string{"api"}
+
+	diff := Compare(old, next)
+
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "ui" {
+		t.Errorf("изменённые: %v", chainNames(diff.Changed))
+	}
+}
+
+// TestCompare_NeedConditions: смена условия — тоже смена порядка запуска,
+// а явное ready равно краткой форме needs.
+func TestCompare_NeedConditions(t *testing.T) {
+	old := sampleFlow()
+	old.Chains[1].Needs = []string{"api"}
+
+	same := sampleFlow()
+	same.Chains[1].Needs = []string{"api"}
+	same.Chains[1].NeedConditions = map[string]NeedCondition{"api": NeedReady}
+
+	if diff := Compare(old, same); len(diff.Changed) != 0 {
+		t.Errorf("явное ready сочтено изменением: %v", chainNames(diff.Changed))
+	}
+
+	next := sampleFlow()
+	next.Chains[1].Needs = []string{"api"}
+	next.Chains[1].NeedConditions = map[string]NeedCondition{"api": NeedStarted}
+
+	if diff := Compare(old, next); len(diff.Changed) != 1 || diff.Changed[0].Name != "ui" {
+		t.Errorf("изменённые: %v", chainNames(diff.Changed))
+	}
+}
+
+// TestCompare_Log: новый файл журнала должен начать писаться, а значит
+// цепочка перезапускается.
+func TestCompare_Log(t *testing.T) {
+	old := sampleFlow()
+	next := sampleFlow()
+	next.Chains[2].Log = &LogFile{Path: "worker.log"}
+
+	diff := Compare(old, next)
+
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "worker" {
+		t.Errorf("изменённые: %v", chainNames(diff.Changed))
+	}
+}
+
+func TestCompare_Same(t *testing.T) {
+	if diff := Compare(sampleFlow(), sampleFlow()); !diff.Empty() {
+		t.Errorf("одинаковые версии дали разницу: %+v", diff)
+	}
+}
+
+func chainNames(chains []*CommandChain) []string {
+	out := make([]string, 0, len(chains))
+	for _, chain := range chains {
+		out = append(out, chain.Name)
+	}
+
+	return out
+}
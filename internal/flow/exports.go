@@ -0,0 +1,261 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrExportNotNeeded — команда ссылается на переменную цепочки, которой
+	// нет в её needs.
+	ErrExportNotNeeded = errors.New("chain variable of a chain not listed in needs")
+	// ErrExportNotReady — переменная берётся у цепочки, которую ждут лишь
+	// до запуска.
+	ErrExportNotReady = errors.New("chain variable of a chain needed only as started")
+	// ErrUnknownExport — цепочка не выставляет такой переменной.
+	ErrUnknownExport = errors.New("unknown chain variable")
+	// ErrUnresolvedExport — к старту команды переменная так и не получила
+	// значения.
+	ErrUnresolvedExport = errors.New("chain variable has no value")
+)
+
+// exportRe находит кандидата в ссылки на переменную цепочки: ${chain.var}.
+//
+// Точка отличает её от переменной окружения: ${PORT} подставляется при сборке
+// конфигурации, а ${db.port} — только при старте команды, когда зависимость
+// уже готова и значение известно. $${db.port} — литеральная строка.
+//
+// Одной формы мало: `${process.version}` в шаблонной строке JavaScript
+// выглядит так же и до появления переменных цепочек передавался команде как
+// есть. Ссылкой кандидат становится, только если до точки — имя цепочки.
+//
+//nolint:gochecknoglobals // скомпилированное выражение, константой объявить нельзя
+var exportRe = regexp.MustCompile(`\$\$?\{([A-Za-z0-9_-]+)\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExportRef — ссылка на переменную цепочки.
+type ExportRef struct {
+	Chain string
+	Name  string
+}
+
+func (r ExportRef) String() string {
+	return "${" + r.Chain + "." + r.Name + "}"
+}
+
+// ExportRefs перечисляет ссылки на переменные цепочек в команде: в самой
+// команде, аргументах, окружении, условии готовности и пробе здоровья. isChain отличает
+// ссылку от похожего текста: остальное — литерал, а не ссылка.
+func (cmd Command) ExportRefs(isChain func(name string) bool) []ExportRef {
+	var refs []ExportRef
+
+	cmd.mapStrings(func(s string) string {
+		for _, m := range exportRe.FindAllStringSubmatch(s, -1) {
+			if !strings.HasPrefix(m[0], "$$") && isChain(m[1]) {
+				refs = append(refs, ExportRef{Chain: m[1], Name: m[2]})
+			}
+		}
+
+		return s
+	})
+
+	return refs
+}
+
+// ExpandExports возвращает копию команды с подставленными переменными цепочек;
+// isChain — как у ExportRefs.
+//
+// Переменная без значения — ошибка, а не пустая строка: порт, подставленный
+// пустым, дал бы команду, которая молча слушает не там.
+func (cmd Command) ExpandExports(
+	isChain func(name string) bool, lookup func(ref ExportRef) (string, bool),
+) (Command, error) {
+	var missing []string
+
+	out := cmd.mapStrings(func(s string) string {
+		if !strings.Contains(s, "${") {
+			return s
+		}
+
+		return exportRe.ReplaceAllStringFunc(s, func(match string) string {
+			m := exportRe.FindStringSubmatch(match)
+			if !isChain(m[1]) {
+				return match
+			}
+
+			if strings.HasPrefix(match, "$$") {
+				return match[1:]
+			}
+
+			ref := ExportRef{Chain: m[1], Name: m[2]}
+
+			value, ok := lookup(ref)
+			if !ok {
+				missing = append(missing, ref.String())
+			}
+
+			return value
+		})
+	})
+
+	if len(missing) > 0 {
+		return Command{}, fmt.Errorf("%w: %s", ErrUnresolvedExport, strings.Join(missing, ", "))
+	}
+
+	return out, nil
+}
+
+// mapStrings возвращает копию команды, пропустив через fn строки, в которых
+// допустимы переменные цепочек. Срезы и условие готовности копируются:
+// команда хранится по значению и делится между запусками.
+func (cmd Command) mapStrings(fn func(string) string) Command {
+	cmd.Cmd = fn(cmd.Cmd)
+	cmd.Args = mapAll(cmd.Args, fn)
+	cmd.Env = mapAll(cmd.Env, fn)
+	cmd.Ready = cmd.Ready.mapStrings(fn)
+
+	// Проба здоровья ходит туда же, куда и готовность: в порт или адрес,
+	// который выбрала зависимость.
+	if cmd.Health != nil {
+		health := *cmd.Health
+		health.Probe = health.Probe.mapStrings(fn)
+		cmd.Health = &health
+	}
+
+	return cmd
+}
+
+// mapStrings копирует условие, пропустив его строки через fn. Выражения не
+// трогаются: `${` в них — скорее квантификатор, чем переменная.
+func (r *ReadyCondition) mapStrings(fn func(string) string) *ReadyCondition {
+	if r == nil {
+		return nil
+	}
+
+	out := *r
+	out.TCP = fn(r.TCP)
+	out.LogLine = fn(r.LogLine)
+	out.Exec = mapAll(r.Exec, fn)
+
+	if r.File != nil {
+		out.File = &FileProbe{Path: fn(r.File.Path), NonEmpty: r.File.NonEmpty}
+	}
+
+	if r.HTTP != nil {
+		probe := *r.HTTP
+		probe.URL, probe.Body = fn(probe.URL), fn(probe.Body)
+		probe.Headers = maps.Clone(probe.Headers)
+
+		for key, value := range probe.Headers {
+			probe.Headers[key] = fn(value)
+		}
+
+		out.HTTP = &probe
+	}
+
+	out.All = mapParts(r.All, fn)
+	out.Any = mapParts(r.Any, fn)
+
+	return &out
+}
+
+func mapParts(parts []*ReadyCondition, fn func(string) string) []*ReadyCondition {
+	if parts == nil {
+		return nil
+	}
+
+	out := make([]*ReadyCondition, len(parts))
+	for i, part := range parts {
+		out[i] = part.mapStrings(fn)
+	}
+
+	return out
+}
+
+func mapAll(items []string, fn func(string) string) []string {
+	if items == nil {
+		return nil
+	}
+
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = fn(item)
+	}
+
+	return out
+}
+
+// validateExports проверяет ссылки на переменные цепочек: цепочка должна
+// быть в needs, а переменная — именованной группой её logLine.
+//
+// Обе ошибки ловятся до запуска: иначе опечатка в имени группы всплыла бы
+// только после того, как зависимость поднялась, — то есть через минуту
+// ожидания и уже с запущенными сервисами.
+func validateExports(f *Flow) error {
+	exports := make(map[string][]string, len(f.Chains))
+
+	for _, chain := range f.Chains {
+		for _, cmd := range chain.commands {
+			exports[chain.Name] = append(exports[chain.Name], cmd.Ready.Exports()...)
+		}
+	}
+
+	graph := newDepGraph(*f)
+
+	for _, chain := range f.Chains {
+		// Ссылка — то, что называет цепочку запуска или её needs; прочие
+		// ${a.b} остаются литералами, как и до переменных цепочек.
+		isChain := func(name string) bool {
+			_, known := exports[name]
+
+			return known || chain.NeedsChain(name)
+		}
+
+		for _, cmd := range chain.commands {
+			for _, ref := range cmd.ExportRefs(isChain) {
+				need, ok := neededFrom(graph, chain, ref.Chain)
+				if !ok {
+					return fmt.Errorf("%w: chain %q uses %s", ErrExportNotNeeded, chain.Name, ref)
+				}
+
+				// Переменные выставляет готовность: запущенный предшественник
+				// их ещё не знает.
+				if chain.NeedOf(need) == NeedStarted {
+					return fmt.Errorf("%w: chain %q uses %s, but needs %q only started",
+						ErrExportNotReady, chain.Name, ref, ref.Chain)
+				}
+
+				names, known := exports[ref.Chain]
+				if known && !slices.Contains(names, ref.Name) {
+					return fmt.Errorf("%w: chain %q uses %s, %q exports: %s",
+						ErrUnknownExport, chain.Name, ref, ref.Chain, describeExports(names))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// neededFrom находит запись needs, через которую цепочка ждёт источник
+// переменной: саму цепочку либо одну из её команд.
+func neededFrom(graph depGraph, chain *CommandChain, source string) (string, bool) {
+	for _, need := range chain.Needs {
+		if owner, ok := graph.chainOf(need); need == source || ok && owner.Name == source {
+			return need, true
+		}
+	}
+
+	return "", false
+}
+
+func describeExports(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
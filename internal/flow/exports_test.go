@@ -0,0 +1,207 @@
+package flow
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommand_ExpandExports(t *testing.T) {
+	cmd := Command{
+		Cmd:  "curl",
+		Args: []string{"http://127.0.0.1:${web.port}/", "$${web.port}", "${PORT}", "$${db.port}"},
+		Env:  []string{"TOKEN=${web.token}"},
+		Ready: &ReadyCondition{All: []*ReadyCondition{
+			{TCP: "127.0.0.1:${web.port}"},
+			{LogRegex: `port=(?P<p>\d+)`},
+		}},
+		Health: &Health{Probe: &ReadyCondition{HTTP: &HTTPProbe{URL: "http://127.0.0.1:${web.port}/healthz"}}},
+	}
+
+	vars := map[string]string{"port": "43127", "token": "abc"}
+
+	isWeb := func(name string) bool { return name == "web" }
+
+	got, err := cmd.ExpandExports(isWeb, func(ref ExportRef) (string, bool) {
+		v, ok := vars[ref.Name]
+
+		return v, ok && ref.Chain == "web"
+	})
+	if err != nil {
+		t.Fatalf("ExpandExports: %v", err)
+	}
+
+	// Переменные окружения с точкой не путаются, а экранированная ссылка
+	// остаётся литералом; не цепочка — литерал вместе с экранированием.
+	wantArgs := []string{"http://127.0.0.1:43127/", "${web.port}", "${PORT}", "$${db.port}"}
+	if !slices.Equal(got.Args, wantArgs) || got.Env[0] != "TOKEN=abc" {
+		t.Errorf("args = %q, env = %q", got.Args, got.Env)
+	}
+
+	if got.Ready.All[0].TCP != "127.0.0.1:43127" || got.Ready.All[1].LogRegex != `port=(?P<p>\d+)` {
+		t.Errorf("ready = %s", got.Ready.Describe())
+	}
+
+	if got.Health.Probe.HTTP.URL != "http://127.0.0.1:43127/healthz" {
+		t.Errorf("health = %s", got.Health.Describe())
+	}
+
+	// Исходная команда не тронута: её делят между запусками.
+	if cmd.Args[0] != "http://127.0.0.1:${web.port}/" || cmd.Ready.All[0].TCP != "127.0.0.1:${web.port}" ||
+		cmd.Health.Probe.HTTP.URL != "http://127.0.0.1:${web.port}/healthz" {
+		t.Error("подстановка изменила исходную команду")
+	}
+
+	if _, err := cmd.ExpandExports(isWeb, func(ExportRef) (string, bool) { return "", false }); !errors.Is(
+		err, ErrUnresolvedExport) {
+		t.Errorf("ожидалась ErrUnresolvedExport, получено %v", err)
+	}
+}
+
+func TestCommand_ExportRefs(t *testing.T) {
+	cmd := Command{
+		Cmd:  "echo",
+		Args: []string{"${db.port} $${db.user} ${os.arch}"},
+		Env:  []string{"DSN=${db-main.dsn}"},
+	}
+	isChain := func(name string) bool { return name != "os" }
+
+	want := []ExportRef{{Chain: "db", Name: "port"}, {Chain: "db-main", Name: "dsn"}}
+	if got := cmd.ExportRefs(isChain); !slices.Equal(got, want) {
+		t.Errorf("ExportRefs() = %v, ожидалось %v", got, want)
+	}
+}
+
+func TestFlow_ValidateExports(t *testing.T) {
+	build := func(needs []string, arg string) *Flow {
+		web := &CommandChain{Name: "web"}
+		web.Add(Command{Cmd: "vite", Ready: &ReadyCondition{LogRegex: `Local: .*:(?P<port>\d+)`}})
+
+		e2e := &CommandChain{Name: "e2e", Needs: needs}
+		e2e.Add(Command{Cmd: "npx", Args: []string{arg}})
+
+		return &Flow{Chains: []*CommandChain{web, e2e}}
+	}
+
+	tests := []struct {
+		name    string
+		flow    *Flow
+		wantErr error
+	}{
+		{name: "из needs", flow: build([]string{"web"}, "--port=${web.port}")},
+		{name: "не в needs", flow: build(nil, "--port=${web.port}"), wantErr: ErrExportNotNeeded},
+		{name: "нет такой группы", flow: build([]string{"web"}, "${web.host}"), wantErr: ErrUnknownExport},
+		{name: "экранированная", flow: build(nil, "$${web.port}")},
+		{name: "не цепочка", flow: build(nil, "console.log(`${process.version}`)")},
+		{name: "цепочка из needs вне запуска", flow: build([]string{"db"}, "${db.port}")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flow.Validate()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ожидалась %v, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestCommand_ExportsLeaveNonChainsLiteral — шаблонная строка JavaScript до
+// переменных цепочек доходила до команды как есть и должна доходить и дальше:
+// схема заморожена.
+func TestCommand_ExportsLeaveNonChainsLiteral(t *testing.T) {
+	script := "console.log(`${process.version}`)"
+	chain := &CommandChain{Name: "js", Needs: []string{"db.migrate"}}
+	chain.Add(Command{Cmd: "node", Args: []string{"-e", script}})
+
+	f := &Flow{Chains: []*CommandChain{chain}}
+	if err := f.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	got, err := chain.Commands()[0].ExpandExports(chain.NeedsChain, func(ExportRef) (string, bool) { return "", false })
+	if err != nil || got.Args[1] != script {
+		t.Fatalf("args = %q, err = %v", got.Args, err)
+	}
+
+	if !chain.NeedsChain("db") || chain.NeedsChain("process") || chain.NeedsChain("d") {
+		t.Error("NeedsChain: db ждётся через команду, остальные — нет")
+	}
+}
+
+// TestFlow_ValidateHealthExports — проба здоровья проверяется наравне с
+// командой: ссылка на цепочку не из needs отвергается до запуска.
+func TestFlow_ValidateHealthExports(t *testing.T) {
+	web := &CommandChain{Name: "web"}
+	web.Add(Command{Cmd: "vite", Ready: &ReadyCondition{LogRegex: `:(?P<port>\d+)`}})
+
+	api := &CommandChain{Name: "api"}
+	api.Add(Command{Cmd: "serve", Health: &Health{Probe: &ReadyCondition{TCP: "127.0.0.1:${web.port}"}}})
+
+	f := &Flow{Chains: []*CommandChain{web, api}}
+	if err := f.Validate(); !errors.Is(err, ErrExportNotNeeded) {
+		t.Fatalf("ожидалась ErrExportNotNeeded, получено %v", err)
+	}
+
+	api.Needs = []string{"web"}
+	if err := f.Validate(); err != nil {
+		t.Fatalf("из needs: %v", err)
+	}
+}
+
+// TestFlow_ValidateExportsNone — цепочка без именованных групп так и
+// называется в ошибке, а не пустым списком.
+func TestFlow_ValidateExportsNone(t *testing.T) {
+	db := &CommandChain{Name: "db"}
+	db.Add(Command{Cmd: "postgres", Ready: &ReadyCondition{TCP: "127.0.0.1:5432"}})
+
+	api := &CommandChain{Name: "api", Needs: []string{"db"}}
+	api.Add(Command{Cmd: "serve", Args: []string{"${db.port}"}})
+
+	err := (&Flow{Chains: []*CommandChain{db, api}}).Validate()
+	if !errors.Is(err, ErrUnknownExport) || !strings.HasSuffix(err.Error(), `"db" exports: none`) {
+		t.Fatalf("ожидалась ErrUnknownExport без переменных, получено %v", err)
+	}
+}
+
+// TestReadyCondition_MapStrings — подстановка доходит до каждой строки
+// условия, включая вложенные, и не трогает выражения и исходник.
+func TestReadyCondition_MapStrings(t *testing.T) {
+	ready := &ReadyCondition{Any: []*ReadyCondition{
+		{File: &FileProbe{Path: "/tmp/x", NonEmpty: true}},
+		{HTTP: &HTTPProbe{URL: "http://h/", Body: "ok", Headers: map[string]string{"Host": "h"}}},
+		{Exec: []string{"pg_isready", "-h", "h"}},
+		{LogRegex: `h=(?P<h>\w+)`},
+	}}
+
+	got := ready.mapStrings(strings.ToUpper)
+
+	if f := got.Any[0].File; f.Path != "/TMP/X" || !f.NonEmpty {
+		t.Errorf("file = %+v", f)
+	}
+
+	if h := got.Any[1].HTTP; h.URL != "HTTP://H/" || h.Body != "OK" || h.Headers["Host"] != "H" {
+		t.Errorf("http = %+v", h)
+	}
+
+	if e := got.Any[2].Exec; !slices.Equal(e, []string{"PG_ISREADY", "-H", "H"}) {
+		t.Errorf("exec = %q", e)
+	}
+
+	if got.Any[3].LogRegex != `h=(?P<h>\w+)` {
+		t.Errorf("выражение изменено: %q", got.Any[3].LogRegex)
+	}
+
+	if ready.Any[1].HTTP.Headers["Host"] != "h" || ready.Any[2].Exec[0] != "pg_isready" {
+		t.Error("подстановка изменила исходное условие")
+	}
+
+	if (*ReadyCondition)(nil).mapStrings(strings.ToUpper) != nil {
+		t.Error("nil-условие должно остаться nil")
+	}
+}
@@ -23,6 +23,8 @@ var (
 
 	// ErrUnknownRestartPolicy — значение поля restart вне перечисления.
 	ErrUnknownRestartPolicy = errors.New("unknown restart policy")
+	// ErrUnknownTimestampMode — значение format.timestamp вне перечисления.
+	ErrUnknownTimestampMode = errors.New("unknown timestamp mode")
 	// ErrNegativeTimeout — отрицательный срок ожидания.
 	ErrNegativeTimeout = errors.New("timeout cannot be negative")
 )
@@ -30,6 +32,14 @@ var (
 // Flow — разобранная конфигурация: набор цепочек, выполняемых параллельно.
 type Flow struct {
 	Chains []*CommandChain
+
+	// Before — команды подготовки: выполняются по очереди до старта цепочек,
+	// и отказ любой из них отменяет запуск.
+	Before []Command
+
+	// OnExit — команды, выполняемые один раз в конце запуска при любом
+	// исходе, в том числе после Ctrl+C.
+	OnExit []Command
 }
 
 func (f *Flow) AddChain(chain *CommandChain) {
@@ -51,14 +61,30 @@ func (f *Flow) Validate() error {
 			return fmt.Errorf("chain %q: %w", chain.Name, ErrEmptyChain)
 		}
 
+		if err := chain.Log.Validate(); err != nil {
+			return fmt.Errorf("chain %q, log: %w", chain.Name, err)
+		}
+
 		for _, cmd := range chain.commands {
 			if err := cmd.Validate(); err != nil {
 				return fmt.Errorf("invalid command in chain %q: %w", chain.Name, err)
 			}
 		}
+
+		if err := validateHooks(chain.Finally); err != nil {
+			return fmt.Errorf("chain %q, finally: %w", chain.Name, err)
+		}
 	}
 
-	return nil
+	if err := validateHooks(f.Before); err != nil {
+		return fmt.Errorf("before: %w", err)
+	}
+
+	if err := validateHooks(f.OnExit); err != nil {
+		return fmt.Errorf("onExit: %w", err)
+	}
+
+	return validateExports(f)
 }
 
 // CommandChain — именованная последовательность команд.
@@ -71,8 +97,23 @@ type CommandChain struct {
 	commands []Command
 	ColorIdx int
 
-	// Needs — цепочки, готовности которых надо дождаться перед запуском.
+	// Needs — цепочки, которых надо дождаться перед запуском, в порядке
+	// объявления.
 	Needs []string
+	// NeedConditions — чего ждать от предшественника. Нет записи — ready.
+	NeedConditions map[string]NeedCondition
+
+	// Log — файл, в который дублируется вывод цепочки. nil — не дублировать.
+	Log *LogFile
+
+	// Tags — метки для отбора: `parallel @backend` выбирает все цепочки с
+	// тегом backend. На запуск цепочки они не влияют.
+	Tags []string
+
+	// Finally — команды уборки: выполняются после каждого запуска цепочки
+	// при любом исходе, в том числе после Ctrl+C. Цепочка, которая так и не
+	// запустилась, уборки не делает.
+	Finally []Command
 }
 
 func (cc *CommandChain) GetChainName() string {
@@ -0,0 +1,96 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrHealthProbe — проба здоровья не задана либо такой не бывает.
+	ErrHealthProbe = errors.New("health must define one of tcp, http, file or exec")
+	// ErrHealthThreshold — порог отказов отрицательный.
+	ErrHealthThreshold = errors.New("health threshold cannot be negative")
+)
+
+// Умолчания проверки здоровья.
+const (
+	// DefaultHealthInterval — как часто пробовать, если интервал не задан.
+	// Реже, чем ждётся готовность: здоровье проверяется всё время работы
+	// сервиса, и частая проба сама становилась бы нагрузкой.
+	DefaultHealthInterval = 10 * time.Second
+	// DefaultHealthThreshold — сколько отказов подряд делают команду
+	// нездоровой. Один отказ — это ещё сборка мусора или медленный запрос.
+	DefaultHealthThreshold = 3
+)
+
+// Health описывает, как проверять уже работающую команду.
+//
+// Готовность — разовый гейт: открывшись, он больше ничего не проверяет.
+// Зависший процесс не выходит, и политика перезапуска о нём не узнаёт.
+// Проверка здоровья пробует команду всё время работы и, когда отказов
+// набирается порог, снимает её как упавшую — дальше решает политика.
+type Health struct {
+	// Probe — проба: tcp, http, file или exec. logLine здесь не бывает:
+	// строка журнала появляется однажды и о живости ничего не говорит.
+	Probe *ReadyCondition
+	// Interval — пауза между пробами; ноль означает DefaultHealthInterval.
+	// Она же — предел одной пробы, чтобы зависшая проба не копилась.
+	Interval time.Duration
+	// Threshold — сколько отказов подряд делают команду нездоровой; ноль
+	// означает DefaultHealthThreshold.
+	Threshold int
+}
+
+// Validate проверяет пробу и числа.
+func (h *Health) Validate() error {
+	if h == nil {
+		return nil
+	}
+
+	p := h.Probe
+	if p == nil || p.LogLine != "" || p.LogRegex != "" || p.All != nil || p.Any != nil {
+		return ErrHealthProbe
+	}
+
+	if err := p.validate(); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+
+	if h.Interval < 0 {
+		return fmt.Errorf("%w: health interval is %s", ErrNegativeTimeout, h.Interval)
+	}
+
+	if h.Threshold < 0 {
+		return fmt.Errorf("%w, got %d", ErrHealthThreshold, h.Threshold)
+	}
+
+	return nil
+}
+
+// Every возвращает интервал проб с учётом умолчания.
+func (h *Health) Every() time.Duration {
+	if h == nil || h.Interval <= 0 {
+		return DefaultHealthInterval
+	}
+
+	return h.Interval
+}
+
+// Failures возвращает порог отказов с учётом умолчания.
+func (h *Health) Failures() int {
+	if h == nil || h.Threshold <= 0 {
+		return DefaultHealthThreshold
+	}
+
+	return h.Threshold
+}
+
+// Describe коротко описывает проверку — для предпросмотра.
+func (h *Health) Describe() string {
+	if h == nil {
+		return "none"
+	}
+
+	return fmt.Sprintf("%s every %s, unhealthy after %d failures", h.Probe.Describe(), h.Every(), h.Failures())
+}
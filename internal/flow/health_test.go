@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealth_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		health  *Health
+		wantErr error
+	}{
+		{name: "отсутствует вовсе", health: nil},
+		{name: "tcp", health: &Health{Probe: &ReadyCondition{TCP: "127.0.0.1:5672"}}},
+		{
+			name: "http с интервалом и порогом",
+			health: &Health{
+				Probe:    &ReadyCondition{HTTP: &HTTPProbe{URL: "http://x/healthz"}},
+				Interval: time.Second, Threshold: 5,
+			},
+		},
+		{name: "без пробы", health: &Health{}, wantErr: ErrHealthProbe},
+		{name: "logLine", health: &Health{Probe: &ReadyCondition{LogLine: "up"}}, wantErr: ErrHealthProbe},
+		{
+			name:    "составная",
+			health:  &Health{Probe: &ReadyCondition{All: []*ReadyCondition{{TCP: "127.0.0.1:1"}}}},
+			wantErr: ErrHealthProbe,
+		},
+		{
+			name:    "две пробы сразу",
+			health:  &Health{Probe: &ReadyCondition{TCP: "127.0.0.1:1", Exec: []string{"true"}}},
+			wantErr: ErrReadyAmbiguous,
+		},
+		{
+			name:    "битый http",
+			health:  &Health{Probe: &ReadyCondition{HTTP: &HTTPProbe{URL: "localhost"}}},
+			wantErr: ErrReadyHTTP,
+		},
+		{
+			name:    "отрицательный интервал",
+			health:  &Health{Probe: &ReadyCondition{TCP: "127.0.0.1:1"}, Interval: -time.Second},
+			wantErr: ErrNegativeTimeout,
+		},
+		{
+			name:    "отрицательный порог",
+			health:  &Health{Probe: &ReadyCondition{TCP: "127.0.0.1:1"}, Threshold: -1},
+			wantErr: ErrHealthThreshold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.health.Validate()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("неожиданная ошибка: %v", err)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ожидалась %v, получено %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHealth_Defaults(t *testing.T) {
+	h := &Health{Probe: &ReadyCondition{TCP: "127.0.0.1:5672"}}
+
+	if h.Every() != DefaultHealthInterval || h.Failures() != DefaultHealthThreshold {
+		t.Fatalf("умолчания: %s, %d", h.Every(), h.Failures())
+	}
+
+	want := "tcp 127.0.0.1:5672 every 10s, unhealthy after 3 failures"
+	if got := h.Describe(); got != want {
+		t.Fatalf("Describe() = %q, ожидалось %q", got, want)
+	}
+
+	h.Interval, h.Threshold = time.Second, 5
+	if h.Every() != time.Second || h.Failures() != 5 {
+		t.Errorf("заданные значения: %s, %d", h.Every(), h.Failures())
+	}
+
+	// Без секции health предпросмотр и умолчания не должны падать.
+	var none *Health
+	if none.Every() != DefaultHealthInterval || none.Failures() != DefaultHealthThreshold || none.Describe() != "none" {
+		t.Errorf("nil: %s, %d, %q", none.Every(), none.Failures(), none.Describe())
+	}
+}
+
+// TestCommand_ValidateHealth — битая проверка здоровья делает негодной всю команду.
+func TestCommand_ValidateHealth(t *testing.T) {
+	cmd := Command{Cmd: "worker", Health: &Health{}}
+	if err := cmd.Validate(); !errors.Is(err, ErrHealthProbe) {
+		t.Fatalf("ожидалась ErrHealthProbe, получено %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHookCommand — у команды before, finally или onExit поле, которому там
+// нет смысла.
+var ErrHookCommand = errors.New("before, finally and onExit commands cannot have ready, health, watch or restart")
+
+// DefaultTeardownTimeout — сколько даётся одному списку уборки: finally
+// цепочки либо onExit. Уборка идёт и после Ctrl+C, и зависшая
+// `docker compose down` не должна держать выход бесконечно. Собственный
+// timeout команды действует внутри этого срока.
+const DefaultTeardownTimeout = 30 * time.Second
+
+// validateHooks проверяет команды подготовки и уборки.
+//
+// Готовность, здоровье, слежение за файлами и перезапуск описывают
+// работающий сервис. Подготовка и уборка выполняются по разу и до конца, и
+// такое поле в них — почти наверняка команда, вставленная не в ту секцию.
+func validateHooks(cmds []Command) error {
+	for _, cmd := range cmds {
+		if err := cmd.Validate(); err != nil {
+			return err
+		}
+
+		service := cmd.Ready != nil || cmd.Health != nil || cmd.Watch != nil
+		if service || cmd.Restart.String() != string(RestartNever) {
+			return fmt.Errorf("%w: command %q", ErrHookCommand, cmd.DisplayName())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestFlow_ValidateHooks — поля работающего сервиса в подготовке и уборке
+// отвергаются, а сами команды проверяются так же, как команды цепочки.
+func TestFlow_ValidateHooks(t *testing.T) {
+	build := func(finally, onExit Command) *Flow {
+		chain := &CommandChain{Name: "db", Finally: []Command{finally}}
+		chain.Add(Command{Cmd: "docker"})
+
+		return &Flow{Chains: []*CommandChain{chain}, OnExit: []Command{onExit}}
+	}
+
+	ok := Command{Name: "down", Cmd: "docker"}
+	if err := build(ok, ok).Validate(); err != nil {
+		t.Fatalf("корректная уборка отвергнута: %v", err)
+	}
+
+	for name, bad := range map[string]Command{
+		"ready":   {Cmd: "docker", Ready: &ReadyCondition{TCP: "localhost:5432", Timeout: time.Second}},
+		"restart": {Cmd: "docker", Restart: RestartOnFailure},
+	} {
+		if err := build(bad, ok).Validate(); !errors.Is(err, ErrHookCommand) {
+			t.Errorf("finally с %s: %v", name, err)
+		}
+
+		if err := build(ok, bad).Validate(); !errors.Is(err, ErrHookCommand) {
+			t.Errorf("onExit с %s: %v", name, err)
+		}
+	}
+
+	if err := build(Command{}, ok).Validate(); err == nil || errors.Is(err, ErrHookCommand) {
+		t.Errorf("пустая команда уборки: %v", err)
+	}
+
+	f := build(ok, ok)
+	f.Before = []Command{{Cmd: "go", Restart: RestartAlways}}
+
+	if err := f.Validate(); !errors.Is(err, ErrHookCommand) {
+		t.Errorf("before с restart: %v", err)
+	}
+}
+
+// TestSelect_KeepsHooks — before и onExit относятся к запуску, и сужение
+// отбора их не теряет — даже через -except.
+func TestSelect_KeepsHooks(t *testing.T) {
+	f := depsFlow(nil, "db", "api")
+	f.Before = []Command{{Name: "deps", Cmd: "go"}}
+	f.OnExit = []Command{{Name: "unlock", Cmd: "rm"}}
+
+	got, err := Select(f, nil, []string{"db"})
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+
+	if len(got.Before) != 1 || len(got.OnExit) != 1 {
+		t.Errorf("после отбора before = %+v, onExit = %+v", got.Before, got.OnExit)
+	}
+}
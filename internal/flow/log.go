@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrLogFileEmpty — секция log не называет файла.
+	ErrLogFileEmpty = errors.New("log file cannot be empty")
+	// ErrNegativeLogLimit — отрицательный предел размера или числа файлов.
+	ErrNegativeLogLimit = errors.New("log limit cannot be negative")
+)
+
+// DefaultLogMaxFiles — сколько прежних файлов хранить при ротации, если
+// число не задано. Ночь работы болтливого сервиса укладывается в несколько
+// файлов, а неограниченная история — это диск, который однажды кончится.
+const DefaultLogMaxFiles = 3
+
+// LogFile описывает файл, в который дублируется вывод цепочки.
+//
+// Как писать и когда ротировать — дело раннера; домен знает только параметры.
+type LogFile struct {
+	// Path — путь к файлу, уже разрешённый относительно конфигурации.
+	Path string
+	// MaxSize — размер в байтах, по достижении которого файл ротируется;
+	// ноль — без ротации.
+	MaxSize int64
+	// MaxFiles — сколько прежних файлов хранить; ноль означает
+	// DefaultLogMaxFiles.
+	MaxFiles int
+	// Timestamps предваряет каждую строку временем её получения.
+	Timestamps bool
+}
+
+// Validate проверяет параметры файла.
+func (l *LogFile) Validate() error {
+	if l == nil {
+		return nil
+	}
+
+	if l.Path == "" {
+		return ErrLogFileEmpty
+	}
+
+	if l.MaxSize < 0 || l.MaxFiles < 0 {
+		return fmt.Errorf("%w: maxSize is %d, maxFiles is %d", ErrNegativeLogLimit, l.MaxSize, l.MaxFiles)
+	}
+
+	return nil
+}
+
+// Keep возвращает число хранимых прежних файлов с учётом умолчания.
+func (l *LogFile) Keep() int {
+	if l == nil || l.MaxFiles <= 0 {
+		return DefaultLogMaxFiles
+	}
+
+	return l.MaxFiles
+}
+
+// Describe коротко описывает файл — для предпросмотра.
+func (l *LogFile) Describe() string {
+	if l == nil {
+		return "none"
+	}
+
+	desc := l.Path
+	if l.MaxSize > 0 {
+		desc += fmt.Sprintf(", rotate at %d bytes, keep %d", l.MaxSize, l.Keep())
+	}
+
+	if l.Timestamps {
+		desc += ", timestamps"
+	}
+
+	return desc
+}
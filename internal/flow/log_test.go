@@ -0,0 +1,44 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLogFile_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     *LogFile
+		wantErr error
+	}{
+		{name: "отсутствует вовсе", log: nil},
+		{name: "только путь", log: &LogFile{Path: "logs/api.log"}},
+		{name: "без пути", log: &LogFile{MaxSize: 1024}, wantErr: ErrLogFileEmpty},
+		{name: "отрицательный размер", log: &LogFile{Path: "a.log", MaxSize: -1}, wantErr: ErrNegativeLogLimit},
+		{name: "отрицательное число файлов", log: &LogFile{Path: "a.log", MaxFiles: -1}, wantErr: ErrNegativeLogLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.log.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("получено %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogFile_KeepAndDescribe(t *testing.T) {
+	var none *LogFile
+	if none.Keep() != DefaultLogMaxFiles || none.Describe() != "none" {
+		t.Errorf("nil: %d, %q", none.Keep(), none.Describe())
+	}
+
+	log := &LogFile{Path: "api.log", MaxSize: 1024, MaxFiles: 5, Timestamps: true}
+	if log.Keep() != 5 {
+		t.Errorf("заданное число файлов должно сохраниться, получено %d", log.Keep())
+	}
+
+	if got := log.Describe(); got != "api.log, rotate at 1024 bytes, keep 5, timestamps" {
+		t.Errorf("описание: %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownNeedCondition — условие в needs вне перечисления.
+var ErrUnknownNeedCondition = errors.New("unknown needs condition")
+
+// NeedCondition — чего дождаться от предшественника.
+//
+// Одного значения needs недостаточно: сервер не завершается, и ждать его
+// можно только до готовности, а разовое задание вроде наполнения базы
+// готово, лишь когда отработало. Цепочке, которой нужны оба, приходится
+// говорить, чего ждать от каждого.
+type NeedCondition string
+
+// Условия needs.
+const (
+	// NeedReady — умолчание и прежний смысл needs: готовность, если у
+	// предшественника есть условия готовности, иначе успешное завершение.
+	NeedReady NeedCondition = "ready"
+	// NeedStarted — предшественник запущен; его готовность не важна.
+	NeedStarted NeedCondition = "started"
+	// NeedCompleted — предшественник завершился с любым исходом. Нужно
+	// уборке, которая работает и после отказа.
+	NeedCompleted NeedCondition = "completed"
+	// NeedCompletedSuccessfully — предшественник завершился успешно, даже
+	// если у него есть условия готовности.
+	NeedCompletedSuccessfully NeedCondition = "completed_successfully"
+)
+
+// needConditions перечисляет допустимые значения в порядке от раннего
+// события к позднему — в этом же порядке они показываются в ошибке.
+//
+//nolint:gochecknoglobals // неизменяемый список, массивом объявить нельзя
+var needConditions = []NeedCondition{NeedStarted, NeedReady, NeedCompleted, NeedCompletedSuccessfully}
+
+// String возвращает условие в том виде, в каком его пишут в конфигурации.
+func (c NeedCondition) String() string {
+	if c == "" {
+		return string(NeedReady)
+	}
+
+	return string(c)
+}
+
+// ParseNeedCondition разбирает условие из needs. Пустая строка — краткая
+// форма needs без условия, то есть готовность.
+func ParseNeedCondition(s string) (NeedCondition, error) {
+	if s == "" {
+		return NeedReady, nil
+	}
+
+	cond := NeedCondition(s)
+	for _, known := range needConditions {
+		if cond == known {
+			return cond, nil
+		}
+	}
+
+	names := make([]string, 0, len(needConditions))
+	for _, known := range needConditions {
+		names = append(names, string(known))
+	}
+
+	return "", fmt.Errorf("%w %q, allowed: %s", ErrUnknownNeedCondition, s, strings.Join(names, ", "))
+}
+
+// NeedsChain сообщает, ждёт ли цепочка цепочку name — целиком или одну из её
+// команд.
+func (cc *CommandChain) NeedsChain(name string) bool {
+	for _, need := range cc.Needs {
+		if need == name || strings.HasPrefix(need, name+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeedOf возвращает условие, которого цепочка ждёт от предшественника.
+func (cc *CommandChain) NeedOf(name string) NeedCondition {
+	if cond, ok := cc.NeedConditions[name]; ok && cond != "" {
+		return cond
+	}
+
+	return NeedReady
+}
+
+// DescribeNeeds перечисляет предшественников для показа. Условие ready не
+// пишется: это умолчание, и краткая форма needs его не упоминает.
+func (cc *CommandChain) DescribeNeeds() string {
+	parts := make([]string, 0, len(cc.Needs))
+
+	for _, need := range cc.Needs {
+		if cond := cc.NeedOf(need); cond != NeedReady {
+			need += " (" + string(cond) + ")"
+		}
+
+		parts = append(parts, need)
+	}
+
+	return strings.Join(parts, ", ")
+}
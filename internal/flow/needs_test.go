@@ -0,0 +1,81 @@
+package flow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseNeedCondition(t *testing.T) {
+	valid := map[string]NeedCondition{
+		"":                       NeedReady,
+		"ready":                  NeedReady,
+		"started":                NeedStarted,
+		"completed":              NeedCompleted,
+		"completed_successfully": NeedCompletedSuccessfully,
+	}
+
+	for in, want := range valid {
+		if got, err := ParseNeedCondition(in); err != nil || got != want {
+			t.Errorf("%q → %q, %v; ожидалось %q", in, got, err, want)
+		}
+	}
+
+	// Написание compose с дефисом или префиксом service_ — частая опечатка.
+	for _, bad := range []string{"completed-successfully", "service_healthy", "Ready"} {
+		_, err := ParseNeedCondition(bad)
+		if !errors.Is(err, ErrUnknownNeedCondition) || !strings.Contains(err.Error(), "completed_successfully") {
+			t.Errorf("%q: %v", bad, err)
+		}
+	}
+}
+
+func TestCommandChain_DescribeNeeds(t *testing.T) {
+	chain := &CommandChain{
+		Needs: []string{"db", "seed", "logs"},
+		NeedConditions: map[string]NeedCondition{
+			"db": NeedReady, "seed": NeedCompletedSuccessfully, "logs": NeedStarted,
+		},
+	}
+
+	want := "db, seed (completed_successfully), logs (started)"
+	if got := chain.DescribeNeeds(); got != want {
+		t.Errorf("DescribeNeeds() = %q, ожидалось %q", got, want)
+	}
+
+	if got := chain.NeedOf("other"); got != NeedReady {
+		t.Errorf("умолчание = %q", got)
+	}
+
+	if got := NeedCondition("").String(); got != "ready" {
+		t.Errorf("пустое условие = %q", got)
+	}
+}
+
+// TestValidateDeps_UnknownCondition — условие в needs проверяет домен:
+// программная сборка Flow идёт мимо разбора YAML.
+func TestValidateDeps_UnknownCondition(t *testing.T) {
+	f := depsFlow(map[string][]string{"api": {"db"}}, "db", "api")
+	f.Chains[1].NeedConditions = map[string]NeedCondition{"db": "healthy"}
+
+	if err := ValidateDeps(f); !errors.Is(err, ErrUnknownNeedCondition) {
+		t.Fatalf("ожидалась ErrUnknownNeedCondition, получено %v", err)
+	}
+}
+
+// TestFlow_ValidateExportsStarted — переменные выставляет готовность, и
+// зависимый, ждущий лишь запуска, получить их не может.
+func TestFlow_ValidateExportsStarted(t *testing.T) {
+	web := &CommandChain{Name: "web"}
+	web.Add(Command{Cmd: "vite", Ready: &ReadyCondition{LogRegex: `:(?P<port>\d+)`}})
+
+	e2e := &CommandChain{
+		Name: "e2e", Needs: []string{"web"}, NeedConditions: map[string]NeedCondition{"web": NeedStarted},
+	}
+	e2e.Add(Command{Cmd: "npx", Args: []string{"--port=${web.port}"}})
+
+	f := &Flow{Chains: []*CommandChain{web, e2e}}
+	if err := f.Validate(); !errors.Is(err, ErrExportNotReady) {
+		t.Fatalf("ожидалась ErrExportNotReady, получено %v", err)
+	}
+}
@@ -3,19 +3,41 @@ package flow
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var (
 	// ErrReadyEmpty — секция ready не задаёт ни одного условия.
-	ErrReadyEmpty = errors.New("ready must define one of tcp, exec or logLine")
+	ErrReadyEmpty = errors.New("ready must define one of tcp, http, file, exec, logLine, all or any")
 	// ErrReadyAmbiguous — задано больше одного условия сразу.
 	ErrReadyAmbiguous = errors.New("ready must define exactly one condition")
+	// ErrReadyNestedTimeout — срок задан у условия внутри all или any.
+	ErrReadyNestedTimeout = errors.New("timeout belongs to the top-level ready only")
+	// ErrReadyHTTP — проба http задана так, что выполниться не может.
+	ErrReadyHTTP = errors.New("invalid ready http probe")
+	// ErrReadyLogRegex — выражение logLine не разбирается.
+	ErrReadyLogRegex = errors.New("invalid ready logLine regex")
 )
 
 // DefaultReadyTimeout — сколько ждать готовности, если срок не задан.
 const DefaultReadyTimeout = 30 * time.Second
 
+// Границы кодов ответа HTTP.
+const (
+	// minHTTPStatus и maxHTTPStatus — коды, которые вообще бывают.
+	minHTTPStatus = 100
+	maxHTTPStatus = 599
+	// defaultStatusMin и defaultStatusMax — успешный ответ, если коды не
+	// заданы: 3xx означал бы перенаправление, которое проба не проходит.
+	defaultStatusMin = 200
+	defaultStatusMax = 299
+)
+
 // ReadyCondition описывает, по какому признаку команда считается готовой.
 //
 // Домен только описывает условие; как его проверять — дело раннера. Здесь нет
@@ -23,27 +45,62 @@ const DefaultReadyTimeout = 30 * time.Second
 type ReadyCondition struct {
 	// TCP — адрес вида host:port, который должен начать принимать соединения.
 	TCP string
+	// HTTP — адрес, который должен ответить ожидаемым кодом и телом.
+	HTTP *HTTPProbe
+	// File — файл, который должен появиться.
+	File *FileProbe
 	// Exec — команда, которую надо повторять до нулевого кода возврата.
 	Exec []string
 	// LogLine — подстрока, появление которой в выводе означает готовность.
 	LogLine string
+	// LogRegex — то же регулярным выражением. Именованные группы
+	// совпавшей строки становятся переменными цепочки: `${chain.group}` в
+	// командах зависимых цепочек.
+	LogRegex string
+	// All и Any составляют условие из других: готовность наступает, когда
+	// выполнились все вложенные либо хотя бы одно.
+	All []*ReadyCondition
+	Any []*ReadyCondition
 	// Timeout ограничивает ожидание; ноль означает DefaultReadyTimeout.
+	// Задаётся только у внешнего условия: срок на всё ожидание один.
 	Timeout time.Duration
 }
 
-// Validate проверяет, что задано ровно одно условие.
+// FileProbe — условие готовности по появлению файла.
+type FileProbe struct {
+	// Path — абсолютный путь; относительный в конфигурации разрешается при
+	// сборке.
+	Path string
+	// NonEmpty требует, чтобы файл был не пуст: сборщик, создавший манифест
+	// и ещё не дописавший его, готовым не считается.
+	NonEmpty bool
+}
+
+// Validate проверяет, что задано ровно одно условие, а составные — из
+// верных условий.
 //
 // Ни одного — почти наверняка недосмотр при правке, и трактовать это как
-// «готова сразу» значило бы тихо снять ожидание. Больше одного — неясно, какое
-// из них главное, и выбирать за пользователя нельзя.
+// «готова сразу» значило бы тихо снять ожидание. Больше одного — неясно, как
+// их сочетать, и выбирать за пользователя нельзя: для этого есть all и any.
 func (r *ReadyCondition) Validate() error {
 	if r == nil {
 		return nil
 	}
 
+	if r.Timeout < 0 {
+		return fmt.Errorf("%w: ready timeout is %s", ErrNegativeTimeout, r.Timeout)
+	}
+
+	return r.validate()
+}
+
+func (r *ReadyCondition) validate() error {
 	defined := 0
 
-	for _, set := range []bool{r.TCP != "", len(r.Exec) > 0, r.LogLine != ""} {
+	for _, set := range []bool{
+		r.TCP != "", r.HTTP != nil, r.File != nil, len(r.Exec) > 0, r.LogLine != "", r.LogRegex != "",
+		r.All != nil, r.Any != nil,
+	} {
 		if set {
 			defined++
 		}
@@ -54,13 +111,73 @@ func (r *ReadyCondition) Validate() error {
 		return ErrReadyEmpty
 	case defined > 1:
 		return fmt.Errorf("%w, got %d", ErrReadyAmbiguous, defined)
+	case r.File != nil && r.File.Path == "":
+		return fmt.Errorf("%w: file path is empty", ErrReadyEmpty)
 	}
 
-	if r.Timeout < 0 {
-		return fmt.Errorf("%w: ready timeout is %s", ErrNegativeTimeout, r.Timeout)
+	for name, parts := range map[string][]*ReadyCondition{"all": r.All, "any": r.Any} {
+		if parts != nil && len(parts) == 0 {
+			return fmt.Errorf("%w: %s lists no conditions", ErrReadyEmpty, name)
+		}
+
+		for _, part := range parts {
+			if part == nil {
+				return fmt.Errorf("%w: %s lists an empty condition", ErrReadyEmpty, name)
+			}
+
+			if part.Timeout != 0 {
+				return fmt.Errorf("%w: %s", ErrReadyNestedTimeout, part.Describe())
+			}
+
+			if err := part.validate(); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	if _, err := r.LinePattern(); err != nil {
+		return err
+	}
+
+	return r.HTTP.validate()
+}
+
+// LinePattern разбирает LogRegex; nil — выражение не задано.
+func (r *ReadyCondition) LinePattern() (*regexp.Regexp, error) {
+	if r.LogRegex == "" {
+		return nil, nil //nolint:nilnil // отсутствие выражения — не ошибка
+	}
+
+	re, err := regexp.Compile(r.LogRegex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReadyLogRegex, err)
+	}
+
+	return re, nil
+}
+
+// Exports перечисляет переменные, которые условие выставляет цепочке:
+// именованные группы всех вложенных выражений logLine.
+func (r *ReadyCondition) Exports() []string {
+	if r == nil {
+		return nil
+	}
+
+	var names []string
+
+	if re, err := r.LinePattern(); err == nil && re != nil {
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, part := range slices.Concat(r.All, r.Any) {
+		names = append(names, part.Exports()...)
 	}
 
-	return nil
+	return names
 }
 
 // Limit возвращает срок ожидания с учётом умолчания.
@@ -79,11 +196,118 @@ func (r *ReadyCondition) Describe() string {
 		return "none"
 	case r.TCP != "":
 		return fmt.Sprintf("tcp %s", r.TCP)
+	case r.HTTP != nil:
+		return r.HTTP.describe()
+	case r.File != nil:
+		return r.File.describe()
 	case len(r.Exec) > 0:
 		return fmt.Sprintf("exec %v", r.Exec)
 	case r.LogLine != "":
 		return fmt.Sprintf("log line %q", r.LogLine)
+	case r.LogRegex != "":
+		return fmt.Sprintf("log line matching %q", r.LogRegex)
+	case len(r.All) > 0:
+		return describeAll("all of", r.All)
+	case len(r.Any) > 0:
+		return describeAll("any of", r.Any)
 	default:
 		return "none"
 	}
 }
+
+// describeAll называет составное условие: «all of (tcp :5432, log line "up")».
+func describeAll(kind string, parts []*ReadyCondition) string {
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		names = append(names, part.Describe())
+	}
+
+	return kind + " (" + strings.Join(names, ", ") + ")"
+}
+
+func (f *FileProbe) describe() string {
+	if f.NonEmpty {
+		return fmt.Sprintf("file %s, non-empty", f.Path)
+	}
+
+	return fmt.Sprintf("file %s", f.Path)
+}
+
+// HTTPProbe — условие готовности по ответу HTTP.
+//
+// Открытый порт ещё не значит, что сервис готов: API отвечает 503, пока не
+// прогреты кэши и не прошли миграции. Проба ждёт ответа, который говорит
+// именно о готовности.
+type HTTPProbe struct {
+	// URL — адрес запроса GET, http или https.
+	URL string
+	// StatusMin и StatusMax — допустимые коды ответа включительно; нули —
+	// любой 2xx.
+	StatusMin int
+	StatusMax int
+	// Body — подстрока, которая должна быть в теле ответа.
+	Body string
+	// BodyRegex — регулярное выражение, под которое должно подойти тело.
+	// Хранится строкой: разбирается при проверке, а сравнение определений при
+	// перечитывании конфигурации скомпилированное выражение бы только путало.
+	BodyRegex string
+	// Headers — заголовки запроса.
+	Headers map[string]string
+	// Insecure отключает проверку TLS-сертификата — для самоподписанных
+	// сертификатов локальной разработки.
+	Insecure bool
+}
+
+// Statuses возвращает допустимые коды ответа с учётом умолчания.
+func (p *HTTPProbe) Statuses() (low, high int) {
+	if p.StatusMin == 0 && p.StatusMax == 0 {
+		return defaultStatusMin, defaultStatusMax
+	}
+
+	return p.StatusMin, p.StatusMax
+}
+
+// BodyPattern разбирает BodyRegex; nil — выражение не задано.
+func (p *HTTPProbe) BodyPattern() (*regexp.Regexp, error) {
+	if p.BodyRegex == "" {
+		return nil, nil //nolint:nilnil // отсутствие выражения — не ошибка
+	}
+
+	re, err := regexp.Compile(p.BodyRegex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bodyRegex: %w", ErrReadyHTTP, err)
+	}
+
+	return re, nil
+}
+
+func (p *HTTPProbe) validate() error {
+	if p == nil {
+		return nil
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url %q must be an absolute http or https address", ErrReadyHTTP, p.URL)
+	}
+
+	low, high := p.Statuses()
+	if low < minHTTPStatus || high > maxHTTPStatus || low > high {
+		return fmt.Errorf("%w: status range %d-%d", ErrReadyHTTP, low, high)
+	}
+
+	_, err = p.BodyPattern()
+
+	return err
+}
+
+func (p *HTTPProbe) describe() string {
+	low, high := p.Statuses()
+
+	status := strconv.Itoa(low)
+	if high != low {
+		status += "-" + strconv.Itoa(high)
+	}
+
+	return fmt.Sprintf("http %s (status %s)", p.URL, status)
+}
@@ -16,6 +16,46 @@ func TestReadyCondition_Validate(t *testing.T) {
 		{name: "tcp", ready: &ReadyCondition{TCP: "127.0.0.1:5432"}},
 		{name: "exec", ready: &ReadyCondition{Exec: []string{"pg_isready"}}},
 		{name: "logLine", ready: &ReadyCondition{LogLine: "ready"}},
+		{name: "logLine выражением", ready: &ReadyCondition{LogRegex: `port (?P<port>\d+)`}},
+		{name: "битое выражение logLine", ready: &ReadyCondition{LogRegex: "("}, wantErr: ErrReadyLogRegex},
+		{name: "http", ready: &ReadyCondition{HTTP: &HTTPProbe{URL: "http://localhost:8080/healthz"}}},
+		{
+			name:    "http без схемы",
+			ready:   &ReadyCondition{HTTP: &HTTPProbe{URL: "localhost:8080"}},
+			wantErr: ErrReadyHTTP,
+		},
+		{
+			name:    "перевёрнутый диапазон кодов",
+			ready:   &ReadyCondition{HTTP: &HTTPProbe{URL: "http://x", StatusMin: 299, StatusMax: 200}},
+			wantErr: ErrReadyHTTP,
+		},
+		{
+			name:    "неразборчивое выражение тела",
+			ready:   &ReadyCondition{HTTP: &HTTPProbe{URL: "http://x", BodyRegex: "("}},
+			wantErr: ErrReadyHTTP,
+		},
+		{name: "file", ready: &ReadyCondition{File: &FileProbe{Path: "/srv/dist/manifest.json"}}},
+		{
+			name: "all из двух",
+			ready: &ReadyCondition{All: []*ReadyCondition{
+				{TCP: "127.0.0.1:5432"}, {LogLine: "database system is ready"},
+			}},
+		},
+		{
+			name:    "пустой all",
+			ready:   &ReadyCondition{All: []*ReadyCondition{}},
+			wantErr: ErrReadyEmpty,
+		},
+		{
+			name:    "срок внутри any",
+			ready:   &ReadyCondition{Any: []*ReadyCondition{{TCP: "127.0.0.1:1", Timeout: time.Second}}},
+			wantErr: ErrReadyNestedTimeout,
+		},
+		{
+			name:    "два условия внутри all",
+			ready:   &ReadyCondition{All: []*ReadyCondition{{TCP: "127.0.0.1:1", LogLine: "up"}}},
+			wantErr: ErrReadyAmbiguous,
+		},
 		{name: "ни одного", ready: &ReadyCondition{}, wantErr: ErrReadyEmpty},
 		{
 			name:    "два сразу",
@@ -65,9 +105,20 @@ func TestReadyCondition_Limit(t *testing.T) {
 // чего именно не дождались.
 func TestReadyCondition_Describe(t *testing.T) {
 	cases := map[string]*ReadyCondition{
-		"tcp 127.0.0.1:5432":   {TCP: "127.0.0.1:5432"},
-		`log line "ready"`:     {LogLine: "ready"},
-		"exec [pg_isready -q]": {Exec: []string{"pg_isready", "-q"}},
+		"tcp 127.0.0.1:5432":                    {TCP: "127.0.0.1:5432"},
+		`log line "ready"`:                      {LogLine: "ready"},
+		"exec [pg_isready -q]":                  {Exec: []string{"pg_isready", "-q"}},
+		"http http://x/health (status 200-299)": {HTTP: &HTTPProbe{URL: "http://x/health"}},
+		`all of (tcp :5432, file /tmp/m.json, non-empty)`: {
+			All: []*ReadyCondition{{TCP: ":5432"}, {File: &FileProbe{Path: "/tmp/m.json", NonEmpty: true}}},
+		},
+		"http http://x/health (status 503)": {
+			HTTP: &HTTPProbe{URL: "http://x/health", StatusMin: 503, StatusMax: 503},
+		},
+		`log line matching "port=\\d+"`: {LogRegex: `port=\d+`},
+		"any of (file /tmp/ready, tcp :80)": {
+			Any: []*ReadyCondition{{File: &FileProbe{Path: "/tmp/ready"}}, {TCP: ":80"}},
+		},
 	}
 
 	for want, ready := range cases {
@@ -75,4 +126,10 @@ func TestReadyCondition_Describe(t *testing.T) {
 			t.Errorf("Describe = %q, ожидалось %q", got, want)
 		}
 	}
+
+	for _, ready := range []*ReadyCondition{nil, {}} {
+		if got := ready.Describe(); got != "none" {
+			t.Errorf("Describe пустого условия = %q", got)
+		}
+	}
 }
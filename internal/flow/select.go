@@ -3,11 +3,19 @@ package flow
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
-// ErrUnknownChain — в отборе назван несуществующей цепочки.
-var ErrUnknownChain = errors.New("unknown chain")
+var (
+	// ErrUnknownChain — в отборе назван несуществующей цепочки.
+	ErrUnknownChain = errors.New("unknown chain")
+	// ErrUnknownTag — в отборе назван тег, которого нет ни у одной цепочки.
+	ErrUnknownTag = errors.New("unknown tag")
+)
+
+// TagPrefix отличает тег от имени цепочки в отборе: @backend.
+const TagPrefix = "@"
 
 // Select оставляет во Flow только названные цепочки, затем убирает исключённые.
 //
@@ -28,6 +36,17 @@ func Select(f Flow, include, exclude []string) (Flow, error) {
 		known[chain.Name] = true
 	}
 
+	include, err := tagMembers(f, include)
+	if err != nil {
+		return Flow{}, err
+	}
+
+	if exclude, err = tagMembers(f, exclude); err != nil {
+		return Flow{}, err
+	}
+
+	include, exclude = matrixMembers(f, known, include), matrixMembers(f, known, exclude)
+
 	for _, name := range append(append([]string{}, include...), exclude...) {
 		if !known[name] {
 			return Flow{}, fmt.Errorf("%w %q, available: %s", ErrUnknownChain, name, strings.Join(names(f), ", "))
@@ -40,7 +59,10 @@ func Select(f Flow, include, exclude []string) (Flow, error) {
 	wanted := toSet(WithDependencies(f, include))
 	unwanted := toSet(exclude)
 
-	result := Flow{}
+	// before и onExit отбором не сужаются: они относятся к запуску, а не к
+	// цепочке. Ради этого их и не моделируют цепочкой с needs у остальных —
+	// такую цепочку -except убрал бы вместе с подготовкой.
+	result := Flow{Before: f.Before, OnExit: f.OnExit}
 
 	for _, chain := range f.Chains {
 		if len(wanted) > 0 && !wanted[chain.Name] {
@@ -61,6 +83,85 @@ func Select(f Flow, include, exclude []string) (Flow, error) {
 	return result, nil
 }
 
+// tagMembers заменяет каждый @тег именами цепочек с этим тегом.
+//
+// Теги раскрываются до WithDependencies, и зависимости подтягиваются как у
+// перечисленных по имени: `parallel @frontend` поднимет и db, без которой
+// фронтенд не работает, даже если у db тега frontend нет.
+func tagMembers(f Flow, selected []string) ([]string, error) {
+	out := make([]string, 0, len(selected))
+
+	for _, name := range selected {
+		tag, isTag := strings.CutPrefix(name, TagPrefix)
+		if !isTag {
+			out = append(out, name)
+
+			continue
+		}
+
+		found := false
+
+		for _, chain := range f.Chains {
+			if slices.Contains(chain.Tags, tag) {
+				out, found = append(out, chain.Name), true
+			}
+		}
+
+		if !found {
+			return nil, unknownTag(f, tag)
+		}
+	}
+
+	return out, nil
+}
+
+// unknownTag перечисляет теги, которые есть в конфигурации.
+func unknownTag(f Flow, tag string) error {
+	var tags []string
+	for _, chain := range f.Chains {
+		tags = append(tags, chain.Tags...)
+	}
+
+	if len(tags) == 0 {
+		return fmt.Errorf("%w %q: no chain has tags", ErrUnknownTag, tag)
+	}
+
+	slices.Sort(tags)
+	available := TagPrefix + strings.Join(slices.Compact(tags), ", "+TagPrefix)
+
+	return fmt.Errorf("%w %q, available: %s", ErrUnknownTag, tag, available)
+}
+
+// matrixMembers заменяет имя цепочки с матрицей именами всех её сочетаний:
+// `parallel test` выбирает каждый test[go=1.26,db=pg], и сочетания не надо
+// перечислять в кавычках. Точное имя сочетания по-прежнему выбирает только его.
+func matrixMembers(f Flow, known map[string]bool, selected []string) []string {
+	out := make([]string, 0, len(selected))
+
+	for _, name := range selected {
+		if known[name] {
+			out = append(out, name)
+
+			continue
+		}
+
+		found := false
+
+		for _, chain := range f.Chains {
+			if strings.HasPrefix(chain.Name, name+"[") {
+				out, found = append(out, chain.Name), true
+			}
+		}
+
+		// Неизвестное имя остаётся как есть — об ошибке скажет проверка.
+		if !found {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
+
 // names возвращает имена цепочек в порядке объявления — для сообщения об ошибке.
 func names(f Flow) []string {
 	out := make([]string, 0, len(f.Chains))
@@ -103,3 +103,88 @@ func TestSelect_EmptyResult(t *testing.T) {
 		t.Fatalf("ожидалась ErrNoChains, получено %v", err)
 	}
 }
+
+// TestSelect_MatrixBaseName: имя цепочки с матрицей выбирает все её
+// сочетания, а точное имя сочетания — только его.
+func TestSelect_MatrixBaseName(t *testing.T) {
+	f := sampleFlow()
+
+	for _, name := range []string{"test[db=pg]", "test[db=mysql]"} {
+		chain := &CommandChain{Name: name}
+		chain.Add(Command{Name: "unit", Cmd: "echo"})
+		f.AddChain(chain)
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{name: "все сочетания", include: []string{"test"}, want: []string{"test[db=pg]", "test[db=mysql]"}},
+		{name: "одно сочетание", include: []string{"test[db=mysql]"}, want: []string{"test[db=mysql]"}},
+		{name: "исключение", exclude: []string{"test"}, want: []string{"api", "ui", "worker"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(f, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("select: %v", err)
+			}
+
+			if strings.Join(got.Names(), ",") != strings.Join(tt.want, ",") {
+				t.Errorf("получено %v, ожидалось %v", got.Names(), tt.want)
+			}
+		})
+	}
+}
+
+// TestSelect_Tags: тег раскрывается в цепочки до подтягивания зависимостей —
+// `@frontend` поднимает и db, у которой такого тега нет.
+func TestSelect_Tags(t *testing.T) {
+	f := Flow{}
+
+	for i, spec := range []struct {
+		name  string
+		tags  []string
+		needs []string
+	}{
+		{name: "db", tags: []string{"infra"}},
+		{name: "api", tags: []string{"backend"}, needs: []string{"db"}},
+		{name: "web", tags: []string{"frontend"}, needs: []string{"db"}},
+		{name: "worker", tags: []string{"backend"}},
+	} {
+		chain := &CommandChain{Name: spec.name, ColorIdx: i, Tags: spec.tags, Needs: spec.needs}
+		chain.Add(Command{Name: "run", Cmd: "echo"})
+		f.AddChain(chain)
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{name: "тег с зависимостями", include: []string{"@frontend"}, want: []string{"db", "web"}},
+		{name: "несколько цепочек", include: []string{"@backend"}, want: []string{"db", "api", "worker"}},
+		{name: "тег и имя", include: []string{"@frontend", "worker"}, want: []string{"db", "web", "worker"}},
+		{name: "исключение тега", exclude: []string{"@backend"}, want: []string{"db", "web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(f, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("select: %v", err)
+			}
+
+			if strings.Join(got.Names(), ",") != strings.Join(tt.want, ",") {
+				t.Errorf("получено %v, ожидалось %v", got.Names(), tt.want)
+			}
+		})
+	}
+
+	_, err := Select(f, []string{"@backnd"}, nil)
+	if !errors.Is(err, ErrUnknownTag) || !strings.Contains(err.Error(), "@backend") {
+		t.Errorf("ожидалась ErrUnknownTag со списком тегов, получено %v", err)
+	}
+}
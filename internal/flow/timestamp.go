@@ -0,0 +1,76 @@
+package flow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TimestampMode — какое время ставить перед строкой вывода команды.
+//
+// Пустое значение означает «не задано»: решение остаётся за уровнем выше —
+// верхнеуровневым format.timestamp или флагом -timestamp.
+type TimestampMode string
+
+// Режимы метки времени.
+const (
+	// TimestampNone — без метки; явное значение, чтобы команда могла
+	// отключить метку, заданную для всех.
+	TimestampNone TimestampMode = "none"
+	// TimestampWall — время суток с миллисекундами: короче всего и хватает,
+	// чтобы сопоставить строки соседних цепочек.
+	TimestampWall TimestampMode = "wall"
+	// TimestampRFC3339 — полная дата со смещением зоны: для вывода, который
+	// потом сопоставляют с журналами других машин.
+	TimestampRFC3339 TimestampMode = "rfc3339"
+	// TimestampElapsed — время от старта цепочки: «через сколько после
+	// запуска» часто важнее, чем «во сколько».
+	TimestampElapsed TimestampMode = "elapsed"
+)
+
+//nolint:gochecknoglobals // неизменяемый список, массивом объявить нельзя
+var timestampModes = []TimestampMode{TimestampNone, TimestampWall, TimestampRFC3339, TimestampElapsed}
+
+// String возвращает режим в том виде, в каком его пишут в конфигурации.
+func (m TimestampMode) String() string {
+	if m == "" {
+		return string(TimestampNone)
+	}
+
+	return string(m)
+}
+
+// Enabled сообщает, ставится ли метка вообще.
+func (m TimestampMode) Enabled() bool {
+	return m != "" && m != TimestampNone
+}
+
+// Or возвращает m, если режим задан, и fallback иначе.
+func (m TimestampMode) Or(fallback TimestampMode) TimestampMode {
+	if m == "" {
+		return fallback
+	}
+
+	return m
+}
+
+// ParseTimestampMode разбирает значение format.timestamp и флага -timestamp.
+// Пустая строка — отсутствие значения, а не ошибка.
+func ParseTimestampMode(s string) (TimestampMode, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	mode := TimestampMode(strings.ToLower(s))
+	for _, known := range timestampModes {
+		if mode == known {
+			return mode, nil
+		}
+	}
+
+	names := make([]string, 0, len(timestampModes))
+	for _, m := range timestampModes {
+		names = append(names, string(m))
+	}
+
+	return "", fmt.Errorf("%w %q, allowed: %s", ErrUnknownTimestampMode, s, strings.Join(names, ", "))
+}
@@ -0,0 +1,44 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTimestampMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    TimestampMode
+		wantErr error
+	}{
+		{in: "", want: ""},
+		{in: "none", want: TimestampNone},
+		{in: "wall", want: TimestampWall},
+		{in: "RFC3339", want: TimestampRFC3339},
+		{in: "elapsed", want: TimestampElapsed},
+		{in: "unix", wantErr: ErrUnknownTimestampMode},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimestampMode(tt.in)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("%q: получено %q, %v; ожидалось %q, %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestTimestampMode_Or — пустой режим наследует, явный none — нет: иначе
+// команда не могла бы отключить метку, заданную для всех.
+func TestTimestampMode_Or(t *testing.T) {
+	if got := TimestampMode("").Or(TimestampWall); got != TimestampWall {
+		t.Errorf("пустой режим должен наследовать, получено %q", got)
+	}
+
+	if got := TimestampNone.Or(TimestampWall); got != TimestampNone || got.Enabled() {
+		t.Errorf("none должен перекрывать и выключать метку, получено %q", got)
+	}
+
+	if TimestampMode("").String() != "none" || !TimestampElapsed.Enabled() {
+		t.Error("неверные String или Enabled")
+	}
+}
@@ -0,0 +1,112 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWatchEmpty — секция watch не задаёт ни одного пути.
+	ErrWatchEmpty = errors.New("watch must define at least one path")
+	// ErrWatchPattern — шаблон пути не разбирается.
+	ErrWatchPattern = errors.New("invalid watch pattern")
+)
+
+// DefaultWatchDebounce — сколько ждать тишины после изменения, если срок не задан.
+//
+// Редактор сохраняет файл несколькими записями, а `git checkout` меняет сотни
+// файлов подряд: перезапуск на каждое событие положил бы сервер десять раз за
+// секунду.
+const DefaultWatchDebounce = 300 * time.Millisecond
+
+// Watch описывает, изменения каких файлов перезапускают команду.
+//
+// Как следить за файлами — дело раннера; домен знает только шаблоны.
+type Watch struct {
+	// Root — каталог, от которого отсчитываются шаблоны.
+	Root string
+	// Paths — шаблоны отслеживаемых файлов. `*` не переходит через `/`,
+	// `**` — любое число каталогов.
+	Paths []string
+	// Ignore — шаблоны, исключаемые из Paths.
+	Ignore []string
+	// Debounce — сколько ждать тишины перед перезапуском; ноль означает
+	// DefaultWatchDebounce.
+	Debounce time.Duration
+}
+
+// Validate проверяет, что шаблоны заданы и разбираются.
+//
+// Битый шаблон проверяется заранее, а не при первом изменении: иначе опечатка
+// в `[` тихо превратила бы слежение в пустое.
+func (w *Watch) Validate() error {
+	if w == nil {
+		return nil
+	}
+
+	if len(w.Paths) == 0 {
+		return ErrWatchEmpty
+	}
+
+	for _, pattern := range append(append([]string(nil), w.Paths...), w.Ignore...) {
+		if err := validatePattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	if w.Debounce < 0 {
+		return fmt.Errorf("%w: watch debounce is %s", ErrNegativeTimeout, w.Debounce)
+	}
+
+	return nil
+}
+
+// Delay возвращает срок ожидания тишины с учётом умолчания.
+func (w *Watch) Delay() time.Duration {
+	if w == nil || w.Debounce <= 0 {
+		return DefaultWatchDebounce
+	}
+
+	return w.Debounce
+}
+
+// Describe коротко перечисляет шаблоны — для предпросмотра.
+func (w *Watch) Describe() string {
+	if w == nil {
+		return "none"
+	}
+
+	desc := strings.Join(w.Paths, ", ")
+	if len(w.Ignore) > 0 {
+		desc += " (ignore " + strings.Join(w.Ignore, ", ") + ")"
+	}
+
+	return desc
+}
+
+// validatePattern проверяет шаблон по сегментам: `**` допустим только целым
+// сегментом, остальное — синтаксис path.Match.
+func validatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("%w: empty pattern", ErrWatchPattern)
+	}
+
+	for seg := range strings.SplitSeq(pattern, "/") {
+		if seg == "**" {
+			continue
+		}
+
+		if strings.Contains(seg, "**") {
+			return fmt.Errorf("%w %q: '**' must be a whole path segment", ErrWatchPattern, pattern)
+		}
+
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("%w %q: %w", ErrWatchPattern, pattern, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatch_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		watch   *Watch
+		wantErr error
+	}{
+		{name: "отсутствует вовсе", watch: nil},
+		{name: "шаблоны с **", watch: &Watch{Paths: []string{"**/*.go", "web/src/**"}, Ignore: []string{"vendor/**"}}},
+		{name: "без путей", watch: &Watch{Ignore: []string{"*.tmp"}}, wantErr: ErrWatchEmpty},
+		{name: "битый класс символов", watch: &Watch{Paths: []string{"src/[a-"}}, wantErr: ErrWatchPattern},
+		{name: "** внутри сегмента", watch: &Watch{Paths: []string{"src/**.go"}}, wantErr: ErrWatchPattern},
+		{name: "битый ignore", watch: &Watch{Paths: []string{"*"}, Ignore: []string{"["}}, wantErr: ErrWatchPattern},
+		{name: "пустой шаблон", watch: &Watch{Paths: []string{""}}, wantErr: ErrWatchPattern},
+		{
+			name:    "отрицательная задержка",
+			watch:   &Watch{Paths: []string{"*.go"}, Debounce: -time.Second},
+			wantErr: ErrNegativeTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.watch.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("неожиданная ошибка: %v", err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("получено %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatch_DelayAndDescribe(t *testing.T) {
+	var none *Watch
+	if none.Delay() != DefaultWatchDebounce || none.Describe() != "none" {
+		t.Errorf("nil: %s, %q", none.Delay(), none.Describe())
+	}
+
+	w := &Watch{Paths: []string{"**/*.go"}, Ignore: []string{"vendor/**"}, Debounce: time.Second}
+	if w.Delay() != time.Second {
+		t.Errorf("заданная задержка потеряна: %s", w.Delay())
+	}
+
+	if got := w.Describe(); got != "**/*.go (ignore vendor/**)" {
+		t.Errorf("описание = %q", got)
+	}
+}
+
+// TestCommand_ValidateWatch — битый watch делает негодной всю команду.
+func TestCommand_ValidateWatch(t *testing.T) {
+	cmd := Command{Cmd: "go", Watch: &Watch{}}
+	if err := cmd.Validate(); !errors.Is(err, ErrWatchEmpty) {
+		t.Fatalf("ожидалась ErrWatchEmpty, получено %v", err)
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -39,6 +39,31 @@ type chainExecutor struct {
 	// ExecuteParallel и читается в том числе слоем вывода — через observeLine.
 	ready atomic.Pointer[readySet]
 
+	// live управляет цепочками текущего запуска по одной: через него
+	// приходят остановка, запуск и перезапуск извне. nil вне запуска.
+	live atomic.Pointer[liveSet]
+
+	// watchPoll — период опроса файлов для команд с watch; ноль означает
+	// defaultWatchPoll.
+	watchPoll time.Duration
+
+	// events принимает события цепочек и перезапусков; nil — не нужны.
+	events EventSink
+
+	// chainDone вызывается по окончании каждого запуска цепочки; nil — не нужен.
+	chainDone func(name string)
+
+	// watching вызывается, когда ожидание готовности начало следить за
+	// строками цепочки; nil — не нужен. По нему тесты печатают строку не
+	// раньше, чем её ждут.
+	watching func(chainName string)
+
+	// before — команды, выполняемые по очереди до старта цепочек.
+	before []flow.Command
+
+	// onExit — команды, выполняемые после всех цепочек при любом исходе.
+	onExit []flow.Command
+
 	// results заполняется в конце ExecuteParallel и читается уже после её
 	// возврата, поэтому синхронизации не требует: запись всех горутин
 	// упорядочена относительно чтения вызовом group.Wait.
@@ -58,6 +83,22 @@ func withMaxParallel(n int) chainOption {
 	return func(c *chainExecutor) { c.maxParallel = n }
 }
 
+// withWatchPoll задаёт период опроса отслеживаемых файлов.
+func withWatchPoll(d time.Duration) chainOption {
+	return func(c *chainExecutor) { c.watchPoll = d }
+}
+
+// withEvents направляет события цепочек в приёмник.
+func withEvents(sink EventSink) chainOption {
+	return func(c *chainExecutor) { c.events = sink }
+}
+
+// withChainDone сообщает об окончании каждого запуска цепочки — так вывод,
+// накопленный за запуск, печатается сразу, а не в конце всего прогона.
+func withChainDone(fn func(name string)) chainOption {
+	return func(c *chainExecutor) { c.chainDone = fn }
+}
+
 // observeLine передаёт строку вывода наблюдателям готовности.
 //
 // Вызывается слоем вывода на каждой строке, поэтому обязан быть дешёвым:
@@ -68,6 +109,16 @@ func (c *chainExecutor) observeLine(chainName, line string) {
 	}
 }
 
+// startedAt возвращает начало текущего запуска цепочки — от него считается
+// метка времени elapsed. Нулевое время — запуска нет.
+func (c *chainExecutor) startedAt(chainName string) time.Time {
+	if live := c.live.Load(); live != nil {
+		return live.startedAt(chainName)
+	}
+
+	return time.Time{}
+}
+
 func newChainExecutor(
 	lgr ui.Logger, runner CommandRunner, stopAll stopAllFunc, opts ...chainOption,
 ) *chainExecutor {
@@ -100,11 +151,21 @@ func (c *chainExecutor) ExecuteParallel(ctx context.Context, chains []*flow.Comm
 		group, groupCtx = errgroup.WithContext(ctx)
 	}
 
+	emitEvent(c.events, Event{Kind: EventRunStarted})
+
+	if err := c.runBefore(ctx, chains); err != nil {
+		return c.abortRun(ctx, chains, err)
+	}
+
 	set := newReadySet(chains)
+	set.watching = c.watching
 	c.ready.Store(set)
 
 	defer c.ready.Store(nil)
 
+	live := newLiveSet(chains)
+	defer c.live.Store(nil)
+
 	// Слоты — буферизованный канал, а не errgroup.SetLimit. Разница
 	// принципиальна: SetLimit занимает слот ещё до входа в горутину, то есть
 	// до ожидания предшественника. При маленьком лимите потомок держал бы
@@ -126,35 +187,92 @@ func (c *chainExecutor) ExecuteParallel(ctx context.Context, chains []*flow.Comm
 	// проблеме за прогон. От errgroup берём другое: отмену groupCtx при первом
 	// отказе и корректное ожидание всех горутин.
 	//
-	// Слот на цепочку, а не общий срез с мьютексом: порядок ошибок обязан
+	// Исход на цепочку, а не общий срез ошибок: порядок ошибок обязан
 	// повторять порядок цепочек в конфигурации. Иначе он зависел бы от того,
-	// кто раньше упал, а от порядка зависит код возврата утилиты.
-	errs := make([]error, len(chains))
-	durations := make([]time.Duration, len(chains))
-	interrupted := make([]bool, len(chains))
+	// кто раньше упал, а от порядка зависит код возврата утилиты. Цепочки,
+	// добавленные при перечитывании конфигурации, встают в конец.
+	var (
+		mu       sync.Mutex
+		outcomes []*chainOutcome
+	)
 
-	skipped := make([]bool, len(chains))
+	spawn := func(chain *flow.CommandChain) {
+		out := &chainOutcome{name: chain.Name}
 
-	for i, chain := range chains {
-		group.Go(func() error {
-			startedAt := time.Now()
+		mu.Lock()
+		outcomes = append(outcomes, out)
+		mu.Unlock()
 
-			wasStopped, wasSkipped, err := c.runChain(groupCtx, set, slots, chain)
+		group.Go(func() error {
+			out.stopped, out.skipped, out.err = c.runChain(groupCtx, set, live, slots, chain.Name)
 
-			durations[i] = time.Since(startedAt)
-			errs[i] = err
-			interrupted[i] = wasStopped
-			skipped[i] = wasSkipped
+			// Время берётся без простоя: цепочка, припаркованная после
+			// работы, не должна выглядеть в сводке работавшей всё это время.
+			out.duration = live.busy(chain.Name)
+			out.commands = live.commands(chain.Name)
 
-			return err
+			return out.err
 		})
 	}
 
+	// Гейт добавленной цепочке заводится вместе с горутиной, а не раньше:
+	// при отказе добавления гейт существующей цепочки остался бы нетронутым.
+	live.spawn = func(chain *flow.CommandChain) {
+		set.addChain(chain)
+		spawn(chain)
+	}
+
+	// Управление открывается только теперь: добавить цепочку до того, как
+	// заведён spawn, было бы нечем.
+	c.live.Store(live)
+
+	for _, chain := range chains {
+		spawn(chain)
+	}
+
 	_ = group.Wait()
 
-	c.results = collectResults(chains, errs, durations, interrupted, skipped)
+	c.results = collectResults(outcomes)
+
+	errs := make([]error, 0, len(outcomes)+1)
+	for _, out := range outcomes {
+		errs = append(errs, out.err)
+	}
+
+	errs = append(errs, c.runOnExit(ctx, chains))
 
-	return joinRealErrors(errs)
+	err := joinRealErrors(errs)
+	emitEvent(c.events, Event{
+		Kind: EventRunFinished, Status: finalState(ctx.Err() != nil, err), Error: errorText(err),
+	})
+
+	return err
+}
+
+// chainOutcome — исход горутины одной цепочки. Каждый пишет только его
+// горутина, а читается он после group.Wait.
+type chainOutcome struct {
+	name     string
+	err      error
+	duration time.Duration
+	stopped  bool
+	skipped  bool
+	commands []CommandResult
+}
+
+// remove убирает цепочку из запуска.
+//
+// Гейты открываются успехом ДО остановки: иначе ждущие цепочку получили бы
+// отмену и остановились вместе с ней. Ждать её могут только те, кто сам
+// меняется вместе с конфигурацией, — новая версия от убранной цепочки
+// зависеть уже не может.
+func (c *chainExecutor) remove(live *liveSet, name string) error {
+	if set := c.ready.Load(); set != nil {
+		set.gatesOf(name).openAll(nil)
+		set.settleCommands(live.definition(name), nil)
+	}
+
+	return live.remove(name)
 }
 
 // runChain проводит цепочку через все три этапа: ожидание предшественников,
@@ -162,34 +280,89 @@ func (c *chainExecutor) ExecuteParallel(ctx context.Context, chains []*flow.Comm
 //
 // Порядок этапов — главное решение задачи. Ожидание идёт ДО взятия слота,
 // иначе цепочка занимала бы слот, пока ждёт того, кому этот слот нужен.
+//
+// Отработав, цепочка паркуется и может быть запущена снова по запросу извне.
+// Предшественники проверяются на каждом круге, но их гейты уже открыты, и
+// перезапуск одного сервиса не тянет за собой базу данных. Зато цепочка, чей
+// needs поменялся при перечитывании конфигурации, дождётся и нового
+// предшественника.
 func (c *chainExecutor) runChain(
-	ctx context.Context, set *readySet, slots chan struct{}, chain *flow.CommandChain,
+	ctx context.Context, set *readySet, live *liveSet, slots chan struct{}, name string,
 ) (stopped, skipped bool, err error) {
-	if depErr := c.awaitDependencies(ctx, set, chain); depErr != nil {
-		gateErr := fmt.Errorf("chain %q not started: %w", chain.Name, depErr)
-		set.gateOf(chain.Name).open(gateErr)
+	ctx, drop := live.enter(ctx, name)
+	defer drop()
+
+	for {
+		if depErr := c.awaitDependencies(ctx, set, live.definition(name)); depErr != nil {
+			gateErr := fmt.Errorf("chain %q not started: %w", name, depErr)
+
+			set.settleCommands(live.definition(name), gateErr)
+
+			if errors.Is(depErr, context.Canceled) {
+				set.gatesOf(name).openAll(gateErr)
+				live.leave(name, ChainStopped)
 
-		if errors.Is(depErr, context.Canceled) {
-			return true, false, depErr
+				return true, false, depErr
+			}
+
+			set.gatesOf(name).skip(gateErr)
+			live.leave(name, ChainSkipped)
+			emitEvent(c.events, Event{Kind: EventChainSkipped, Chain: name, Error: errorText(gateErr)})
+
+			return false, true, gateErr
 		}
 
-		return false, true, gateErr
+		stopped, err = c.runOnce(ctx, set, live, slots, name)
+
+		// Отказ в режиме fail-fast парковкой не прикрывается: он обязан
+		// вернуться из горутины немедленно, иначе errgroup не остановит соседей.
+		if err != nil && !errors.Is(err, context.Canceled) && !c.keepGoing {
+			live.leave(name, ChainFailed)
+
+			return stopped, false, err
+		}
+
+		if !live.park(ctx, name) {
+			return stopped, false, err
+		}
 	}
+}
+
+// runOnce выполняет цепочку один раз: берёт слот, запускает команды и пробу
+// готовности, по итогам закрывает гейт.
+//
+// Определение цепочки берётся уже со слотом на руках: конфигурация могла
+// смениться, пока цепочка ждала, и запускать надо последнюю её версию.
+func (c *chainExecutor) runOnce(
+	ctx context.Context, set *readySet, live *liveSet, slots chan struct{}, name string,
+) (stopped bool, err error) {
+	// При повторном запуске слот мог быть занят: пока его нет, цепочка ждёт.
+	live.mark(name, ChainWaiting)
 
 	if !acquire(ctx, slots) {
 		err := ctx.Err()
-		set.gateOf(chain.Name).open(err)
+		set.gatesOf(name).openAll(err)
+		set.settleCommands(live.definition(name), err)
+		live.mark(name, ChainStopped)
 
-		return true, false, err
+		return true, err
 	}
 
 	defer release(slots)
 
+	chain := live.definition(name)
+
+	runCtx, cancelRun := live.begin(ctx, chain.Name)
+	defer cancelRun()
+
+	set.gatesOf(chain.Name).started.open(nil)
+	emitEvent(c.events, Event{Kind: EventChainStarted, Chain: chain.Name})
+
 	// Проба готовности идёт параллельно самой цепочке и открывает гейт САМА,
 	// как только условие выполнено. Ждать здесь завершения цепочки нельзя:
 	// долгоживущий сервер не завершается никогда, и зависимые от него не
 	// дождались бы запуска вовсе — ровно та задача, ради которой всё затевалось.
-	readyCtx, cancelReady := context.WithCancel(ctx)
+	readyCtx, cancelReady := context.WithCancel(runCtx)
 	defer cancelReady()
 
 	readyDone := make(chan error, 1)
@@ -206,47 +379,94 @@ func (c *chainExecutor) runChain(
 
 		readyErr := set.awaitChain(readyCtx, chain)
 		if readyErr == nil {
-			set.gateOf(chain.Name).open(nil)
+			set.gatesOf(chain.Name).ready.open(nil)
+			emitEvent(c.events, Event{Kind: EventChainReady, Chain: chain.Name})
 		}
 
 		readyDone <- readyErr
 	}()
 
-	stopped, err = c.executeChain(ctx, chain)
+	var report []CommandResult
+
+	stopped, report, err = c.executeChain(runCtx, chain)
+
+	// Уборка — часть запуска: зависимые с completed ждут и её, а сгруппированный
+	// вывод цепочки печатается вместе с её выводом. Отказ уборки — отказ цепочки,
+	// иначе неснятый контейнер прошёл бы незамеченным.
+	//
+	// Перезапуск цепочки целиком — тоже конец запуска, и уборка идёт до нового:
+	// поднятое прежним запуском им же и снимается. Перезапуски одной команды
+	// (restart, watch, health) остаются внутри executeChain и сюда не доходят.
+	err = errors.Join(err, c.runTeardown(ctx, chain, chain.Finally))
+	live.record(chain.Name, report)
+
+	if c.chainDone != nil {
+		c.chainDone(chain.Name)
+	}
 
 	c.settleGate(set, chain, readyDone, err)
+	set.settleCommands(chain, set.gatesOf(chain.Name).ready.wait(context.WithoutCancel(ctx)))
+
+	state := finalState(stopped, err)
+	live.mark(chain.Name, state)
+
+	// Без условий готовности цепочка готова, когда успешно доработала.
+	// Остановленная тоже открывает гейт, но готовой её не назовёшь.
+	if state == ChainDone && !hasReadyConditions(chain) {
+		emitEvent(c.events, Event{Kind: EventChainReady, Chain: chain.Name})
+	}
 
-	return stopped, false, err
+	emitEvent(c.events, Event{Kind: EventChainFinished, Chain: chain.Name, Status: state, Error: errorText(err)})
+
+	return stopped, err
 }
 
-// settleGate закрывает гейт цепочки по итогам её работы — если проба готовности
-// не сделала этого раньше.
+// finalState переводит исход запуска в состояние цепочки.
+func finalState(stopped bool, err error) ChainState {
+	switch {
+	case err != nil:
+		return ChainFailed
+	case stopped:
+		return ChainStopped
+	default:
+		return ChainDone
+	}
+}
+
+// settleGate закрывает гейты цепочки по итогам её работы — если проба
+// готовности не сделала этого раньше.
 //
-// Правило зависит от того, заданы ли условия готовности. Если заданы — решает
-// проба. Если нет — цепочка готова, когда успешно завершилась: миграции и
-// сборки не слушают портов, и «дождаться» для них означает именно это.
+// Правило готовности зависит от того, заданы ли её условия. Если заданы —
+// решает проба. Если нет — цепочка готова, когда успешно завершилась: миграции
+// и сборки не слушают портов, и «дождаться» для них означает именно это.
+// Гейты завершения от условий готовности не зависят.
 //
 // Первый вызов open побеждает, поэтому успевшая раньше проба уже всё решила.
 func (c *chainExecutor) settleGate(
 	set *readySet, chain *flow.CommandChain, readyDone <-chan error, runErr error,
 ) {
-	g := set.gateOf(chain.Name)
+	gates := set.gatesOf(chain.Name)
+	gates.completed.open(nil)
 
-	// Отказ закрывает гейт немедленно, не дожидаясь пробы: ждать готовности
+	// Отказ закрывает гейты немедленно, не дожидаясь пробы: ждать готовности
 	// от упавшей команды бессмысленно, а зависимые тем временем стоят.
 	if runErr != nil {
-		g.open(fmt.Errorf("%w: chain %q", ErrDependencyFailed, chain.Name))
+		failed := fmt.Errorf("%w: chain %q", ErrDependencyFailed, chain.Name)
+		gates.ready.open(failed)
+		gates.succeeded.open(failed)
 
 		return
 	}
 
+	gates.succeeded.open(nil)
+
 	if !hasReadyConditions(chain) {
-		g.open(nil)
+		gates.ready.open(nil)
 
 		return
 	}
 
-	g.open(<-readyDone)
+	gates.ready.open(<-readyDone)
 }
 
 // hasReadyConditions сообщает, задано ли у цепочки хоть одно условие готовности.
@@ -260,7 +480,8 @@ func hasReadyConditions(chain *flow.CommandChain) bool {
 	return false
 }
 
-// awaitDependencies ждёт готовности всех предшественников цепочки.
+// awaitDependencies ждёт всех предшественников цепочки — каждого до своего
+// условия из needs.
 func (c *chainExecutor) awaitDependencies(
 	ctx context.Context, set *readySet, chain *flow.CommandChain,
 ) error {
@@ -268,11 +489,10 @@ func (c *chainExecutor) awaitDependencies(
 		return nil
 	}
 
-	c.lgr.Debug("Waiting for dependencies",
-		ui.F("chain", chain.Name), ui.F("needs", strings.Join(chain.Needs, ", ")))
+	c.lgr.Debug("Waiting for dependencies", ui.F("chain", chain.Name), ui.F("needs", chain.DescribeNeeds()))
 
 	for _, need := range chain.Needs {
-		if err := set.gateOf(need).wait(ctx); err != nil {
+		if err := set.gatesOf(need).of(chain.NeedOf(need)).wait(ctx); err != nil {
 			return err
 		}
 	}
@@ -307,23 +527,22 @@ func release(slots chan struct{}) {
 // Отмена контекста ошибкой не считается и здесь: цепочка, остановленная из-за
 // отказа соседней, в сводке должна выглядеть остановленной, а не упавшей —
 // иначе один отказ выглядит как пять.
-func collectResults(
-	chains []*flow.CommandChain, errs []error, durations []time.Duration, interrupted, skipped []bool,
-) []ChainResult {
-	results := make([]ChainResult, len(chains))
+func collectResults(outcomes []*chainOutcome) []ChainResult {
+	results := make([]ChainResult, len(outcomes))
 
-	for i, chain := range chains {
-		err := errs[i]
+	for i, out := range outcomes {
+		err := out.err
 		if errors.Is(err, context.Canceled) {
 			err = nil
 		}
 
 		results[i] = ChainResult{
-			Name:     chain.Name,
+			Name:     out.name,
 			Err:      err,
-			Duration: durations[i],
-			Stopped:  interrupted[i],
-			Skipped:  skipped[i],
+			Duration: out.duration,
+			Stopped:  out.stopped,
+			Skipped:  out.skipped,
+			Commands: out.commands,
 		}
 	}
 
@@ -376,7 +595,12 @@ func (c *chainExecutor) logSkipped(chain *flow.CommandChain, cmd flow.Command) {
 //
 // Отказ последовательной команды прекращает запуск следующих; уже запущенные
 // pipe-команды всё равно дожидаются.
-func (c *chainExecutor) executeChain(ctx context.Context, chain *flow.CommandChain) (stopped bool, err error) {
+//
+// Вместе с ошибкой возвращается итог каждой команды — для отчёта. Команда,
+// до которой очередь не дошла, числится пропущенной.
+func (c *chainExecutor) executeChain(
+	ctx context.Context, chain *flow.CommandChain,
+) (stopped bool, report []CommandResult, err error) {
 	var (
 		piped    errgroup.Group
 		firstErr error
@@ -388,6 +612,11 @@ func (c *chainExecutor) executeChain(ctx context.Context, chain *flow.CommandCha
 	// вернула бы только первую ошибку, и отказ второй pipe-команды потерялся бы.
 	pipedErrs := make([]error, len(commands))
 
+	report = make([]CommandResult, len(commands))
+	for i, cmd := range commands {
+		report[i] = CommandResult{Name: cmd.DisplayName(), Skipped: true}
+	}
+
 	for i, cmd := range commands {
 		if ctx.Err() != nil {
 			firstErr = ctx.Err()
@@ -403,10 +632,10 @@ func (c *chainExecutor) executeChain(ctx context.Context, chain *flow.CommandCha
 
 		if cmd.Pipe {
 			piped.Go(func() error {
-				err := c.runWithRestart(ctx, chain, cmd, func(runCtx context.Context) error {
-					return c.runner.ExecuteWithPipe(runCtx, chain, cmd)
-				})
+				start := time.Now()
+				err := c.runCommand(ctx, chain, cmd)
 				pipedErrs[i] = err
+				report[i] = commandOutcome(report[i].Name, time.Since(start), err)
 
 				return err
 			})
@@ -414,9 +643,10 @@ func (c *chainExecutor) executeChain(ctx context.Context, chain *flow.CommandCha
 			continue
 		}
 
-		err := c.runWithRestart(ctx, chain, cmd, func(runCtx context.Context) error {
-			return c.runner.Execute(runCtx, chain, cmd)
-		})
+		start := time.Now()
+		err := c.runCommand(ctx, chain, cmd)
+		report[i] = commandOutcome(report[i].Name, time.Since(start), err)
+
 		if err != nil {
 			firstErr = err
 
@@ -433,7 +663,68 @@ func (c *chainExecutor) executeChain(ctx context.Context, chain *flow.CommandCha
 	// Отмена — не отказ, но и не успех: цепочку остановил отказ соседней либо
 	// сигнал. Показать её в сводке как «ok» значило бы выдать убитое за
 	// доработавшее, а именно на сводку и смотрят, когда что-то пошло не так.
-	return ctx.Err() != nil, joined
+	return ctx.Err() != nil, report, joined
+}
+
+// runCommand выполняет команду и открывает её гейты для тех, кто ждёт
+// именно её: needs: [db.migrate].
+func (c *chainExecutor) runCommand(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	gates := commandGates(c.ready.Load(), chain, cmd)
+	gates.started.open(nil)
+
+	err := c.execCommand(ctx, chain, cmd)
+
+	// Правила те же, что у цепочки: остановка — не отказ, а без условия
+	// готовности команда готова, когда успешно отработала. Гейт готовности
+	// команды с условием открывает проба.
+	gates.completed.open(nil)
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		failed := fmt.Errorf("%w: command %q in chain %q", ErrDependencyFailed, cmd.DisplayName(), chain.Name)
+		gates.ready.open(failed)
+		gates.succeeded.open(failed)
+
+		return err
+	}
+
+	gates.succeeded.open(nil)
+
+	if cmd.Ready == nil {
+		gates.ready.open(nil)
+	}
+
+	return err
+}
+
+// execCommand выполняет команду с её политикой перезапуска.
+//
+// Переменные зависимостей подставляются здесь, при старте команды: раньше
+// их значений нет, а зависимость к этому моменту уже готова.
+func (c *chainExecutor) execCommand(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	expanded, err := withExports(c.ready.Load(), chain, cmd)
+	if err != nil {
+		return fmt.Errorf("chain %q, command %q: %w", chain.Name, cmd.DisplayName(), err)
+	}
+
+	execute := c.runner.Execute
+	if cmd.Pipe {
+		execute = c.runner.ExecuteWithPipe
+	}
+
+	return c.runWithRestart(ctx, chain, expanded, func(runCtx context.Context) error {
+		return execute(runCtx, chain, expanded)
+	})
+}
+
+// commandOutcome сводит исход запуска команды. Отмена — не отказ, а остановка,
+// как и у цепочки целиком.
+func commandOutcome(name string, d time.Duration, err error) CommandResult {
+	res := CommandResult{Name: name, Duration: d, Err: err}
+	if errors.Is(err, context.Canceled) {
+		res.Err, res.Stopped = nil, true
+	}
+
+	return res
 }
 
 // Границы задержки между перезапусками.
@@ -459,13 +750,20 @@ func (c *chainExecutor) runWithRestart(
 	cmd flow.Command,
 	run func(context.Context) error,
 ) error {
-	delay := cmd.RestartDelay
-	if delay <= 0 {
-		delay = defaultRestartDelay
+	initialDelay := cmd.RestartDelay
+	if initialDelay <= 0 {
+		initialDelay = defaultRestartDelay
 	}
 
+	delay := initialDelay
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+
+	changes := c.watchFiles(watchCtx, cmd)
+
 	for attempt := 1; ; attempt++ {
-		err := run(ctx)
+		changed, err := runWatched(ctx, changes, c.withHealth(chain, cmd, run))
 
 		// Отмена проверяется раньше политики: после Ctrl+C перезапускать нечего
 		// и незачем. Иначе команда поднималась бы заново быстрее, чем её
@@ -474,6 +772,24 @@ func (c *chainExecutor) runWithRestart(
 			return err
 		}
 
+		// Перезапуск по изменению файлов — не отказ: ни политика, ни счётчик
+		// попыток, ни растущая задержка к нему не относятся. Иначе десятое
+		// сохранение файла ждало бы полминуты или не перезапускало вовсе.
+		if changed != "" {
+			c.lgr.Info("Files changed, restarting command",
+				ui.F("chain", chain.GetChainName()),
+				ui.F("command", cmd.DisplayName()),
+				ui.F("file", changed))
+			emitEvent(c.events, Event{
+				Kind: EventCommandRestarted, Chain: chain.GetChainName(), Command: cmd.DisplayName(),
+				Reason: RestartReasonWatch, File: changed,
+			})
+
+			attempt, delay = 0, initialDelay
+
+			continue
+		}
+
 		if !cmd.Restart.ShouldRestart(err) {
 			return err
 		}
@@ -487,6 +803,10 @@ func (c *chainExecutor) runWithRestart(
 			ui.F("command", cmd.DisplayName()),
 			ui.F("attempt", attempt+1),
 			ui.F("delay", delay.String()))
+		emitEvent(c.events, Event{
+			Kind: EventCommandRestarted, Chain: chain.GetChainName(), Command: cmd.DisplayName(),
+			Reason: RestartReasonPolicy, Attempt: attempt + 1, Delay: delay, Error: errorText(err),
+		})
 
 		if !sleepOrCancel(ctx, delay) {
 			return err
@@ -496,6 +816,67 @@ func (c *chainExecutor) runWithRestart(
 	}
 }
 
+// watchFiles запускает слежение за файлами команды и возвращает канал
+// изменений; nil, если команда ни за чем не следит.
+func (c *chainExecutor) watchFiles(ctx context.Context, cmd flow.Command) <-chan string {
+	if cmd.Watch == nil {
+		return nil
+	}
+
+	changes := make(chan string, 1)
+	w := newFileWatcher(cmd.Watch, c.watchPoll)
+
+	go w.run(ctx, w.snapshot(), changes)
+
+	return changes
+}
+
+// runWatched выполняет один запуск команды и останавливает его, если
+// изменились отслеживаемые файлы. changed — изменившийся файл, если запуск
+// остановлен из-за него.
+//
+// Остановка идёт отменой контекста запуска, то есть той же лестницей
+// stopCommand, что и по Ctrl+C: сигнал группе, затем убийство. Отдельного пути
+// для слежения нет, и процессу не нужно уметь что-то ещё.
+func runWatched(
+	ctx context.Context, changes <-chan string, run func(context.Context) error,
+) (changed string, err error) {
+	if changes == nil {
+		return "", run(ctx)
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	relayed := make(chan string, 1)
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		select {
+		case p := <-changes:
+			relayed <- p
+			cancel(errFilesChanged)
+		case <-runCtx.Done():
+		}
+	}()
+
+	err = run(runCtx)
+
+	// Ретранслятор дожидается до конца: изменение, пойманное ровно в момент
+	// выхода команды, иначе потерялось бы вместе с перезапуском.
+	cancel(nil)
+	<-finished
+
+	select {
+	case p := <-relayed:
+		return p, err
+	default:
+		return "", err
+	}
+}
+
 // sleepOrCancel ждёт указанное время и сообщает, дождались ли: false означает
 // отмену контекста. Голый сон здесь недопустим — он сделал бы Ctrl+C
 // неотзывчивым ровно на величину задержки.
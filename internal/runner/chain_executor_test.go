@@ -2,6 +2,7 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -231,3 +232,31 @@ func TestChainExecutor_WaitsForAllPiped(t *testing.T) {
 		t.Fatalf("ExecuteParallel returned error: %v", err)
 	}
 }
+
+func TestChainExecutor_RecordsCommandResults(t *testing.T) {
+	failure := &ExitError{Chain: "c1", Command: "bad", Code: 2, Stderr: "boom\n"}
+	exec := newChainExecutor(ui.NewDiscardLogger(), &failingRunner{byName: map[string]error{"bad": failure}}, nil)
+
+	chain := flow.CommandChain{Name: "c1"}
+	chain.Add(flow.Command{Name: "off", Cmd: "echo", Disable: true})
+	chain.Add(flow.Command{Name: "good", Cmd: "echo"})
+	chain.Add(flow.Command{Name: "bad", Cmd: "echo"})
+	chain.Add(flow.Command{Name: "after", Cmd: "echo"})
+
+	if err := exec.ExecuteParallel(t.Context(), []*flow.CommandChain{&chain}); err == nil {
+		t.Fatal("ожидался отказ цепочки")
+	}
+
+	got := exec.results[0].Commands
+	if len(got) != 4 {
+		t.Fatalf("ожидались итоги всех четырёх команд, получено %+v", got)
+	}
+
+	if !got[0].Skipped || got[1].Err != nil || got[1].Skipped || !got[3].Skipped {
+		t.Fatalf("отключённая и не дошедшая до запуска команды — пропущенные, остальные — запущенные: %+v", got)
+	}
+
+	if !errors.Is(got[2].Err, failure) {
+		t.Fatalf("итог упавшей команды должен нести её ошибку, получено %v", got[2].Err)
+	}
+}
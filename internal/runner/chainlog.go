@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// logTimeLayout — формат времени в начале строки журнала: с миллисекундами,
+// иначе строки одной секунды не упорядочить при чтении глазами.
+const logTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// chainLogs держит открытые файлы журналов цепочек.
+//
+// Файлы ключуются путём, а не именем цепочки: перечитанная конфигурация может
+// перенаправить цепочку в другой файл, и старый тогда просто перестаёт
+// пополняться. Открываются лениво, по первой строке: цепочка, которая ничего
+// не напечатала, не оставляет пустых файлов.
+type chainLogs struct {
+	lgr ui.Logger
+
+	mu    sync.Mutex
+	files map[string]*logWriter
+}
+
+func newChainLogs(lgr ui.Logger) *chainLogs {
+	return &chainLogs{lgr: lgr, files: make(map[string]*logWriter)}
+}
+
+// write дописывает строку вывода в журнал цепочки, если он задан.
+func (l *chainLogs) write(chain *flow.CommandChain, line string) {
+	if chain == nil || chain.Log == nil {
+		return
+	}
+
+	l.writerOf(chain.Log.Path).writeLine(*chain.Log, line)
+}
+
+func (l *chainLogs) writerOf(path string) *logWriter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.files[path]
+	if !ok {
+		w = &logWriter{lgr: l.lgr}
+		l.files[path] = w
+	}
+
+	return w
+}
+
+// closeAll закрывает все файлы. Запись после закрытия откроет файл снова:
+// журнал дополняется, а не теряет строки опоздавших.
+func (l *chainLogs) closeAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, w := range l.files {
+		w.close()
+	}
+}
+
+// logWriter пишет журнал одной цепочки и ротирует его по размеру.
+//
+// Отказ записи запуск не останавливает: журнал — копия вывода, и из-за
+// заполненного диска сервисы работать не перестают. О первом отказе
+// говорится предупреждением, дальше журнал молчит.
+//
+// Параметры приходят с каждой строкой, а не фиксируются при открытии:
+// перечитанная конфигурация может поменять предел ротации, не меняя пути.
+type logWriter struct {
+	spec flow.LogFile
+	lgr  ui.Logger
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	failed bool
+}
+
+func (w *logWriter) writeLine(spec flow.LogFile, line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.failed {
+		return
+	}
+
+	w.spec = spec
+
+	if err := w.writeLocked(line); err != nil {
+		w.failed = true
+
+		w.lgr.Warn("Chain log is not writable, lines are dropped",
+			ui.F("path", w.spec.Path), ui.F("error", err.Error()))
+	}
+}
+
+func (w *logWriter) writeLocked(line string) error {
+	if w.f == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, 0, len(logTimeLayout)+len(line)+2) //nolint:mnd // пробел и перевод строки
+	if w.spec.Timestamps {
+		buf = time.Now().AppendFormat(buf, logTimeLayout)
+		buf = append(buf, ' ')
+	}
+
+	buf = append(buf, line...)
+	buf = append(buf, '\n')
+
+	// Ротация до записи, а не после: строка не разрывается между файлами, а
+	// файл превышает предел не больше чем на одну строку.
+	if w.spec.MaxSize > 0 && w.size > 0 && w.size+int64(len(buf)) > w.spec.MaxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.f.Write(buf)
+	w.size += int64(n)
+
+	return err
+}
+
+// open открывает файл на дописывание: журнал прошлого запуска — тоже
+// история, и затирать его при каждом старте значило бы терять её.
+func (w *logWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.spec.Path), 0o750); err != nil {
+		return fmt.Errorf("log: %w", err)
+	}
+
+	//nolint:gosec // путь к журналу задаёт сам пользователь в конфигурации
+	f, err := os.OpenFile(w.spec.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("log: %w", err)
+	}
+
+	w.f, w.size = f, info.Size()
+
+	return nil
+}
+
+// rotate сдвигает прежние файлы на номер вверх — worker.log становится
+// worker.log.1, worker.log.1 — worker.log.2 — и открывает пустой. Файл
+// сверх предела хранения удаляется.
+func (w *logWriter) rotate() error {
+	w.closeLocked()
+
+	keep := w.spec.Keep()
+	_ = os.Remove(rotatedName(w.spec.Path, keep))
+
+	for i := keep - 1; i >= 1; i-- {
+		// Отсутствие промежуточного файла — норма первых ротаций.
+		_ = os.Rename(rotatedName(w.spec.Path, i), rotatedName(w.spec.Path, i+1))
+	}
+
+	if err := os.Rename(w.spec.Path, rotatedName(w.spec.Path, 1)); err != nil {
+		return fmt.Errorf("log: %w", err)
+	}
+
+	return w.open()
+}
+
+func (w *logWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closeLocked()
+}
+
+func (w *logWriter) closeLocked() {
+	if w.f != nil {
+		_ = w.f.Close()
+		w.f = nil
+	}
+}
+
+func rotatedName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
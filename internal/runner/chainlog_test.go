@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("чтение %s: %v", path, err)
+	}
+
+	return string(data)
+}
+
+// TestChainLogs_AppendsAcrossRuns — журнал прошлого запуска дополняется, а
+// каталог журнала создаётся сам.
+func TestChainLogs_AppendsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "api.log")
+	chain := &flow.CommandChain{Name: "api", Log: &flow.LogFile{Path: path}}
+
+	for _, line := range []string{"first", "second"} {
+		logs := newChainLogs(ui.NewDiscardLogger())
+		logs.write(chain, line)
+		logs.closeAll()
+	}
+
+	if got := readLog(t, path); got != "first\nsecond\n" {
+		t.Fatalf("ожидались строки обоих запусков, получено %q", got)
+	}
+}
+
+// TestChainLogs_Rotates — по пределу размера файл уходит в .1, прежний .1 —
+// в .2, а файлы сверх предела хранения удаляются.
+func TestChainLogs_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker.log")
+	chain := &flow.CommandChain{Name: "worker", Log: &flow.LogFile{Path: path, MaxSize: 8, MaxFiles: 2}}
+
+	logs := newChainLogs(ui.NewDiscardLogger())
+	for _, line := range []string{"line-1", "line-2", "line-3", "line-4"} {
+		logs.write(chain, line)
+	}
+
+	logs.closeAll()
+
+	want := map[string]string{path: "line-4\n", path + ".1": "line-3\n", path + ".2": "line-2\n"}
+	for file, content := range want {
+		if got := readLog(t, file); got != content {
+			t.Errorf("%s: получено %q, ожидалось %q", filepath.Base(file), got, content)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("файл сверх предела хранения должен быть удалён: %v", err)
+	}
+}
+
+func TestChainLogs_Timestamps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.log")
+	chain := &flow.CommandChain{Name: "api", Log: &flow.LogFile{Path: path, Timestamps: true}}
+
+	logs := newChainLogs(ui.NewDiscardLogger())
+	logs.write(chain, "ready")
+	logs.closeAll()
+
+	stamped := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}(Z|[+-]\d\d:\d\d) ready\n$`)
+	if got := readLog(t, path); !stamped.MatchString(got) {
+		t.Fatalf("строка без метки времени: %q", got)
+	}
+}
+
+// TestChainLogs_WriteFailure — недоступный журнал не мешает запуску и не
+// засыпает вывод предупреждениями на каждой строке.
+func TestChainLogs_WriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	chain := &flow.CommandChain{Name: "api", Log: &flow.LogFile{Path: filepath.Join(blocker, "api.log")}}
+
+	var buf strings.Builder
+
+	logs := newChainLogs(ui.NewLogger(&buf, ui.WithOutputMode(ui.OutputJSON)))
+	logs.write(chain, "one")
+	logs.write(chain, "two")
+	logs.closeAll()
+
+	if n := strings.Count(buf.String(), "Chain log is not writable"); n != 1 {
+		t.Fatalf("ожидалось одно предупреждение, получено %d:\n%s", n, buf.String())
+	}
+}
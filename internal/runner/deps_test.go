@@ -4,6 +4,11 @@ import (
 	"context"
 	"errors"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -290,3 +295,240 @@ func TestExecuteParallel_CancelDuringWait(t *testing.T) {
 		t.Fatal("отмена во время ожидания готовности не сработала")
 	}
 }
+
+// TestProbeHTTP — открытый порт ещё не готовность: проба ждёт нужного кода и
+// тела, а заголовки доходят до сервиса.
+func TestProbeHTTP(t *testing.T) {
+	var warm atomic.Bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-Probe") != "parallel":
+			w.WriteHeader(http.StatusForbidden)
+		case !warm.Load():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"status":"up"}`))
+		}
+	}))
+	defer srv.Close()
+
+	probe := &flow.HTTPProbe{
+		URL: srv.URL, Headers: map[string]string{"X-Probe": "parallel"}, BodyRegex: `"status":\s*"up"`,
+	}
+
+	if probeHTTP(t.Context(), probe) {
+		t.Fatal("503 принят за готовность")
+	}
+
+	warm.Store(true)
+
+	if !probeHTTP(t.Context(), probe) {
+		t.Fatal("прогретый сервис не признан готовым")
+	}
+
+	if probeHTTP(t.Context(), &flow.HTTPProbe{URL: srv.URL, Headers: probe.Headers, Body: "down"}) {
+		t.Fatal("тело без нужной подстроки принято")
+	}
+
+	if probeHTTP(t.Context(), &flow.HTTPProbe{URL: srv.URL}) {
+		t.Fatal("403 без заголовка принят за готовность")
+	}
+
+	forbidden := &flow.HTTPProbe{URL: srv.URL, StatusMin: 400, StatusMax: 499}
+	if !probeHTTP(t.Context(), forbidden) {
+		t.Fatal("заданный диапазон кодов не учтён")
+	}
+}
+
+// TestReadyCheck_Composite — в all строка журнала, увиденная раньше файла,
+// запоминается; any выполняется по первому сигналу.
+func TestReadyCheck_Composite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	set := newReadySet(nil)
+
+	all := set.checkOf("web", &flow.ReadyCondition{All: []*flow.ReadyCondition{
+		{LogLine: "built in"},
+		{File: &flow.FileProbe{Path: path, NonEmpty: true}},
+	}})
+	anyOf := set.checkOf("web", &flow.ReadyCondition{Any: []*flow.ReadyCondition{
+		{File: &flow.FileProbe{Path: path}},
+		{LogLine: "built in"},
+	}})
+
+	set.observeLine("web", "vite: built in 812ms")
+
+	if all.holds(t.Context()) {
+		t.Fatal("all выполнено без файла")
+	}
+
+	if !anyOf.holds(t.Context()) {
+		t.Fatal("any не выполнено по строке журнала")
+	}
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if all.holds(t.Context()) {
+		t.Fatal("пустой файл принят, хотя требовался непустой")
+	}
+
+	if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if !all.holds(t.Context()) {
+		t.Fatal("all не выполнено: строка журнала забыта, пока ждали файл")
+	}
+
+	set.release("web", all)
+	set.release("web", anyOf)
+
+	if n := len(set.matchers["web"]); n != 0 {
+		t.Errorf("после release осталось наблюдателей: %d", n)
+	}
+}
+
+// TestAwaitOne_ReleasesMatchers — ожидание снимает своих наблюдателей при
+// любом исходе: иначе они копились бы с каждым перезапуском цепочки.
+func TestAwaitOne_ReleasesMatchers(t *testing.T) {
+	set := newReadySet(nil)
+
+	// Файл уже есть: any выполняется сразу, но наблюдатели строк к этому
+	// моменту зарегистрированы.
+	path := filepath.Join(t.TempDir(), "ready")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ready := &flow.ReadyCondition{Any: []*flow.ReadyCondition{
+		{LogLine: "listening"},
+		{LogRegex: `port=(?P<port>\d+)`},
+		{File: &flow.FileProbe{Path: path}},
+	}}
+
+	if err := set.awaitOne(t.Context(), "web", flow.Command{Ready: ready}); err != nil {
+		t.Fatalf("awaitOne: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if err := set.awaitOne(ctx, "web", flow.Command{Ready: &flow.ReadyCondition{LogLine: "never"}}); err == nil {
+		t.Fatal("отменённое ожидание завершилось успехом")
+	}
+
+	if n := len(set.matchers["web"]); n != 0 {
+		t.Errorf("после ожидания осталось наблюдателей: %d", n)
+	}
+}
+
+// exportingRunner печатает «web» строку с портом и запоминает аргументы и
+// окружение остальных команд — в них должны оказаться переменные зависимостей.
+type exportingRunner struct {
+	exec *chainExecutor
+
+	// watched закрывается, когда готовность web начала следить за строками.
+	watched chan struct{}
+
+	mu   sync.Mutex
+	args map[string][]string
+}
+
+func (r *exportingRunner) Execute(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	if chain.Name == "web" {
+		// Наблюдатель регистрируется параллельно с запуском: строка,
+		// напечатанная раньше, прошла бы мимо него.
+		select {
+		case <-r.watched:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		r.exec.observeLine("web", "  ➜  Local: http://localhost:43127/ (token abc)")
+
+		return sleepOrCancelErr(ctx, 100*time.Millisecond)
+	}
+
+	r.mu.Lock()
+	r.args[chain.Name] = slices.Concat(cmd.Args, cmd.Env)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// newExportingExecutor связывает исполнитель с exportingRunner: раннер
+// печатает строку web, только когда её уже ждут.
+func newExportingExecutor() (*chainExecutor, *exportingRunner) {
+	runner := &exportingRunner{watched: make(chan struct{}), args: map[string][]string{}}
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil)
+	runner.exec = exec
+
+	var once sync.Once
+
+	exec.watching = func(chainName string) {
+		if chainName == "web" {
+			once.Do(func() { close(runner.watched) })
+		}
+	}
+
+	return exec, runner
+}
+
+func (r *exportingRunner) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	return r.Execute(ctx, chain, cmd)
+}
+
+func sleepOrCancelErr(ctx context.Context, d time.Duration) error {
+	if !sleepOrCancel(ctx, d) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// TestExecuteParallel_ExportsToDependents — именованные группы logLine
+// доходят до зависимой цепочки, а в её условии готовности подставляются тоже.
+func TestExecuteParallel_ExportsToDependents(t *testing.T) {
+	exec, runner := newExportingExecutor()
+
+	web := depChain("web", nil, &flow.ReadyCondition{
+		LogRegex: `localhost:(?P<port>\d+)/ \(token (?P<token>\w+)\)`, Timeout: 5 * time.Second,
+	})
+
+	e2e := &flow.CommandChain{Name: "e2e", Needs: []string{"web"}}
+	e2e.Add(flow.Command{Name: "run", Cmd: "npx", Args: []string{"--base=http://localhost:${web.port}", "$${web.port}"},
+		Env: []string{"TOKEN=${web.token}"}})
+
+	if err := exec.ExecuteParallel(t.Context(), []*flow.CommandChain{web, e2e}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	want := []string{"--base=http://localhost:43127", "${web.port}", "TOKEN=abc"}
+	if got := runner.args["e2e"]; !slices.Equal(got, want) {
+		t.Errorf("аргументы = %q, ожидалось %q", got, want)
+	}
+}
+
+// TestExecuteParallel_UnresolvedExport — ссылка, которой так и не дали
+// значения, роняет команду, а не подставляет пустую строку.
+func TestExecuteParallel_UnresolvedExport(t *testing.T) {
+	exec, runner := newExportingExecutor()
+
+	// db готова без условия и ничего не выставляет.
+	db := &flow.CommandChain{Name: "db"}
+	db.Add(flow.Command{Name: "up", Cmd: "up"})
+
+	api := &flow.CommandChain{Name: "api", Needs: []string{"db"}}
+	api.Add(flow.Command{Name: "serve", Cmd: "serve", Args: []string{"${db.port}"}})
+
+	err := exec.ExecuteParallel(t.Context(), []*flow.CommandChain{db, api})
+	if !errors.Is(err, flow.ErrUnresolvedExport) {
+		t.Fatalf("ожидалась ErrUnresolvedExport, получено %v", err)
+	}
+
+	if _, ran := runner.args["api"]; ran {
+		t.Error("команда запущена с неразрешённой переменной")
+	}
+}
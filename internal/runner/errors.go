@@ -10,6 +10,7 @@ var (
 	ErrCommandExecution = errors.New("command execution failed")
 	ErrPipeCreation     = errors.New("pipe creation failed")
 	ErrCommandTimeout   = errors.New("command timed out")
+	ErrCommandUnhealthy = errors.New("command unhealthy")
 )
 
 // minExitCode и maxExitCode — диапазон кодов, которые имеет смысл передавать
@@ -36,6 +37,9 @@ type ExitError struct {
 	Chain   string
 	Command string
 	Code    int
+	// Stderr — хвост stderr команды для отчёта. В текст ошибки не входит:
+	// сводке и журналу нужна причина в одну строку, а не вывод целиком.
+	Stderr string
 }
 
 func (e *ExitError) Error() string {
@@ -61,6 +65,8 @@ type TimeoutError struct {
 	Chain   string
 	Command string
 	Limit   time.Duration
+	// Stderr — хвост stderr команды к моменту снятия, как у ExitError.
+	Stderr string
 }
 
 func (e *TimeoutError) Error() string {
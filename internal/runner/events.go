@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"os/exec"
+	"time"
+)
+
+// EventKind — вид события запуска.
+//
+// Строка, а не число: значение уходит наружу как есть, и потребитель
+// потока событий сравнивает его с тем, что прочитал в документации.
+type EventKind string
+
+// Виды событий.
+const (
+	// EventRunStarted и EventRunFinished обрамляют весь запуск.
+	EventRunStarted  EventKind = "run.started"
+	EventRunFinished EventKind = "run.finished"
+
+	// EventChainStarted — очередной запуск цепочки: первый, перезапуск или
+	// запуск по запросу.
+	EventChainStarted EventKind = "chain.started"
+	// EventChainReady — гейт цепочки открылся успехом: зависимые могут стартовать.
+	EventChainReady EventKind = "chain.ready"
+	// EventChainFinished — запуск цепочки закончился; итог в Status.
+	EventChainFinished EventKind = "chain.finished"
+	// EventChainSkipped — цепочка не начиналась: не выполнилось условие
+	// предшественника.
+	EventChainSkipped EventKind = "chain.skipped"
+
+	// EventCommandStarted — процесс команды запущен; PID известен.
+	EventCommandStarted EventKind = "command.started"
+	// EventCommandRestarted — команда будет запущена снова: по политике
+	// перезапуска либо из-за изменения файлов.
+	EventCommandRestarted EventKind = "command.restarted"
+	// EventCommandExited — процесс команды завершился; код в ExitCode.
+	EventCommandExited EventKind = "command.exited"
+	// EventCommandTimeout — команда не уложилась в свой предел и снимается.
+	EventCommandTimeout EventKind = "command.timeout"
+	// EventCommandHealthy — первая удачная проба здоровья запуска команды:
+	// с этого момента отказы проб считаются.
+	EventCommandHealthy EventKind = "command.healthy"
+	// EventCommandUnhealthy — отказов проб подряд набрался порог, и команда
+	// снимается; число отказов в Failures.
+	EventCommandUnhealthy EventKind = "command.unhealthy"
+
+	// EventSignalSent — группе процессов команды отправлен сигнал завершения.
+	EventSignalSent EventKind = "signal.sent"
+	// EventForceKilled — группа не завершилась за отведённое время и убита.
+	EventForceKilled EventKind = "signal.killed"
+)
+
+// Причины перезапуска команды.
+const (
+	RestartReasonPolicy = "policy"
+	RestartReasonWatch  = "watch"
+)
+
+// Event — один факт жизненного цикла запуска для машинного потребителя.
+//
+// Поля, не относящиеся к виду события, остаются нулевыми. Формат передачи
+// решает вызывающий слой: runner знает, что произошло, но не как это
+// записать.
+type Event struct {
+	Time    time.Time
+	Kind    EventKind
+	Chain   string
+	Command string
+	PID     int
+	// ExitCode — код выхода процесса; -1, если процесс убит сигналом. nil —
+	// у события кода нет.
+	ExitCode *int
+	// Attempt — номер предстоящего запуска команды при перезапуске.
+	Attempt int
+	// Delay — пауза перед перезапуском.
+	Delay time.Duration
+	// Reason — причина перезапуска: RestartReasonPolicy или RestartReasonWatch.
+	Reason string
+	// File — изменившийся файл при перезапуске по слежению.
+	File string
+	// Failures — число отказов проб здоровья подряд.
+	Failures int
+	// Signal — отправленный сигнал.
+	Signal string
+	// Status — итог цепочки или запуска: значения ChainState.
+	Status ChainState
+	// Error — текст ошибки, если она была.
+	Error string
+}
+
+// EventSink принимает события запуска.
+//
+// Emit вызывается из многих горутин одновременно и не должен блокироваться
+// надолго: он стоит на пути запуска и остановки процессов.
+type EventSink interface {
+	Emit(ev Event)
+}
+
+// emitEvent отдаёт событие приёмнику, если он задан, проставив время.
+func emitEvent(sink EventSink, ev Event) {
+	if sink == nil {
+		return
+	}
+
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	sink.Emit(ev)
+}
+
+// errorText возвращает текст ошибки либо пустую строку.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
+// exitCodeOf достаёт код выхода завершившегося процесса; nil, если процесс
+// так и не был дождан.
+func exitCodeOf(cmd *exec.Cmd) *int {
+	if cmd.ProcessState == nil {
+		return nil
+	}
+
+	code := cmd.ProcessState.ExitCode()
+
+	return &code
+}
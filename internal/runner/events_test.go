@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// eventRecorder запоминает события в порядке прихода.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) Emit(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, ev)
+}
+
+// kinds возвращает виды событий; с непустым chain — только этой цепочки.
+func (r *eventRecorder) kinds(chain string) []EventKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []EventKind
+
+	for _, ev := range r.events {
+		if chain == "" || ev.Chain == chain {
+			out = append(out, ev.Kind)
+		}
+	}
+
+	return out
+}
+
+func (r *eventRecorder) find(kind EventKind) (Event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, ev := range r.events {
+		if ev.Kind == kind {
+			return ev, true
+		}
+	}
+
+	return Event{}, false
+}
+
+func TestEvents_ChainLifecycle(t *testing.T) {
+	requireIntegration(t)
+
+	rec := &eventRecorder{}
+	out := ui.NewDiscardOutput()
+	mgr := NewManager(out.Logger(), out.Formatter(), WithTimeouts(testTimeouts), WithEvents(rec))
+
+	name, args := sleepCmdNameArgs(0)
+	chain := &flow.CommandChain{Name: "job"}
+	chain.Add(flow.Command{Name: "nap", Cmd: name, Args: args})
+
+	if err := mgr.ExecuteParallel(t.Context(), []*flow.CommandChain{chain}); err != nil {
+		t.Fatalf("ExecuteParallel: %v", err)
+	}
+
+	want := []EventKind{
+		EventRunStarted, EventChainStarted, EventCommandStarted, EventCommandExited,
+		EventChainReady, EventChainFinished, EventRunFinished,
+	}
+	if got := rec.kinds(""); !slices.Equal(got, want) {
+		t.Fatalf("события:\n  %v\nожидалось:\n  %v", got, want)
+	}
+
+	exited, _ := rec.find(EventCommandExited)
+	if exited.PID == 0 || exited.ExitCode == nil || *exited.ExitCode != 0 {
+		t.Errorf("выход без PID или кода: %+v", exited)
+	}
+
+	if finished, _ := rec.find(EventChainFinished); finished.Status != ChainDone {
+		t.Errorf("итог цепочки %q, ожидался %q", finished.Status, ChainDone)
+	}
+}
+
+// TestEvents_TimeoutEscalation — о таймауте сообщается раньше, чем о сигнале
+// и выходе процесса: потребителю важно, почему команду снимают.
+func TestEvents_TimeoutEscalation(t *testing.T) {
+	requireIntegration(t)
+
+	rec := &eventRecorder{}
+	out := ui.NewDiscardOutput()
+	mgr := NewManager(out.Logger(), out.Formatter(), WithTimeouts(testTimeouts), WithEvents(rec))
+	chain, cmd := hangingCommand(t, 100*time.Millisecond)
+
+	_ = mgr.Execute(t.Context(), chain, cmd)
+
+	got := rec.kinds("slow")
+	want := []EventKind{EventCommandStarted, EventCommandTimeout, EventSignalSent, EventCommandExited}
+
+	if !slices.Equal(got, want) {
+		t.Fatalf("события:\n  %v\nожидалось:\n  %v", got, want)
+	}
+}
+
+func TestEvents_Restart(t *testing.T) {
+	rec := &eventRecorder{}
+	runner := &scriptedRunner{outcomes: []error{errFakeA, nil}}
+	chain, cmd := restartCmd(flow.RestartOnFailure, 0)
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withEvents(rec))
+
+	err := exec.runWithRestart(t.Context(), chain, cmd, func(ctx context.Context) error {
+		return runner.Execute(ctx, chain, cmd)
+	})
+	if err != nil {
+		t.Fatalf("runWithRestart: %v", err)
+	}
+
+	ev, ok := rec.find(EventCommandRestarted)
+	if !ok {
+		t.Fatal("о перезапуске не сообщено")
+	}
+
+	if ev.Attempt != 2 || ev.Reason != RestartReasonPolicy || ev.Error != errFakeA.Error() {
+		t.Errorf("событие перезапуска: %+v", ev)
+	}
+}
+
+func TestEvents_SkippedChain(t *testing.T) {
+	rec := &eventRecorder{}
+	runner := &scriptedRunner{outcomes: []error{errFakeA}}
+
+	db := liveChainOf("db")
+	api := liveChainOf("api")
+	api.Needs = []string{"db"}
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withKeepGoing(), withEvents(rec))
+	_ = exec.ExecuteParallel(t.Context(), []*flow.CommandChain{db, api})
+
+	if got := rec.kinds("api"); !slices.Equal(got, []EventKind{EventChainSkipped}) {
+		t.Fatalf("события api: %v", got)
+	}
+
+	if finished, _ := rec.find(EventRunFinished); finished.Status != ChainFailed || finished.Error == "" {
+		t.Errorf("итог запуска: %+v", finished)
+	}
+}
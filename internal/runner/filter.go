@@ -0,0 +1,253 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// ErrInvalidFilter — шаблон фильтра вывода не разбирается как регулярное
+// выражение.
+var ErrInvalidFilter = errors.New("invalid output filter")
+
+// FilterState — снимок фильтра вывода для внешнего наблюдателя.
+type FilterState struct {
+	// Muted — заглушённые цепочки; Solo — цепочки, которые только и видны.
+	Muted []string
+	Solo  []string
+	// Include и Exclude — шаблоны строк; пустая строка — шаблона нет.
+	Include string
+	Exclude string
+}
+
+// filterRules — неизменяемые правила фильтра. Каждое изменение собирает
+// новые правила целиком, поэтому читатель видит либо прежние, либо новые.
+type filterRules struct {
+	muted   map[string]bool
+	solo    map[string]bool
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// outputFilter решает, какие строки вывода команд доходят до терминала.
+//
+// Фильтр стоит после наблюдателей готовности и журналов цепочек: он про то,
+// что видит человек, а logLine и файл журнала должны получать вывод целиком,
+// иначе заглушённый сервис никогда бы не стал готов. Строки читаются
+// сотнями тысяч, а правила меняются руками раз в минуту, поэтому чтение —
+// атомарная загрузка без блокировки, а мьютекс нужен только писателям.
+type outputFilter struct {
+	mu    sync.Mutex
+	rules atomic.Pointer[filterRules]
+}
+
+func newOutputFilter() *outputFilter {
+	f := &outputFilter{}
+	f.rules.Store(&filterRules{})
+
+	return f
+}
+
+// shows сообщает, показывать ли строку цепочки.
+func (f *outputFilter) shows(chain, line string) bool {
+	r := f.rules.Load()
+
+	if len(r.solo) > 0 {
+		if !r.solo[chain] {
+			return false
+		}
+	} else if r.muted[chain] {
+		return false
+	}
+
+	if r.include != nil && !r.include.MatchString(line) {
+		return false
+	}
+
+	return r.exclude == nil || !r.exclude.MatchString(line)
+}
+
+// keep оставляет в собранном выводе не-pipe команды только видимые строки.
+func (f *outputFilter) keep(chain string, data []byte) []byte {
+	r := f.rules.Load()
+	if len(data) == 0 || len(r.solo) == 0 && len(r.muted) == 0 && r.include == nil && r.exclude == nil {
+		return data
+	}
+
+	var out []byte
+
+	for line := range bytes.SplitSeq(bytes.TrimSuffix(data, newlineBytes), newlineBytes) {
+		if f.shows(chain, string(line)) {
+			out = append(append(out, line...), newlineBytes...)
+		}
+	}
+
+	return out
+}
+
+// update применяет изменение к копии правил и публикует её.
+func (f *outputFilter) update(change func(*filterRules)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cur := f.rules.Load()
+	next := &filterRules{
+		muted:   maps.Clone(cur.muted),
+		solo:    maps.Clone(cur.solo),
+		include: cur.include,
+		exclude: cur.exclude,
+	}
+
+	change(next)
+	f.rules.Store(next)
+}
+
+// state возвращает снимок правил.
+func (f *outputFilter) state() FilterState {
+	r := f.rules.Load()
+
+	s := FilterState{
+		Muted: slices.Sorted(maps.Keys(r.muted)),
+		Solo:  slices.Sorted(maps.Keys(r.solo)),
+	}
+
+	if r.include != nil {
+		s.Include = r.include.String()
+	}
+
+	if r.exclude != nil {
+		s.Exclude = r.exclude.String()
+	}
+
+	return s
+}
+
+// toggleSet добавляет имя в набор или убирает его; пустое имя при удалении
+// очищает набор.
+func toggleSet(set map[string]bool, name string, on bool) map[string]bool {
+	switch {
+	case on:
+		if set == nil {
+			set = make(map[string]bool)
+		}
+
+		set[name] = true
+	case name == "":
+		return nil
+	default:
+		delete(set, name)
+	}
+
+	return set
+}
+
+// compileFilter разбирает шаблон фильтра; пустой шаблон — фильтра нет.
+func compileFilter(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil //nolint:nilnil // отсутствие шаблона — штатное «фильтр снят»
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFilter, err)
+	}
+
+	return re, nil
+}
+
+// MuteChain скрывает вывод цепочки. Готовность и журнал цепочки вывод
+// по-прежнему получают.
+func (m *Manager) MuteChain(name string) error {
+	return m.filterChain(name, "Muting chain output", func(r *filterRules) {
+		r.muted = toggleSet(r.muted, name, true)
+	})
+}
+
+// UnmuteChain возвращает вывод цепочки; пустое имя снимает заглушку со всех.
+func (m *Manager) UnmuteChain(name string) error {
+	return m.filterChain(name, "Unmuting chain output", func(r *filterRules) {
+		r.muted = toggleSet(r.muted, name, false)
+	})
+}
+
+// SoloChain оставляет на экране вывод только выбранных цепочек. Пока такие
+// есть, заглушки остальных не важны.
+func (m *Manager) SoloChain(name string) error {
+	return m.filterChain(name, "Soloing chain output", func(r *filterRules) {
+		r.solo = toggleSet(r.solo, name, true)
+	})
+}
+
+// UnsoloChain убирает цепочку из выбранных; пустое имя снимает выбор целиком.
+func (m *Manager) UnsoloChain(name string) error {
+	return m.filterChain(name, "Unsoloing chain output", func(r *filterRules) {
+		r.solo = toggleSet(r.solo, name, false)
+	})
+}
+
+// IncludeLines оставляет на экране только строки, подходящие под шаблон;
+// пустой шаблон снимает фильтр.
+func (m *Manager) IncludeLines(pattern string) error {
+	return m.filterLines(pattern, "Including output lines", func(r *filterRules, re *regexp.Regexp) {
+		r.include = re
+	})
+}
+
+// ExcludeLines скрывает строки, подходящие под шаблон; пустой шаблон снимает
+// фильтр.
+func (m *Manager) ExcludeLines(pattern string) error {
+	return m.filterLines(pattern, "Excluding output lines", func(r *filterRules, re *regexp.Regexp) {
+		r.exclude = re
+	})
+}
+
+// OutputFilter возвращает действующий фильтр вывода.
+func (m *Manager) OutputFilter() FilterState {
+	return m.filter.state()
+}
+
+// filterChain меняет фильтр цепочки, убедившись, что она есть в запуске:
+// опечатка в имени иначе молча заглушила бы несуществующую цепочку.
+// Отработавшую цепочку заглушить можно — её перезапустят.
+func (m *Manager) filterChain(name, msg string, change func(*filterRules)) error {
+	live := m.chains.live.Load()
+	if live == nil {
+		return ErrNoRun
+	}
+
+	if name == "" {
+		m.filter.update(change)
+		m.lgr.Info(msg + " for all chains")
+
+		return nil
+	}
+
+	if err := live.known(name); err != nil {
+		return err
+	}
+
+	m.filter.update(change)
+	m.lgr.Info(msg, ui.F("chain", name))
+
+	return nil
+}
+
+// filterLines меняет шаблон строк фильтра.
+func (m *Manager) filterLines(pattern, msg string, change func(*filterRules, *regexp.Regexp)) error {
+	re, err := compileFilter(pattern)
+	if err != nil {
+		return err
+	}
+
+	m.filter.update(func(r *filterRules) { change(r, re) })
+	m.lgr.Info(msg, ui.F("pattern", pattern))
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOutputFilter_Shows(t *testing.T) {
+	f := newOutputFilter()
+
+	f.update(func(r *filterRules) { r.muted = toggleSet(r.muted, "worker", true) })
+
+	if f.shows("worker", "tick") || !f.shows("api", "GET /") {
+		t.Fatal("заглушка должна скрывать только свою цепочку")
+	}
+
+	// Выбранная цепочка перекрывает заглушки: видна только она.
+	f.update(func(r *filterRules) { r.solo = toggleSet(r.solo, "api", true) })
+
+	if f.shows("db", "ready") || !f.shows("api", "GET /") {
+		t.Fatal("при выборе видна только выбранная цепочка")
+	}
+
+	exclude, err := compileFilter(`GET /health`)
+	if err != nil {
+		t.Fatalf("compileFilter: %v", err)
+	}
+
+	f.update(func(r *filterRules) { r.exclude = exclude })
+
+	if f.shows("api", "GET /health 200") || !f.shows("api", "GET /users 200") {
+		t.Fatal("exclude должен скрывать только подходящие строки")
+	}
+
+	f.update(func(r *filterRules) { r.solo = toggleSet(r.solo, "", false) })
+
+	if st := f.state(); len(st.Solo) != 0 || len(st.Muted) != 1 || st.Exclude != `GET /health` {
+		t.Fatalf("снимок фильтра = %+v", st)
+	}
+}
+
+func TestOutputFilter_Keep(t *testing.T) {
+	f := newOutputFilter()
+
+	include, err := compileFilter(`^ERR`)
+	if err != nil {
+		t.Fatalf("compileFilter: %v", err)
+	}
+
+	f.update(func(r *filterRules) { r.include = include })
+
+	if got := string(f.keep("api", []byte("ok\nERR one\nok\nERR two\n"))); got != "ERR one\nERR two\n" {
+		t.Fatalf("получено %q", got)
+	}
+
+	if got := f.keep("api", []byte("ok\n")); len(got) != 0 {
+		t.Fatalf("скрытый целиком вывод не должен печататься пустым блоком: %q", got)
+	}
+}
+
+func TestCompileFilter_Invalid(t *testing.T) {
+	if _, err := compileFilter(`(`); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("ожидался ErrInvalidFilter, получено %v", err)
+	}
+}
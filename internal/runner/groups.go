@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"sync"
+
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// chainGroups держит открытые группы вывода цепочек в режиме -output grouped.
+//
+// Группа открывается по первой строке цепочки, а закрывается — и печатается —
+// по окончании её запуска. Перезапущенная цепочка начинает новую группу:
+// читателю CI нужен вывод каждого прогона, а не один растущий кусок.
+type chainGroups struct {
+	lgr ui.GroupLogger
+
+	mu   sync.Mutex
+	open map[string]ui.LogGroup
+}
+
+func newChainGroups(lgr ui.GroupLogger) *chainGroups {
+	return &chainGroups{lgr: lgr, open: make(map[string]ui.LogGroup)}
+}
+
+// of возвращает логгер группы цепочки, открывая её при необходимости.
+func (g *chainGroups) of(name string) ui.Logger {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group, ok := g.open[name]
+	if !ok {
+		group = g.lgr.Group(name)
+		g.open[name] = group
+	}
+
+	return group
+}
+
+// close печатает группу цепочки, если она открыта.
+func (g *chainGroups) close(name string) {
+	g.mu.Lock()
+	group, ok := g.open[name]
+	delete(g.open, name)
+	g.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if err := group.Close(); err != nil {
+		g.lgr.Error(err, "Failed to print chain output", ui.F("chain", name))
+	}
+}
+
+// closeAll печатает все оставшиеся группы: вывод цепочки, не дошедшей до
+// штатного конца, нужен тем более.
+func (g *chainGroups) closeAll() {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	names := make([]string, 0, len(g.open))
+
+	for name := range g.open {
+		names = append(names, name)
+	}
+	g.mu.Unlock()
+
+	for _, name := range names {
+		g.close(name)
+	}
+}
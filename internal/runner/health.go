@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// withHealth оборачивает запуск команды проверкой здоровья; без секции
+// health запуск остаётся как есть.
+//
+// Нездоровую команду снимает отмена контекста запуска — та же лестница
+// stopCommand, что у Ctrl+C и слежения за файлами. Запуск при этом
+// возвращает не отмену, а ErrCommandUnhealthy: для политики перезапуска это
+// отказ, и on-failure поднимает команду так же, как после падения.
+func (c *chainExecutor) withHealth(
+	chain *flow.CommandChain, cmd flow.Command, run func(context.Context) error,
+) func(context.Context) error {
+	if cmd.Health == nil {
+		return run
+	}
+
+	return func(ctx context.Context) error {
+		runCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		finished := make(chan struct{})
+
+		go func() {
+			defer close(finished)
+
+			c.watchHealth(runCtx, chain, cmd, cancel)
+		}()
+
+		err := run(runCtx)
+
+		cancel(nil)
+		<-finished
+
+		if cause := context.Cause(runCtx); errors.Is(cause, ErrCommandUnhealthy) {
+			return cause
+		}
+
+		return err
+	}
+}
+
+// watchHealth пробует команду каждый интервал, пока идёт её запуск, и
+// вызывает stop, когда отказов подряд набирается порог.
+//
+// Отказы считаются после первой удачной пробы либо по истечении льготного
+// срока на старт, смотря что раньше. Без срока медленный старт выглядел бы
+// как зависание, а без его предела потребитель, зависший ещё до первой
+// удачной пробы, не снимался бы никогда — а это и есть тот случай, ради
+// которого health существует.
+func (c *chainExecutor) watchHealth(
+	ctx context.Context, chain *flow.CommandChain, cmd flow.Command, stop context.CancelCauseFunc,
+) {
+	health := cmd.Health
+	every := health.Every()
+	graceEnds := time.Now().Add(healthGrace(cmd))
+
+	healthy, failures := false, 0
+
+	for sleepOrCancel(ctx, every) {
+		// Проба ограничена интервалом: зависшая exec-проба иначе копила бы
+		// отказы медленнее, чем их успевают заметить.
+		probeCtx, cancel := context.WithTimeout(ctx, every)
+		ok := probe(probeCtx, health.Probe)
+
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case ok:
+			if !healthy {
+				c.reportHealth(chain, cmd, EventCommandHealthy, 0)
+			}
+
+			healthy, failures = true, 0
+		case healthy || !time.Now().Before(graceEnds):
+			failures++
+
+			c.lgr.Debug("Health check failed",
+				ui.F("chain", chain.GetChainName()),
+				ui.F("command", cmd.DisplayName()),
+				ui.F("failures", failures))
+
+			if failures >= health.Failures() {
+				c.reportHealth(chain, cmd, EventCommandUnhealthy, failures)
+				stop(fmt.Errorf("%w: command %q in chain %q failed %d health checks in a row (%s)",
+					ErrCommandUnhealthy, cmd.DisplayName(), chain.GetChainName(), failures,
+					health.Probe.Describe()))
+
+				return
+			}
+		}
+	}
+}
+
+// healthGrace — льготный срок на старт, в который отказы проб не считаются.
+//
+// Сколько может длиться старт, уже говорит ready: команда, не готовая за его
+// срок, и так считается упавшей. Без ready срок — один интервал: раньше
+// первая проба всё равно не состоится.
+func healthGrace(cmd flow.Command) time.Duration {
+	if cmd.Ready != nil {
+		return cmd.Ready.Limit()
+	}
+
+	return cmd.Health.Every()
+}
+
+// reportHealth сообщает о смене состояния здоровья в журнал и поток событий.
+func (c *chainExecutor) reportHealth(chain *flow.CommandChain, cmd flow.Command, kind EventKind, failures int) {
+	fields := []ui.Field{ui.F("chain", chain.GetChainName()), ui.F("command", cmd.DisplayName())}
+
+	if kind == EventCommandUnhealthy {
+		c.lgr.Warn("Command is unhealthy, stopping", append(fields, ui.F("failures", failures))...)
+	} else {
+		c.lgr.Info("Command is healthy", fields...)
+	}
+
+	emitEvent(c.events, Event{
+		Kind: kind, Chain: chain.GetChainName(), Command: cmd.DisplayName(), Failures: failures,
+	})
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// healthCmd собирает команду с файловой пробой здоровья: файл есть —
+// здорова, удалили — пробы отказывают. Интервал крохотный: проверяется
+// поведение, а не тайминги.
+func healthCmd(t *testing.T, policy flow.RestartPolicy) (flow.Command, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "alive")
+
+	return flow.Command{
+		Name:         "consumer",
+		Cmd:          "worker",
+		Restart:      policy,
+		RestartDelay: time.Millisecond,
+		Health: &flow.Health{
+			Probe:     &flow.ReadyCondition{File: &flow.FileProbe{Path: path}},
+			Interval:  5 * time.Millisecond,
+			Threshold: 2,
+		},
+	}, path
+}
+
+// hangUntilUnhealthy — запуск зависшего процесса: он не выходит сам, а
+// после первой удачной пробы перестаёт отвечать.
+func hangUntilUnhealthy(events *eventRecorder, path string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				if _, ok := events.find(EventCommandHealthy); ok {
+					_ = os.Remove(path)
+				}
+			}
+		}
+	}
+}
+
+// TestRunWithRestart_RestartsUnhealthy — ровно тот случай, ради которого
+// задача делалась: процесс завис, не выходя, и поднимается заново политикой
+// on-failure.
+func TestRunWithRestart_RestartsUnhealthy(t *testing.T) {
+	events := &eventRecorder{}
+	exec := newChainExecutor(ui.NewDiscardLogger(), &fakeRunner{}, nil, withEvents(events))
+	cmd, path := healthCmd(t, flow.RestartOnFailure)
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs atomic.Int32
+
+	hang := hangUntilUnhealthy(events, path)
+
+	err := exec.runWithRestart(t.Context(), &flow.CommandChain{Name: "queue"}, cmd, func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			return hang(ctx)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("второй запуск успешен, ошибки быть не должно: %v", err)
+	}
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("запусков = %d, ожидалось 2", got)
+	}
+
+	ev, ok := events.find(EventCommandUnhealthy)
+	if !ok || ev.Failures != 2 || ev.Command != "consumer" {
+		t.Fatalf("событие command.unhealthy = %+v, есть: %v", ev, ok)
+	}
+
+	if ev, ok = events.find(EventCommandRestarted); !ok || ev.Reason != RestartReasonPolicy {
+		t.Errorf("перезапуск не по политике: %+v", ev)
+	}
+}
+
+// TestRunWithRestart_UnhealthyWithoutRestart — без политики нездоровая
+// команда снимается и даёт отказ, а не штатную остановку.
+func TestRunWithRestart_UnhealthyWithoutRestart(t *testing.T) {
+	events := &eventRecorder{}
+	exec := newChainExecutor(ui.NewDiscardLogger(), &fakeRunner{}, nil, withEvents(events))
+	cmd, path := healthCmd(t, flow.RestartNever)
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := exec.runWithRestart(t.Context(), &flow.CommandChain{Name: "queue"}, cmd, hangUntilUnhealthy(events, path))
+	if !errors.Is(err, ErrCommandUnhealthy) || errors.Is(err, context.Canceled) {
+		t.Fatalf("ожидалась ErrCommandUnhealthy, получено %v", err)
+	}
+}
+
+// TestWithHealth_StartingIsNotUnhealthy — в срок ready отказы не считаются:
+// медленно поднимающийся сервис не должен сниматься как зависший.
+func TestWithHealth_StartingIsNotUnhealthy(t *testing.T) {
+	events := &eventRecorder{}
+	exec := newChainExecutor(ui.NewDiscardLogger(), &fakeRunner{}, nil, withEvents(events))
+	cmd, _ := healthCmd(t, flow.RestartNever)
+	cmd.Ready = &flow.ReadyCondition{TCP: "127.0.0.1:1", Timeout: time.Minute}
+
+	run := exec.withHealth(&flow.CommandChain{Name: "queue"}, cmd, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * cmd.Health.Interval):
+			return nil
+		}
+	})
+
+	if err := run(t.Context()); err != nil {
+		t.Fatalf("неготовая команда снята: %v", err)
+	}
+
+	if kinds := events.kinds(""); len(kinds) != 0 {
+		t.Errorf("неожиданные события: %v", kinds)
+	}
+}
+
+// TestWithHealth_HangsBeforeHealthy — потребитель, зависший ещё до первой
+// удачной пробы, снимается по истечении льготного срока, а не живёт вечно.
+func TestWithHealth_HangsBeforeHealthy(t *testing.T) {
+	events := &eventRecorder{}
+	exec := newChainExecutor(ui.NewDiscardLogger(), &fakeRunner{}, nil, withEvents(events))
+	cmd, _ := healthCmd(t, flow.RestartNever)
+
+	run := exec.withHealth(&flow.CommandChain{Name: "queue"}, cmd, func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+
+	if err := run(t.Context()); !errors.Is(err, ErrCommandUnhealthy) {
+		t.Fatalf("ожидалась ErrCommandUnhealthy, получено %v", err)
+	}
+
+	if _, ok := events.find(EventCommandHealthy); ok {
+		t.Error("команда ни разу не была здорова, а событие command.healthy есть")
+	}
+}
+
+func TestHealthGrace(t *testing.T) {
+	cmd := flow.Command{Health: &flow.Health{Interval: time.Second}}
+	if got := healthGrace(cmd); got != time.Second {
+		t.Errorf("без ready = %s, ожидался интервал", got)
+	}
+
+	cmd.Ready = &flow.ReadyCondition{TCP: "127.0.0.1:1"}
+	if got := healthGrace(cmd); got != flow.DefaultReadyTimeout {
+		t.Errorf("с ready = %s, ожидался его срок", got)
+	}
+}
@@ -0,0 +1,177 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+var (
+	// ErrBeforeFailed — команда before завершилась неуспехом, и цепочки не
+	// запускались.
+	ErrBeforeFailed = errors.New("before command failed")
+	// ErrTeardownFailed — команда finally или onExit завершилась неуспехом.
+	ErrTeardownFailed = errors.New("teardown command failed")
+)
+
+// Имена, под которыми выводятся команды before и onExit. Своей цепочки у
+// них нет, а вывод без имени не отличить от вывода соседей.
+const (
+	beforeChain = "before"
+	onExitChain = "onExit"
+)
+
+// withBefore задаёт команды, выполняемые до старта цепочек.
+func withBefore(cmds []flow.Command) chainOption {
+	return func(c *chainExecutor) { c.before = cmds }
+}
+
+// withOnExit задаёт команды, выполняемые в конце запуска.
+func withOnExit(cmds []flow.Command) chainOption {
+	return func(c *chainExecutor) { c.onExit = cmds }
+}
+
+// hookChain — цепочка-вывеска для команд before и onExit.
+//
+// Цвет — следующий за старшим цветом цепочек: после отбора цвета идут с
+// пропусками, и номер по числу цепочек мог бы совпасть с чужим.
+func hookChain(name string, chains []*flow.CommandChain) *flow.CommandChain {
+	chain := &flow.CommandChain{Name: name}
+	for _, ch := range chains {
+		chain.ColorIdx = max(chain.ColorIdx, ch.ColorIdx+1)
+	}
+
+	return chain
+}
+
+// runHook выполняет одну команду подготовки или уборки — без перезапуска и
+// гейтов: ни того, ни другого у таких команд нет.
+func (c *chainExecutor) runHook(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	if cmd.Pipe {
+		return c.runner.ExecuteWithPipe(ctx, chain, cmd)
+	}
+
+	return c.runner.Execute(ctx, chain, cmd)
+}
+
+// runBefore выполняет before по очереди и останавливается на первом отказе:
+// подготовка, которая не удалась, делает бессмысленным всё, что идёт за ней.
+//
+// Отказ несёт ошибку самой команды, и ExitCode отдаёт наружу её код — скрипту
+// важно, что упал именно `go mod download`, а не что-то внутри parallel.
+func (c *chainExecutor) runBefore(ctx context.Context, chains []*flow.CommandChain) error {
+	if len(c.before) == 0 {
+		return nil
+	}
+
+	chain := hookChain(beforeChain, chains)
+
+	defer func() {
+		if c.chainDone != nil {
+			c.chainDone(chain.Name)
+		}
+	}()
+
+	c.lgr.Info("Running before commands")
+
+	for _, cmd := range c.before {
+		if cmd.Disable {
+			c.logSkipped(chain, cmd)
+
+			continue
+		}
+
+		if err := c.runHook(ctx, chain, cmd); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+
+			return fmt.Errorf("%w: command %q: %w", ErrBeforeFailed, cmd.DisplayName(), err)
+		}
+	}
+
+	return nil
+}
+
+// abortRun завершает запуск, до цепочек которого дело не дошло.
+//
+// Цепочки попадают в сводку пропущенными с причиной, а после остановки по
+// сигналу — остановленными: пустая сводка не объяснила бы, почему не
+// запустилось ничего. onExit выполняется и здесь — он обещан при любом исходе.
+func (c *chainExecutor) abortRun(ctx context.Context, chains []*flow.CommandChain, cause error) error {
+	canceled := errors.Is(cause, context.Canceled)
+
+	c.results = make([]ChainResult, 0, len(chains))
+	for _, chain := range chains {
+		res := ChainResult{Name: chain.Name, Stopped: canceled, Skipped: !canceled}
+		if !canceled {
+			res.Err = fmt.Errorf("chain %q not started: %w", chain.Name, cause)
+		}
+
+		c.results = append(c.results, res)
+	}
+
+	err := joinRealErrors([]error{cause, c.runOnExit(ctx, chains)})
+	emitEvent(c.events, Event{
+		Kind: EventRunFinished, Status: finalState(ctx.Err() != nil, err), Error: errorText(err),
+	})
+
+	return err
+}
+
+// runTeardown выполняет команды уборки по очереди и возвращает все их отказы.
+//
+// Контекст отвязан от отмены: уборка нужна именно тогда, когда запуск
+// прервали, и Ctrl+C, остановивший цепочку, не должен останавливать её
+// `docker compose down`. Взамен у всего списка свой предел —
+// flow.DefaultTeardownTimeout, а повторный Ctrl+C убивает процессы уборки
+// вместе со всеми остальными.
+//
+// Отказ команды не прерывает список: после неудачного `down` удалить
+// lock-файл всё равно нужно.
+func (c *chainExecutor) runTeardown(ctx context.Context, chain *flow.CommandChain, cmds []flow.Command) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flow.DefaultTeardownTimeout)
+	defer cancel()
+
+	c.lgr.Info("Running teardown commands", ui.F("chain", chain.Name))
+
+	errs := make([]error, 0, len(cmds))
+
+	for _, cmd := range cmds {
+		if cmd.Disable {
+			c.logSkipped(chain, cmd)
+
+			continue
+		}
+
+		if err := c.runHook(ctx, chain, cmd); err != nil {
+			errs = append(errs, fmt.Errorf("%w: chain %q, command %q: %w", ErrTeardownFailed, chain.Name,
+				cmd.DisplayName(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// runOnExit выполняет onExit после всех цепочек.
+func (c *chainExecutor) runOnExit(ctx context.Context, chains []*flow.CommandChain) error {
+	if len(c.onExit) == 0 {
+		return nil
+	}
+
+	chain := hookChain(onExitChain, chains)
+	err := c.runTeardown(ctx, chain, c.onExit)
+
+	if c.chainDone != nil {
+		c.chainDone(chain.Name)
+	}
+
+	return err
+}
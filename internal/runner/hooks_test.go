@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// withFinally добавляет цепочке команды уборки с заданными именами.
+func withFinally(chain *flow.CommandChain, names ...string) *flow.CommandChain {
+	for _, name := range names {
+		chain.Finally = append(chain.Finally, flow.Command{Name: name, Cmd: "echo"})
+	}
+
+	return chain
+}
+
+// TestExecuteParallel_FinallyAfterCancel — ради этого задача и делалась:
+// Ctrl+C останавливает сервер, но его уборка выполняется до конца.
+func TestExecuteParallel_FinallyAfterCancel(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.hold["db.db-cmd"] = 5 * time.Second
+	runner.hold["db.down"] = 100 * time.Millisecond
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	chains := []*flow.CommandChain{withFinally(depChain("db", nil, nil), "down")}
+
+	if err := exec.ExecuteParallel(ctx, chains); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if runner.startedAt("db.down").IsZero() {
+		t.Fatal("finally не выполнился после отмены")
+	}
+
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("запуск вернулся за %s — уборку оборвала отмена", elapsed)
+	}
+}
+
+// TestExecuteParallel_FinallyFailure — отказ уборки не прерывает её остаток
+// и делает отказом саму цепочку.
+func TestExecuteParallel_FinallyFailure(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.failing["db.down"] = errFakeA
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil)
+
+	chains := []*flow.CommandChain{withFinally(depChain("db", nil, nil), "down", "unlock")}
+
+	err := exec.ExecuteParallel(t.Context(), chains)
+	if !errors.Is(err, ErrTeardownFailed) || !errors.Is(err, errFakeA) {
+		t.Fatalf("ожидалась ErrTeardownFailed, получено %v", err)
+	}
+
+	if runner.startedAt("db.unlock").IsZero() {
+		t.Error("unlock не выполнился после упавшего down")
+	}
+}
+
+// TestExecuteParallel_FinallySkippedChain — цепочка, которая так и не
+// запустилась, убирать за собой ничего не должна.
+func TestExecuteParallel_FinallySkippedChain(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.failing["db.db-cmd"] = errFakeA
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withKeepGoing())
+
+	chains := []*flow.CommandChain{
+		withFinally(depChain("db", nil, nil), "down"),
+		withFinally(depChain("api", []string{"db"}, nil), "deregister"),
+	}
+
+	_ = exec.ExecuteParallel(t.Context(), chains)
+
+	if runner.startedAt("db.down").IsZero() {
+		t.Error("упавшая цепочка запускалась и обязана убрать за собой")
+	}
+
+	if !runner.startedAt("api.deregister").IsZero() {
+		t.Error("пропущенная цепочка выполнила finally")
+	}
+}
+
+// commandStarts — startsRunner, различающий команды цепочки: уборку нужно
+// отличить от самой команды.
+type commandStarts struct {
+	*startsRunner
+}
+
+func (r commandStarts) Execute(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	return r.startsRunner.Execute(ctx, &flow.CommandChain{Name: flow.CommandNode(chain.Name, cmd.Name)}, cmd)
+}
+
+func (r commandStarts) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	return r.Execute(ctx, chain, cmd)
+}
+
+// TestLive_FinallyAfterEachRun — finally завершает каждый запуск цепочки:
+// перезапуск целиком убирает за прежним запуском раньше, чем начнётся новый.
+func TestLive_FinallyAfterEachRun(t *testing.T) {
+	runner := commandStarts{newStartsRunner("api.api-cmd")}
+	exec, done := runLive(t, runner, withFinally(liveChainOf("api"), "down"))
+
+	runner.expectStart(t, "api.api-cmd")
+
+	live := exec.live.Load()
+
+	if err := live.restart("api"); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+
+	runner.expectStart(t, "api.down")
+	runner.expectStart(t, "api.api-cmd")
+
+	if err := live.stop("api"); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	runner.expectStart(t, "api.down")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ExecuteParallel: %v", err)
+		}
+	case <-time.After(testTimeouts.ForceKill):
+		t.Fatal("запуск не закончился после остановки цепочки")
+	}
+}
+
+// TestExecuteParallel_OnExit — onExit идёт после всех цепочек, и после
+// отказа тоже.
+func TestExecuteParallel_OnExit(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.hold["web.web-cmd"] = 100 * time.Millisecond
+	runner.failing["db.db-cmd"] = errFakeA
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withKeepGoing(),
+		withOnExit([]flow.Command{{Name: "cleanup", Cmd: "echo"}}))
+
+	chains := []*flow.CommandChain{depChain("db", nil, nil), depChain("web", nil, nil)}
+
+	if err := exec.ExecuteParallel(t.Context(), chains); !errors.Is(err, errFakeA) {
+		t.Fatalf("execute: %v", err)
+	}
+
+	webDone := runner.startedAt("web.web-cmd").Add(100 * time.Millisecond)
+	if cleanup := runner.startedAt("onExit.cleanup"); cleanup.IsZero() || cleanup.Before(webDone) {
+		t.Errorf("onExit стартовал %s, web закончил %s", cleanup, webDone)
+	}
+}
+
+// TestExecuteParallel_Before — подготовка идёт по очереди и до старта
+// любой цепочки.
+func TestExecuteParallel_Before(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.hold["before.deps"] = 100 * time.Millisecond
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil,
+		withBefore([]flow.Command{{Name: "deps", Cmd: "go"}, {Name: "certs", Cmd: "mkcert"}}))
+
+	if err := exec.ExecuteParallel(t.Context(), []*flow.CommandChain{depChain("api", nil, nil)}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	depsDone := runner.startedAt("before.deps").Add(100 * time.Millisecond)
+	certs, api := runner.startedAt("before.certs"), runner.startedAt("api.api-cmd")
+
+	if certs.Before(depsDone) || api.Before(certs) {
+		t.Errorf("порядок нарушен: deps до %s, certs %s, api %s", depsDone, certs, api)
+	}
+}
+
+// TestExecuteParallel_BeforeFailure — отказ подготовки отменяет запуск с
+// кодом упавшей команды, а onExit всё равно выполняется.
+func TestExecuteParallel_BeforeFailure(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.failing["before.deps"] = &ExitError{Chain: "before", Command: "deps", Code: 3}
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil,
+		withBefore([]flow.Command{{Name: "deps", Cmd: "go"}, {Name: "certs", Cmd: "mkcert"}}),
+		withOnExit([]flow.Command{{Name: "cleanup", Cmd: "echo"}}))
+
+	err := exec.ExecuteParallel(t.Context(), []*flow.CommandChain{depChain("api", nil, nil)})
+	if !errors.Is(err, ErrBeforeFailed) || ExitCode(err, 1) != 3 {
+		t.Fatalf("ожидалась ErrBeforeFailed с кодом 3, получено %v", err)
+	}
+
+	if !runner.startedAt("before.certs").IsZero() || !runner.startedAt("api.api-cmd").IsZero() {
+		t.Error("после упавшей подготовки запускаться нечему")
+	}
+
+	if runner.startedAt("onExit.cleanup").IsZero() {
+		t.Error("onExit не выполнился после упавшей подготовки")
+	}
+
+	if res := exec.results; len(res) != 1 || !res[0].Skipped || !errors.Is(res[0].Err, ErrBeforeFailed) {
+		t.Errorf("results = %+v", res)
+	}
+}
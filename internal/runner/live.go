@@ -0,0 +1,640 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+var (
+	// ErrNoRun — управляющая команда пришла, когда запуска нет: он ещё не
+	// начался или уже закончился.
+	ErrNoRun = errors.New("no run in progress")
+	// ErrChainRunning — цепочку просят запустить, а она уже работает.
+	ErrChainRunning = errors.New("chain is already running")
+	// ErrChainNotRunning — цепочку просят остановить, а она не работает.
+	ErrChainNotRunning = errors.New("chain is not running")
+	// ErrChainFinished — цепочка завершилась так, что запустить её снова
+	// нельзя: не дождалась предшественников либо упала в режиме fail-fast.
+	ErrChainFinished = errors.New("chain cannot be started again")
+	// ErrChainExists — в запуск добавляют цепочку, которая в нём уже есть.
+	ErrChainExists = errors.New("chain already exists")
+)
+
+// ChainState — состояние цепочки для внешнего наблюдателя.
+//
+// Строка, а не число: значение уходит наружу через управляющий сокет и
+// читается человеком в `parallel ctl status`.
+type ChainState string
+
+// Состояния цепочки.
+const (
+	// ChainWaiting — ждёт предшественников или свободного слота.
+	ChainWaiting ChainState = "waiting"
+	// ChainRunning — команды цепочки выполняются.
+	ChainRunning ChainState = "running"
+	// ChainStopped — остановлена сигналом, отказом соседа или по запросу.
+	ChainStopped ChainState = "stopped"
+	// ChainDone — последний запуск завершился успешно.
+	ChainDone ChainState = "done"
+	// ChainFailed — последний запуск завершился отказом.
+	ChainFailed ChainState = "failed"
+	// ChainSkipped — не начиналась: не выполнилось условие предшественника.
+	ChainSkipped ChainState = "skipped"
+)
+
+// ProcessStatus — процесс, работающий прямо сейчас.
+type ProcessStatus struct {
+	Command string
+	PID     int
+}
+
+// ChainStatus — снимок состояния цепочки во время запуска.
+type ChainStatus struct {
+	Name  string
+	State ChainState
+	// Since — момент перехода в текущее состояние.
+	Since time.Time
+	// Starts — сколько раз цепочка запускалась за этот прогон.
+	Starts int
+	// Processes — работающие процессы цепочки, упорядоченные по PID.
+	Processes []ProcessStatus
+}
+
+// liveChain — управляемое состояние одной цепочки.
+type liveChain struct {
+	state  ChainState
+	since  time.Time
+	starts int
+
+	// def — текущее определение цепочки. Читается перед каждым запуском,
+	// поэтому перечитанная конфигурация вступает в силу со следующего круга.
+	def *flow.CommandChain
+
+	// drop отменяет контекст горутины цепочки целиком — так цепочку убирают
+	// из запуска. nil, пока горутина не началась.
+	drop context.CancelFunc
+	// removed — цепочку убрали из конфигурации; в статусе её больше нет.
+	removed bool
+
+	// cancel останавливает текущий запуск; nil, пока цепочка не работает.
+	cancel context.CancelFunc
+	// again означает, что перезапуск запрошен, пока цепочка работала:
+	// закончив, она не паркуется, а сразу идёт на следующий круг.
+	again bool
+
+	// parked — горутина цепочки ждёт запроса на запуск; wake её будит.
+	parked bool
+	wake   chan struct{}
+	// gone — горутина цепочки завершилась, запустить её снова нечем.
+	gone bool
+
+	// commands — итог команд последнего запуска: для отчёта нужен именно
+	// последний, а не первый, если цепочку перезапускали.
+	commands []CommandResult
+
+	// busy копит время, когда цепочка не была припаркована: именно оно
+	// попадает в сводку, иначе минутный простой выглядел бы минутной работой.
+	busy        time.Duration
+	activeSince time.Time
+}
+
+// liveSet управляет цепочками живого запуска: останавливает и запускает их
+// по одной, не трогая соседей.
+//
+// Отработавшая цепочка не завершает свою горутину, а паркуется: иначе
+// запустить её снова было бы нечем. Запуск кончается, когда припаркованы все —
+// ровно тогда же, когда раньше заканчивались все горутины, так что без
+// управляющих команд поведение прежнее.
+type liveSet struct {
+	mu     sync.Mutex
+	chains map[string]*liveChain
+	order  []string
+
+	// active — сколько цепочек не припарковано. Когда их не остаётся, idle
+	// закрывается и припаркованные горутины расходятся.
+	active int
+	idle   chan struct{}
+	closed bool
+
+	// spawn запускает горутину для цепочки, добавленной во время запуска.
+	// Вызывается под mu: только так добавление не разминётся с концом запуска.
+	spawn func(*flow.CommandChain)
+}
+
+func newLiveSet(chains []*flow.CommandChain) *liveSet {
+	now := time.Now()
+
+	s := &liveSet{
+		chains: make(map[string]*liveChain, len(chains)),
+		order:  make([]string, 0, len(chains)),
+		active: len(chains),
+		idle:   make(chan struct{}),
+	}
+
+	for _, chain := range chains {
+		s.chains[chain.Name] = &liveChain{
+			state:       ChainWaiting,
+			since:       now,
+			def:         chain,
+			wake:        make(chan struct{}, 1),
+			activeSince: now,
+		}
+		s.order = append(s.order, chain.Name)
+	}
+
+	if s.active == 0 {
+		s.closeIdleLocked()
+	}
+
+	return s
+}
+
+// enter возвращает контекст горутины цепочки: его отмена убирает цепочку из
+// запуска, не задевая соседей.
+func (s *liveSet) enter(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	chainCtx, drop := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Цепочку могли убрать раньше, чем её горутина успела начаться.
+	if c, ok := s.chains[name]; ok && !c.removed {
+		c.drop = drop
+	} else {
+		drop()
+	}
+
+	return chainCtx, drop
+}
+
+// definition возвращает текущее определение цепочки.
+func (s *liveSet) definition(name string) *flow.CommandChain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.chains[name].def
+}
+
+// begin отмечает начало очередного запуска цепочки и возвращает контекст,
+// которым его можно остановить по запросу.
+func (s *liveSet) begin(ctx context.Context, name string) (context.Context, context.CancelFunc) {
+	runCtx, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; ok {
+		c.state, c.since = ChainRunning, time.Now()
+		c.starts++
+		c.cancel = cancel
+	}
+
+	return runCtx, cancel
+}
+
+// mark переводит цепочку в состояние вне запуска: остановить её уже нечем.
+func (s *liveSet) mark(name string, state ChainState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; ok {
+		c.state, c.since = state, time.Now()
+		c.cancel = nil
+	}
+}
+
+// park ждёт запроса на повторный запуск цепочки и сообщает, пришёл ли он.
+//
+// false означает, что запуск закончен: отменён снаружи либо припаркованы все.
+func (s *liveSet) park(ctx context.Context, name string) bool {
+	s.mu.Lock()
+
+	c, ok := s.chains[name]
+	if !ok {
+		s.mu.Unlock()
+
+		return false
+	}
+
+	if c.again {
+		c.again = false
+		s.mu.Unlock()
+
+		return true
+	}
+
+	c.parked = true
+	c.busy += time.Since(c.activeSince)
+	s.deactivateLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-c.wake:
+		return true
+	case <-s.idle:
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Запрос на запуск мог проскочить одновременно с концом запуска:
+	// раз никто его уже не исполнит, горутина уходит насовсем. Пробуждение
+	// успело вернуть её в число активных — без обратного шага запуск ждал
+	// бы её вечно.
+	if !c.parked {
+		c.busy += time.Since(c.activeSince)
+		s.deactivateLocked()
+	}
+
+	c.parked = false
+	c.gone = true
+
+	return false
+}
+
+// leave отмечает, что горутина цепочки завершилась без парковки.
+func (s *liveSet) leave(name string, state ChainState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.chains[name]
+	if !ok {
+		return
+	}
+
+	c.state, c.since = state, time.Now()
+	c.cancel = nil
+	c.gone = true
+	c.busy += time.Since(c.activeSince)
+
+	s.deactivateLocked()
+}
+
+// record запоминает итог команд очередного запуска цепочки.
+func (s *liveSet) record(name string, commands []CommandResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; ok {
+		c.commands = commands
+	}
+}
+
+// commands возвращает итог команд последнего запуска цепочки.
+func (s *liveSet) commands(name string) []CommandResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; ok {
+		return c.commands
+	}
+
+	return nil
+}
+
+// startedAt возвращает начало текущего запуска цепочки; нулевое время —
+// цепочка сейчас не работает.
+func (s *liveSet) startedAt(name string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; ok && c.state == ChainRunning {
+		return c.since
+	}
+
+	return time.Time{}
+}
+
+// busy возвращает время работы цепочки без учёта простоя.
+func (s *liveSet) busy(name string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; ok {
+		return c.busy
+	}
+
+	return 0
+}
+
+// start будит припаркованную цепочку.
+func (s *liveSet) start(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.lookupLocked(name)
+	if err != nil {
+		return err
+	}
+
+	if !c.parked {
+		return fmt.Errorf("%w: %q is %s", ErrChainRunning, name, c.state)
+	}
+
+	s.wakeLocked(c)
+
+	return nil
+}
+
+// stop останавливает текущий запуск цепочки. Соседей это не задевает:
+// отменяется только контекст самой цепочки.
+func (s *liveSet) stop(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.lookupLocked(name)
+	if err != nil {
+		return err
+	}
+
+	if c.cancel == nil {
+		return fmt.Errorf("%w: %q is %s", ErrChainNotRunning, name, c.state)
+	}
+
+	c.cancel()
+
+	return nil
+}
+
+// restart останавливает цепочку и запускает её снова; припаркованную — просто
+// запускает.
+func (s *liveSet) restart(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.lookupLocked(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c.parked:
+		s.wakeLocked(c)
+	case c.cancel != nil:
+		c.again = true
+		c.cancel()
+	default:
+		return fmt.Errorf("%w: %q is %s", ErrChainNotRunning, name, c.state)
+	}
+
+	return nil
+}
+
+// replace подменяет определение цепочки и запускает её заново с новым.
+//
+// Ждущая предшественников или слота цепочка не трогается: определение
+// читается при взятии слота, так что она и так запустится уже новой.
+func (s *liveSet) replace(chain *flow.CommandChain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.lookupLocked(chain.Name)
+	if err != nil {
+		return err
+	}
+
+	c.def = chain
+
+	switch {
+	case c.parked:
+		s.wakeLocked(c)
+	case c.cancel != nil:
+		c.again = true
+		c.cancel()
+	}
+
+	return nil
+}
+
+// add добавляет цепочку в идущий запуск и сразу её запускает.
+//
+// Имя убранной цепочки можно занять снова, но только когда её горутина
+// завершилась: две горутины на одно имя делили бы одно состояние.
+func (s *liveSet) add(chain *flow.CommandChain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.spawn == nil {
+		return ErrNoRun
+	}
+
+	c, ok := s.chains[chain.Name]
+
+	switch {
+	case ok && !c.removed:
+		return fmt.Errorf("%w: %q", ErrChainExists, chain.Name)
+	case ok && !c.gone:
+		return fmt.Errorf("%w: %q is still stopping", ErrChainRunning, chain.Name)
+	case !ok:
+		c = &liveChain{wake: make(chan struct{}, 1)}
+		s.chains[chain.Name] = c
+	}
+
+	// Пробуждение, не дошедшее до прежней горутины, новой не адресовано.
+	select {
+	case <-c.wake:
+	default:
+	}
+
+	now := time.Now()
+	c.state, c.since, c.activeSince = ChainWaiting, now, now
+	c.def, c.drop, c.cancel = chain, nil, nil
+	c.removed, c.gone, c.again, c.parked = false, false, false, false
+
+	s.order = append(s.order, chain.Name)
+	s.active++
+	s.spawn(chain)
+
+	return nil
+}
+
+// remove убирает цепочку из запуска: останавливает её, если она работает,
+// и завершает её горутину.
+func (s *liveSet) remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.chains[name]
+	if !ok || c.removed {
+		return fmt.Errorf("%w %q, available: %s", flow.ErrUnknownChain, name, strings.Join(s.order, ", "))
+	}
+
+	if s.closed {
+		return ErrNoRun
+	}
+
+	c.removed = true
+	s.order = slices.DeleteFunc(s.order, func(n string) bool { return n == name })
+
+	if c.drop != nil {
+		c.drop()
+	}
+
+	return nil
+}
+
+// status возвращает снимок всех цепочек в порядке конфигурации.
+func (s *liveSet) status() []ChainStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]ChainStatus, 0, len(s.order))
+
+	for _, name := range s.order {
+		c := s.chains[name]
+		out = append(out, ChainStatus{Name: name, State: c.state, Since: c.since, Starts: c.starts})
+	}
+
+	return out
+}
+
+// known проверяет, что цепочка есть в запуске. В отличие от lookupLocked
+// годится и отработавшая: дело не в том, можно ли её запустить.
+func (s *liveSet) known(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.chains[name]; !ok || c.removed {
+		return fmt.Errorf("%w %q, available: %s", flow.ErrUnknownChain, name, strings.Join(s.order, ", "))
+	}
+
+	return nil
+}
+
+// lookupLocked находит цепочку и проверяет, что запуск ещё идёт.
+func (s *liveSet) lookupLocked(name string) (*liveChain, error) {
+	c, ok := s.chains[name]
+	if !ok || c.removed {
+		return nil, fmt.Errorf("%w %q, available: %s", flow.ErrUnknownChain, name, strings.Join(s.order, ", "))
+	}
+
+	if s.closed {
+		return nil, ErrNoRun
+	}
+
+	if c.gone {
+		return nil, fmt.Errorf("%w: %q is %s", ErrChainFinished, name, c.state)
+	}
+
+	return c, nil
+}
+
+// wakeLocked возвращает припаркованную цепочку в число активных.
+func (s *liveSet) wakeLocked(c *liveChain) {
+	c.parked = false
+	c.activeSince = time.Now()
+	s.active++
+
+	c.wake <- struct{}{}
+}
+
+// deactivateLocked уменьшает число активных цепочек и, если их не осталось,
+// отпускает всех припаркованных.
+func (s *liveSet) deactivateLocked() {
+	s.active--
+
+	if s.active == 0 {
+		s.closeIdleLocked()
+	}
+}
+
+func (s *liveSet) closeIdleLocked() {
+	if !s.closed {
+		s.closed = true
+
+		close(s.idle)
+	}
+}
+
+// withProcesses раскладывает работающие процессы по цепочкам.
+func withProcesses(statuses []ChainStatus, procs []trackedProcess) []ChainStatus {
+	byChain := make(map[string][]ProcessStatus, len(statuses))
+
+	for _, p := range procs {
+		if p.cmd == nil || p.cmd.Process == nil {
+			continue
+		}
+
+		byChain[p.chain] = append(byChain[p.chain], ProcessStatus{Command: p.command, PID: p.cmd.Process.Pid})
+	}
+
+	for i := range statuses {
+		list := byChain[statuses[i].Name]
+		slices.SortFunc(list, func(a, b ProcessStatus) int { return a.PID - b.PID })
+		statuses[i].Processes = list
+	}
+
+	return statuses
+}
+
+// Status возвращает состояние каждой цепочки текущего запуска вместе с её
+// работающими процессами. Вне запуска возвращает nil.
+func (m *Manager) Status() []ChainStatus {
+	live := m.chains.live.Load()
+	if live == nil {
+		return nil
+	}
+
+	return withProcesses(live.status(), m.procs.list())
+}
+
+// StartChain запускает снова отработавшую или остановленную цепочку.
+func (m *Manager) StartChain(name string) error {
+	return m.control(name, "Starting chain on request", (*liveSet).start)
+}
+
+// StopChain останавливает одну цепочку той же лестницей, что и Ctrl+C, не
+// трогая соседей. Цепочка остаётся в запуске и может быть запущена снова.
+func (m *Manager) StopChain(name string) error {
+	return m.control(name, "Stopping chain on request", (*liveSet).stop)
+}
+
+// RestartChain останавливает цепочку и запускает её заново.
+func (m *Manager) RestartChain(name string) error {
+	return m.control(name, "Restarting chain on request", (*liveSet).restart)
+}
+
+// AddChain добавляет цепочку в идущий запуск и запускает её.
+func (m *Manager) AddChain(chain *flow.CommandChain) error {
+	return m.control(chain.Name, "Adding chain", func(live *liveSet, _ string) error {
+		return live.add(chain)
+	})
+}
+
+// ReplaceChain подменяет определение цепочки и перезапускает её с новым.
+// Предшественники повторно не запускаются.
+func (m *Manager) ReplaceChain(chain *flow.CommandChain) error {
+	return m.control(chain.Name, "Replacing chain", func(live *liveSet, _ string) error {
+		return live.replace(chain)
+	})
+}
+
+// RemoveChain останавливает цепочку и убирает её из запуска насовсем.
+func (m *Manager) RemoveChain(name string) error {
+	return m.control(name, "Removing chain", m.chains.remove)
+}
+
+// control применяет управляющее действие к цепочке текущего запуска.
+func (m *Manager) control(name, msg string, action func(*liveSet, string) error) error {
+	live := m.chains.live.Load()
+	if live == nil {
+		return ErrNoRun
+	}
+
+	if err := action(live, name); err != nil {
+		return err
+	}
+
+	m.lgr.Info(msg, ui.F("chain", name))
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// startsRunner сообщает о каждом запуске команды и держит её до отмены, если
+// команда названа в blocking.
+type startsRunner struct {
+	started  chan string
+	blocking map[string]bool
+}
+
+func newStartsRunner(blocking ...string) *startsRunner {
+	r := &startsRunner{started: make(chan string, 16), blocking: map[string]bool{}}
+	for _, name := range blocking {
+		r.blocking[name] = true
+	}
+
+	return r
+}
+
+func (r *startsRunner) Execute(ctx context.Context, chain *flow.CommandChain, _ flow.Command) error {
+	r.started <- chain.Name
+
+	if !r.blocking[chain.Name] {
+		return nil
+	}
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func (r *startsRunner) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	return r.Execute(ctx, chain, cmd)
+}
+
+// expectStart ждёт запуска команды указанной цепочки; пустое имя — любой.
+func (r *startsRunner) expectStart(t *testing.T, chain string) {
+	t.Helper()
+
+	select {
+	case got := <-r.started:
+		if chain != "" && got != chain {
+			t.Fatalf("запустилась цепочка %q, ожидалась %q", got, chain)
+		}
+	case <-time.After(testTimeouts.ForceKill):
+		t.Fatalf("цепочка %q так и не запустилась", chain)
+	}
+}
+
+// runLive запускает цепочки в фоне и возвращает исполнителя и канал с итогом.
+func runLive(t *testing.T, r CommandRunner, chains ...*flow.CommandChain) (*chainExecutor, <-chan error) {
+	t.Helper()
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), r, nil, withKeepGoing())
+	done := make(chan error, 1)
+
+	go func() { done <- exec.ExecuteParallel(t.Context(), chains) }()
+
+	return exec, done
+}
+
+// awaitState ждёт, пока цепочка перейдёт в нужное состояние.
+//
+// Опрос, а не событие: состояние выставляется после возврата команды, и
+// поймать этот момент из раннера нечем.
+func awaitState(t *testing.T, live *liveSet, name string, want ChainState) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeouts.ForceKill)
+
+	for time.Now().Before(deadline) {
+		for _, st := range live.status() {
+			if st.Name == name && st.State == want {
+				return
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("цепочка %q не перешла в состояние %s: %+v", name, want, live.status())
+}
+
+func liveChainOf(name string) *flow.CommandChain {
+	chain := &flow.CommandChain{Name: name}
+	chain.Add(flow.Command{Name: name + "-cmd", Cmd: "echo"})
+
+	return chain
+}
+
+// TestLive_RestartAndStopOneChain — перезапуск и остановка одной цепочки
+// не задевают соседнюю, а запуск заканчивается, когда не работает никто.
+func TestLive_RestartAndStopOneChain(t *testing.T) {
+	runner := newStartsRunner("api", "db")
+	exec, done := runLive(t, runner, liveChainOf("api"), liveChainOf("db"))
+
+	runner.expectStart(t, "")
+	runner.expectStart(t, "")
+
+	live := exec.live.Load()
+
+	if err := live.restart("api"); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+
+	runner.expectStart(t, "api")
+	awaitState(t, live, "api", ChainRunning)
+
+	for _, name := range []string{"api", "db"} {
+		if err := live.stop(name); err != nil {
+			t.Fatalf("stop %s: %v", name, err)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ExecuteParallel: %v", err)
+		}
+	case <-time.After(testTimeouts.ForceKill):
+		t.Fatal("запуск не закончился, когда все цепочки остановлены")
+	}
+
+	for _, res := range exec.results {
+		if !res.Stopped {
+			t.Errorf("цепочка %q должна числиться остановленной", res.Name)
+		}
+	}
+}
+
+// TestLive_StartFinishedChain — отработавшая цепочка паркуется и запускается
+// снова, пока работают соседи.
+func TestLive_StartFinishedChain(t *testing.T) {
+	runner := newStartsRunner("srv")
+	exec, done := runLive(t, runner, liveChainOf("job"), liveChainOf("srv"))
+
+	runner.expectStart(t, "")
+	runner.expectStart(t, "")
+
+	live := exec.live.Load()
+	awaitState(t, live, "job", ChainDone)
+
+	if err := live.stop("job"); !errors.Is(err, ErrChainNotRunning) {
+		t.Fatalf("остановка отработавшей цепочки: ожидался ErrChainNotRunning, получено %v", err)
+	}
+
+	if err := live.start("job"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	runner.expectStart(t, "job")
+	awaitState(t, live, "job", ChainDone)
+
+	if err := live.start("srv"); !errors.Is(err, ErrChainRunning) {
+		t.Fatalf("запуск работающей цепочки: ожидался ErrChainRunning, получено %v", err)
+	}
+
+	if err := live.start("nope"); !errors.Is(err, flow.ErrUnknownChain) {
+		t.Fatalf("ожидался flow.ErrUnknownChain, получено %v", err)
+	}
+
+	if err := live.stop("srv"); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	<-done
+
+	if st := live.status()[0]; st.Starts != 2 {
+		t.Fatalf("цепочка job запускалась %d раз, ожидалось 2", st.Starts)
+	}
+
+	if err := live.start("job"); !errors.Is(err, ErrNoRun) {
+		t.Fatalf("после конца запуска ожидался ErrNoRun, получено %v", err)
+	}
+}
+
+func TestManager_ControlWithoutRun(t *testing.T) {
+	mgr := newTestManager(t)
+
+	if st := mgr.Status(); st != nil {
+		t.Fatalf("вне запуска состояние должно быть пустым: %+v", st)
+	}
+
+	if err := mgr.RestartChain("api"); !errors.Is(err, ErrNoRun) {
+		t.Fatalf("ожидался ErrNoRun, получено %v", err)
+	}
+}
+
+// TestLive_ReloadChains — добавление, подмена и удаление цепочек во время
+// запуска задевают только названные цепочки.
+func TestLive_ReloadChains(t *testing.T) {
+	runner := newStartsRunner("api", "db", "worker")
+	exec, done := runLive(t, runner, liveChainOf("api"), liveChainOf("db"))
+
+	runner.expectStart(t, "")
+	runner.expectStart(t, "")
+
+	live := exec.live.Load()
+
+	if err := live.add(liveChainOf("worker")); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	runner.expectStart(t, "worker")
+
+	if err := live.add(liveChainOf("api")); !errors.Is(err, ErrChainExists) {
+		t.Fatalf("повторное добавление: ожидался ErrChainExists, получено %v", err)
+	}
+
+	next := &flow.CommandChain{Name: "api"}
+	next.Add(flow.Command{Name: "api-cmd", Cmd: "echo", Env: []string{"PORT=8081"}})
+
+	if err := live.replace(next); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+
+	runner.expectStart(t, "api")
+
+	if live.definition("api") != next {
+		t.Fatal("после подмены цепочка работает со старым определением")
+	}
+
+	if err := exec.remove(live, "db"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	awaitGone(t, live, "db")
+
+	for _, name := range []string{"api", "worker"} {
+		if err := live.stop(name); err != nil {
+			t.Fatalf("stop %s: %v", name, err)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ExecuteParallel: %v", err)
+		}
+	case <-time.After(testTimeouts.ForceKill):
+		t.Fatal("запуск не закончился, когда все цепочки остановлены")
+	}
+
+	if len(exec.results) != 3 || exec.results[2].Name != "worker" {
+		t.Fatalf("добавленная цепочка должна попасть в сводку последней: %+v", exec.results)
+	}
+}
+
+// TestLive_RemoveReleasesDependents — удаление цепочки, которую ещё ждут,
+// не останавливает ждущих: в новой конфигурации они от неё уже не зависят.
+func TestLive_RemoveReleasesDependents(t *testing.T) {
+	runner := newStartsRunner("old", "web")
+
+	old := liveChainOf("old")
+	old.Add(flow.Command{Name: "never", Cmd: "echo", Ready: &flow.ReadyCondition{LogLine: "never"}})
+
+	web := liveChainOf("web")
+	web.Needs = []string{"old"}
+
+	exec, done := runLive(t, runner, old, web)
+
+	runner.expectStart(t, "old")
+
+	live := exec.live.Load()
+
+	if err := exec.remove(live, "old"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	runner.expectStart(t, "web")
+
+	if err := live.stop("web"); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+
+	<-done
+
+	if err := live.add(liveChainOf("late")); !errors.Is(err, ErrNoRun) {
+		t.Fatalf("после конца запуска ожидался ErrNoRun, получено %v", err)
+	}
+}
+
+// awaitGone ждёт, пока убранная цепочка исчезнет из статуса и её горутина
+// завершится.
+func awaitGone(t *testing.T, live *liveSet, name string) {
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeouts.ForceKill)
+
+	for time.Now().Before(deadline) {
+		live.mu.Lock()
+		gone := live.chains[name].gone
+		live.mu.Unlock()
+
+		if gone {
+			for _, st := range live.status() {
+				if st.Name == name {
+					t.Fatalf("убранная цепочка %q осталась в статусе", name)
+				}
+			}
+
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("горутина убранной цепочки %q не завершилась", name)
+}
@@ -45,6 +45,12 @@ var newlineBytes = []byte(ui.NewlineChar)
 // нужен на деле, — CommandRunner на стороне chainExecutor.
 type Manager struct {
 	lgr ui.Logger
+	// lines — тот же логгер, если строки вывода команд ему нужны разобранными
+	// (режим -output json); nil — вывод печатается блоками.
+	lines ui.LineLogger
+	// groups копит вывод цепочек и печатает его по их окончании (режим
+	// -output grouped); nil — вывод печатается сразу.
+	groups *chainGroups
 
 	procs *processRegistry
 	// shutdownSig хранит сигнал завершения. Раньше это значение защищал
@@ -65,6 +71,23 @@ type Manager struct {
 
 	// maxParallel переносится в chainExecutor при сборке, как и keepGoing.
 	maxParallel int
+
+	// events принимает события запуска; nil — события никому не нужны.
+	events EventSink
+
+	// logs дублирует вывод цепочек в их файлы журналов.
+	logs *chainLogs
+
+	// filter решает, какие строки вывода доходят до терминала.
+	filter *outputFilter
+
+	// timestamp — метка времени строк вывода, заданная для всех; format.timestamp
+	// у самой команды сильнее.
+	timestamp flow.TimestampMode
+
+	// before и onExit переносятся в chainExecutor при сборке, как и keepGoing.
+	before []flow.Command
+	onExit []flow.Command
 }
 
 // Option настраивает менеджер при создании.
@@ -95,6 +118,30 @@ func WithMaxParallel(n int) Option {
 	return func(m *Manager) { m.maxParallel = n }
 }
 
+// WithEvents направляет события запуска в приёмник: старт и выход процессов,
+// перезапуски, готовность и итог цепочек, эскалацию сигналов.
+func WithEvents(sink EventSink) Option {
+	return func(m *Manager) { m.events = sink }
+}
+
+// WithTimestamp ставит метку времени перед каждой строкой вывода команд.
+// Поле format.timestamp у команды перекрывает это значение.
+func WithTimestamp(mode flow.TimestampMode) Option {
+	return func(m *Manager) { m.timestamp = mode }
+}
+
+// WithBefore задаёт команды, выполняемые по очереди до старта цепочек.
+// Отказ любой из них отменяет запуск с её кодом выхода.
+func WithBefore(cmds []flow.Command) Option {
+	return func(m *Manager) { m.before = cmds }
+}
+
+// WithOnExit задаёт команды, выполняемые один раз после всех цепочек —
+// и после отказа, и после Ctrl+C.
+func WithOnExit(cmds []flow.Command) Option {
+	return func(m *Manager) { m.onExit = cmds }
+}
+
 func WithTimeouts(t Timeouts) Option {
 	return func(m *Manager) { m.timeouts = t.normalize() }
 }
@@ -106,6 +153,14 @@ func NewManager(logger ui.Logger, formatter *ui.OutputFormatter, opts ...Option)
 		procs:    newProcessRegistry(),
 		output:   formatter,
 		timeouts: DefaultTimeouts(),
+		logs:     newChainLogs(logger),
+		filter:   newOutputFilter(),
+	}
+
+	m.lines, _ = logger.(ui.LineLogger)
+
+	if grouped, ok := logger.(ui.GroupLogger); ok {
+		m.groups = newChainGroups(grouped)
 	}
 
 	m.shutdownSig.Store(defaultShutdownSignal())
@@ -123,6 +178,22 @@ func NewManager(logger ui.Logger, formatter *ui.OutputFormatter, opts ...Option)
 		chainOpts = append(chainOpts, withMaxParallel(m.maxParallel))
 	}
 
+	if m.events != nil {
+		chainOpts = append(chainOpts, withEvents(m.events))
+	}
+
+	if m.groups != nil {
+		chainOpts = append(chainOpts, withChainDone(m.groups.close))
+	}
+
+	if len(m.before) > 0 {
+		chainOpts = append(chainOpts, withBefore(m.before))
+	}
+
+	if len(m.onExit) > 0 {
+		chainOpts = append(chainOpts, withOnExit(m.onExit))
+	}
+
 	m.chains = newChainExecutor(logger, m, m.stopAllCommands, chainOpts...)
 
 	return m
@@ -169,6 +240,14 @@ func (m *Manager) KillAll() {
 		return
 	}
 
+	for _, p := range m.procs.list() {
+		if p.cmd != nil && p.cmd.Process != nil {
+			emitEvent(m.events, Event{
+				Kind: EventForceKilled, Chain: p.chain, Command: p.command, PID: p.cmd.Process.Pid,
+			})
+		}
+	}
+
 	m.procs.killAll(m.lgr)
 }
 
@@ -203,7 +282,7 @@ func (m *Manager) supervise(
 	waitErr := make(chan error, 1)
 	waitDone := make(chan struct{})
 
-	m.procs.add(cmdKey, cmd, waitDone)
+	m.procs.addOwned(cmdKey, chainName, command.DisplayName(), cmd, waitDone)
 	defer m.procs.remove(cmdKey)
 
 	go func() {
@@ -212,28 +291,55 @@ func (m *Manager) supervise(
 		close(waitDone)
 	}()
 
+	var err error
+
 	select {
 	case <-ctx.Done():
-		m.stopCommand(cmd, command, waitDone, abandonOutput)
+		// Событие таймаута идёт до остановки: потребителю важно, почему
+		// команду снимают, раньше, чем то, как она умерла.
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			emitEvent(m.events, Event{
+				Kind: EventCommandTimeout, Chain: chainName, Command: command.DisplayName(), PID: cmd.Process.Pid,
+			})
+		}
 
-		return ctx.Err()
+		m.stopCommand(cmd, chainName, command, waitDone, abandonOutput)
+
+		err = ctx.Err()
 
 	case <-waitDone:
-		return <-waitErr
+		err = <-waitErr
 	}
+
+	emitEvent(m.events, Event{
+		Kind:     EventCommandExited,
+		Chain:    chainName,
+		Command:  command.DisplayName(),
+		PID:      cmd.Process.Pid,
+		ExitCode: exitCodeOf(cmd),
+	})
+
+	return err
 }
 
 // stopCommand останавливает команду по отмене контекста: сигнал группе, затем
 // убийство по таймауту, затем — в крайнем случае — отказ от чтения вывода.
 func (m *Manager) stopCommand(
 	cmd *exec.Cmd,
+	chainName string,
 	command flow.Command,
 	waitDone <-chan struct{},
 	abandonOutput func(),
 ) {
 	m.lgr.Info("Context canceled, stopping command", ui.F("cmd", command.Cmd))
 
-	if err := sendSignalToGroup(cmd, m.getShutdownSignal()); err != nil {
+	sig := m.getShutdownSignal()
+	emitEvent(m.events, Event{
+		Kind: EventSignalSent, Chain: chainName, Command: command.DisplayName(), PID: cmd.Process.Pid,
+		Signal: signalName(sig),
+	})
+
+	if err := sendSignalToGroup(cmd, sig); err != nil {
 		m.lgr.Warn("Failed to send shutdown signal to process group", ui.F("err", err), ui.F("cmd", command.Cmd))
 	}
 
@@ -244,6 +350,9 @@ func (m *Manager) stopCommand(
 	}
 
 	m.lgr.Warn("Force killing command group", ui.F("cmd", command.Cmd))
+	emitEvent(m.events, Event{
+		Kind: EventForceKilled, Chain: chainName, Command: command.DisplayName(), PID: cmd.Process.Pid,
+	})
 
 	if err := killProcessGroup(cmd); err != nil {
 		m.lgr.Warn("Failed to kill process group", ui.F("err", err), ui.F("cmd", command.Cmd))
@@ -268,6 +377,13 @@ func (m *Manager) stopCommand(
 	<-waitDone
 }
 
+// emitStarted сообщает о запущенном процессе команды.
+func (m *Manager) emitStarted(chain *flow.CommandChain, command flow.Command, cmd *exec.Cmd) {
+	emitEvent(m.events, Event{
+		Kind: EventCommandStarted, Chain: chainName(chain), Command: command.DisplayName(), PID: cmd.Process.Pid,
+	})
+}
+
 // commandTimeout возвращает предел для конкретной команды: собственный, если
 // задан, иначе общий из флага. Ноль означает «без предела».
 func (m *Manager) commandTimeout(command flow.Command) time.Duration {
@@ -302,14 +418,22 @@ func (m *Manager) withCommandDeadline(
 //
 // Различие берётся из контекста команды, а не из текста ошибки: дедлайн даёт
 // DeadlineExceeded, отмена родителя — Canceled, и перепутать их нельзя.
+//
+// stderr — хвост вывода команды в stderr: он едет в ошибке до отчёта.
 func (m *Manager) stopError(
-	runCtx context.Context, chain *flow.CommandChain, command flow.Command, limit time.Duration, err error,
+	runCtx context.Context,
+	chain *flow.CommandChain,
+	command flow.Command,
+	limit time.Duration,
+	err error,
+	stderr string,
 ) error {
 	if limit > 0 && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
 		return &TimeoutError{
 			Chain:   chainName(chain),
 			Command: command.DisplayName(),
 			Limit:   limit,
+			Stderr:  stderr,
 		}
 	}
 
@@ -317,7 +441,7 @@ func (m *Manager) stopError(
 		return err
 	}
 
-	return m.completionError(chain, command, err)
+	return m.completionError(chain, command, err, stderr)
 }
 
 func (m *Manager) Execute(ctx context.Context, chain *flow.CommandChain, command flow.Command) error {
@@ -340,6 +464,7 @@ func (m *Manager) Execute(ctx context.Context, chain *flow.CommandChain, command
 	}
 
 	m.lgr.Info("Command started: " + ui.FullDisplayName(chainName(chain), command))
+	m.emitStarted(chain, command, cmd)
 
 	runCtx, cancel, limit := m.withCommandDeadline(ctx, command)
 	defer cancel()
@@ -351,7 +476,7 @@ func (m *Manager) Execute(ctx context.Context, chain *flow.CommandChain, command
 		// команда встала, а молчащий отказ не объясняет ничего.
 		m.printBlock(chain, command, stdoutBuf.Bytes(), stderrBuf.Bytes())
 
-		return m.stopError(runCtx, chain, command, limit, err)
+		return m.stopError(runCtx, chain, command, limit, err, tailOf(stderrBuf.Bytes(), stderrTailLimit))
 	}
 
 	m.printBlock(chain, command, stdoutBuf.Bytes(), stderrBuf.Bytes())
@@ -362,17 +487,108 @@ func (m *Manager) Execute(ctx context.Context, chain *flow.CommandChain, command
 // printBlock печатает результат не-pipe команды, сохраняя разделение потоков:
 // stdout выводится обычным блоком, непустой stderr — отдельным блоком ошибки.
 func (m *Manager) printBlock(chain *flow.CommandChain, command flow.Command, stdout, stderr []byte) {
+	m.logBlock(chain, stdout)
+	m.logBlock(chain, stderr)
+
+	stdout = m.filter.keep(chainName(chain), stdout)
+	stderr = m.filter.keep(chainName(chain), stderr)
+
+	if m.lines != nil {
+		m.printLines(chain, command, stdout, stderr)
+
+		return
+	}
+
+	lgr := m.outputLogger(chain)
 	output := m.output.FormatChainInfo(chain, command)
 
+	// Блок печатается по завершении команды, и метка у него одна — время
+	// печати: разложить по строкам вывод, собранный целиком, уже нечем.
+	stamp := m.stamper(chain, command)()
+
 	if len(stdout) > 0 {
-		m.lgr.Blocks(output.Header, output.CmdName, indentBlock(stdout))
+		lgr.Blocks(stamped(stamp, output.Header, output.CmdName, indentBlock(stdout))...)
 	}
 
 	if len(stderr) > 0 {
-		m.lgr.ErrorBlocks(errors.New(indentBlock(stderr)), output.Header, output.CmdName)
+		lgr.ErrorBlocks(errors.New(indentBlock(stderr)), stamped(stamp, output.Header, output.CmdName)...)
 	}
 }
 
+// outputLogger возвращает логгер для вывода команд цепочки: группу цепочки в
+// режиме -output grouped и общий логгер иначе.
+func (m *Manager) outputLogger(chain *flow.CommandChain) ui.Logger {
+	if m.groups == nil {
+		return m.lgr
+	}
+
+	return m.groups.of(chainName(chain))
+}
+
+// stamper возвращает функцию метки времени для строк команды.
+//
+// Начало цепочки берётся один раз, а не на каждую строку: состояние запуска
+// живёт под мьютексом, а строк бывают сотни тысяч. Вне запуска отсчёт идёт
+// от начала вывода команды.
+func (m *Manager) stamper(chain *flow.CommandChain, command flow.Command) func() string {
+	mode := command.Format.Timestamp.Or(m.timestamp)
+	if !mode.Enabled() {
+		return func() string { return "" }
+	}
+
+	start := m.chains.startedAt(chainName(chain))
+	if start.IsZero() {
+		start = time.Now()
+	}
+
+	return func() string { return ui.Timestamp(mode, time.Now(), start) }
+}
+
+// stamped ставит метку времени первым блоком, если она есть.
+func stamped(stamp string, blocks ...string) []string {
+	if stamp == "" {
+		return blocks
+	}
+
+	return append([]string{stamp}, blocks...)
+}
+
+// logBlock дописывает собранный вывод не-pipe команды в журнал цепочки.
+func (m *Manager) logBlock(chain *flow.CommandChain, data []byte) {
+	if chain == nil || chain.Log == nil || len(data) == 0 {
+		return
+	}
+
+	for line := range bytes.SplitSeq(bytes.TrimSuffix(data, newlineBytes), newlineBytes) {
+		m.logs.write(chain, string(line))
+	}
+}
+
+// printLines отдаёт вывод не-pipe команды построчно: сначала stdout, затем
+// stderr. Порядок между потоками здесь не восстановить — буферы раздельные.
+func (m *Manager) printLines(chain *flow.CommandChain, command flow.Command, stdout, stderr []byte) {
+	seq := 0
+
+	for _, stream := range []struct {
+		name string
+		data []byte
+	}{{ui.StreamStdout, stdout}, {ui.StreamStderr, stderr}} {
+		if len(stream.data) == 0 {
+			continue
+		}
+
+		for line := range bytes.SplitSeq(bytes.TrimSuffix(stream.data, newlineBytes), newlineBytes) {
+			m.lines.Line(outputLine(chain, command, stream.name, seq, string(line)))
+			seq++
+		}
+	}
+}
+
+// outputLine собирает разобранную строку вывода команды.
+func outputLine(chain *flow.CommandChain, command flow.Command, stream string, seq int, line string) ui.OutputLine {
+	return ui.OutputLine{Chain: chainName(chain), Command: command.DisplayName(), Stream: stream, Seq: seq, Line: line}
+}
+
 // indentBlock форматирует многострочный вывод с отступом для читаемости.
 //
 // Раньше строка накапливалась конкатенацией в цикле, то есть на каждой итерации
@@ -424,6 +640,7 @@ func (m *Manager) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain,
 	}
 
 	m.lgr.Info("Command started: " + ui.FullDisplayName(chainName(chain), command))
+	m.emitStarted(chain, command, cmd)
 
 	// Контекст чтения вывода намеренно НЕ производный от ctx: отмена ctx
 	// означает «останови команду», а не «перестань читать её вывод». Чтение
@@ -441,7 +658,8 @@ func (m *Manager) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain,
 		_ = stderr.Close()
 	}
 
-	wg := m.streamPipes(outputCtx, chain, command, stdout, stderr)
+	stderrTail := newTailBuffer(stderrTailLimit)
+	wg := m.streamPipes(outputCtx, chain, command, stdout, stderr, stderrTail)
 
 	runCtx, cancel, limit := m.withCommandDeadline(ctx, command)
 	defer cancel()
@@ -455,19 +673,21 @@ func (m *Manager) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain,
 	}
 
 	if err := m.supervise(runCtx, cmd, chainName(chain), command, waitFn, abandonOutput); err != nil {
-		return m.stopError(runCtx, chain, command, limit, err)
+		return m.stopError(runCtx, chain, command, limit, err, stderrTail.String())
 	}
 
 	return nil
 }
 
 // streamPipes запускает две горутины чтения stdout/stderr и возвращает WaitGroup,
-// по которой можно дождаться завершения обработки вывода.
+// по которой можно дождаться завершения обработки вывода. Строки stderr
+// дополнительно оседают в stderrTail — для отчёта об отказе.
 func (m *Manager) streamPipes(
 	ctx context.Context,
 	chain *flow.CommandChain,
 	command flow.Command,
 	stdout, stderr io.ReadCloser,
+	stderrTail *tailBuffer,
 ) *sync.WaitGroup {
 	var wg sync.WaitGroup
 
@@ -480,19 +700,61 @@ func (m *Manager) streamPipes(
 	// без запуска он выходит по nil-указателю.
 	name := chainName(chain)
 
+	// Номер строки общий для обоих потоков: счётчики HandleOutput у каждого
+	// потока свои, а потребителю JSON нужен единый порядок.
+	var seq atomic.Int64
+
+	stamp := m.stamper(chain, command)
+	lgr := m.outputLogger(chain)
+
 	stdoutHandler := func(chainNameStyleText, cmdName, content string, counter int) {
 		m.chains.observeLine(name, content)
+		m.logs.write(chain, content)
+
+		if !m.filter.shows(name, content) {
+			return
+		}
+
+		if m.lines != nil {
+			m.lines.Line(outputLine(chain, command, ui.StreamStdout, int(seq.Add(1)-1), content))
+
+			return
+		}
 
 		cmdNameStyled := fmt.Sprintf(`%s (%d) %s`, cmdName, counter, div)
-		m.lgr.Blocks(chainNameStyleText, cmdNameStyled, content)
+		if ts := stamp(); ts != "" {
+			lgr.Blocks(ts, chainNameStyleText, cmdNameStyled, content)
+
+			return
+		}
+
+		lgr.Blocks(chainNameStyleText, cmdNameStyled, content)
 	}
 
 	stderrHandler := func(chainNameStyleText, cmdName, content string, counter int) {
 		// Готовность часто печатается именно в stderr — туда пишут журналы
 		// многие серверы.
 		m.chains.observeLine(name, content)
+		stderrTail.writeLine(content)
+		m.logs.write(chain, content)
+
+		if !m.filter.shows(name, content) {
+			return
+		}
+
+		if m.lines != nil {
+			m.lines.Line(outputLine(chain, command, ui.StreamStderr, int(seq.Add(1)-1), content))
+
+			return
+		}
+
+		if ts := stamp(); ts != "" {
+			lgr.ErrorBlocks(errors.New(content), ts, chainNameStyleText, cmdName)
+
+			return
+		}
 
-		m.lgr.ErrorBlocks(errors.New(content), chainNameStyleText, cmdName)
+		lgr.ErrorBlocks(errors.New(content), chainNameStyleText, cmdName)
 	}
 
 	wg.Go(func() {
@@ -526,7 +788,9 @@ func startError(command flow.Command, err error) error {
 }
 
 // completionError переводит отказ команды в ошибку с кодом выхода.
-func (m *Manager) completionError(chain *flow.CommandChain, command flow.Command, waitErr error) error {
+func (m *Manager) completionError(
+	chain *flow.CommandChain, command flow.Command, waitErr error, stderr string,
+) error {
 	// ExitCode() вместо syscall.WaitStatus: портируемо и не тянет платформенный
 	// пакет в кросс-платформенный файл.
 	var exitErr *exec.ExitError
@@ -534,7 +798,7 @@ func (m *Manager) completionError(chain *flow.CommandChain, command flow.Command
 		code := exitErr.ExitCode()
 		m.lgr.Error(nil, "Command failed", ui.F("Exit Status", code))
 
-		return &ExitError{Chain: chainName(chain), Command: command.DisplayName(), Code: code}
+		return &ExitError{Chain: chainName(chain), Command: command.DisplayName(), Code: code, Stderr: stderr}
 	}
 
 	m.lgr.Error(waitErr, "command failed")
@@ -543,6 +807,11 @@ func (m *Manager) completionError(chain *flow.CommandChain, command flow.Command
 }
 
 func (m *Manager) ExecuteParallel(ctx context.Context, chains []*flow.CommandChain) error {
+	// Журналы закрываются, а недопечатанные группы печатаются, когда все
+	// команды уже дождались: строк больше не будет.
+	defer m.logs.closeAll()
+	defer m.groups.closeAll()
+
 	return m.chains.ExecuteParallel(ctx, chains)
 }
 
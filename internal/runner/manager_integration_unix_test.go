@@ -3,12 +3,18 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
 )
 
 // shCommand собирает команду, выполняющую скрипт через sh, вместе с её цепочкой.
@@ -200,3 +206,194 @@ func TestManager_ExecuteParallelPropagatesFailure(t *testing.T) {
 		t.Fatalf("expected ExecuteParallel to return failure, got nil")
 	}
 }
+
+func TestManager_FailureKeepsStderrTail(t *testing.T) {
+	requireIntegration(t)
+
+	for _, pipe := range []bool{false, true} {
+		mgr := newTestManager(t)
+
+		chain, cmd := shCommand("stderr", "echo out; echo first 1>&2; echo second 1>&2; exit 4", pipe)
+
+		execute := mgr.Execute
+		if pipe {
+			execute = mgr.ExecuteWithPipe
+		}
+
+		var exitErr *ExitError
+		if err := execute(t.Context(), chain, cmd); !errors.As(err, &exitErr) {
+			t.Fatalf("pipe=%v: ожидалась ExitError, получено %v", pipe, err)
+		}
+
+		if exitErr.Stderr != "first\nsecond\n" {
+			t.Fatalf("pipe=%v: в ошибке должен быть stderr команды без stdout, получено %q", pipe, exitErr.Stderr)
+		}
+	}
+}
+
+func TestManager_JSONOutputLines(t *testing.T) {
+	requireIntegration(t)
+
+	for _, pipe := range []bool{false, true} {
+		var buf bytes.Buffer
+
+		out := ui.NewOutput(&buf, ui.WithOutputMode(ui.OutputJSON))
+		mgr := NewManager(out.Logger(), out.Formatter(), WithTimeouts(testTimeouts))
+
+		chain, cmd := shCommand("json", "echo one; echo two 1>&2", pipe)
+
+		execute := mgr.Execute
+		if pipe {
+			execute = mgr.ExecuteWithPipe
+		}
+
+		if err := execute(t.Context(), chain, cmd); err != nil {
+			t.Fatalf("pipe=%v: %v", pipe, err)
+		}
+
+		_ = out.Close()
+
+		var got []ui.OutputLine
+
+		for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+			var rec struct {
+				Chain, Command, Stream, Line string
+				Seq                          int
+			}
+
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				t.Fatalf("pipe=%v: строка вывода не JSON: %q", pipe, line)
+			}
+
+			if rec.Stream != "" {
+				got = append(got, ui.OutputLine{
+					Chain: rec.Chain, Command: rec.Command, Stream: rec.Stream, Seq: rec.Seq, Line: rec.Line,
+				})
+			}
+		}
+
+		if len(got) != 2 || got[0].Seq == got[1].Seq {
+			t.Fatalf("pipe=%v: ожидались две строки с разными seq, получено %+v", pipe, got)
+		}
+
+		for _, line := range got {
+			want := map[string]string{ui.StreamStdout: "one", ui.StreamStderr: "two"}[line.Stream]
+			if line.Chain != "chain-json" || line.Command != "json" || line.Line != want {
+				t.Fatalf("pipe=%v: неверная строка %+v", pipe, line)
+			}
+		}
+	}
+}
+
+// TestManager_TimestampPrefix — метка, заданная для всех, стоит перед каждой
+// строкой потока, а format.timestamp команды её перекрывает.
+func TestManager_TimestampPrefix(t *testing.T) {
+	requireIntegration(t)
+
+	stamp := regexp.MustCompile(`\+00:00\.\d{3}`)
+
+	for _, own := range []flow.TimestampMode{"", flow.TimestampNone} {
+		var buf bytes.Buffer
+
+		out := ui.NewOutput(&buf, ui.WithoutColor())
+		mgr := NewManager(out.Logger(), out.Formatter(), WithTimeouts(testTimeouts),
+			WithTimestamp(flow.TimestampElapsed))
+
+		chain, cmd := shCommand("stamp", "echo one; echo two 1>&2", true)
+		cmd.Format.Timestamp = own
+
+		if err := mgr.ExecuteWithPipe(t.Context(), chain, cmd); err != nil {
+			t.Fatalf("%q: %v", own, err)
+		}
+
+		_ = out.Close()
+
+		got := len(stamp.FindAllString(buf.String(), -1))
+		if want := map[flow.TimestampMode]int{"": 2, flow.TimestampNone: 0}[own]; got != want {
+			t.Fatalf("%q: ожидалось меток %d, получено %d:\n%s", own, want, got, buf.String())
+		}
+	}
+}
+
+// TestManager_GroupedOutput — в режиме grouped строки цепочек, шедшие
+// вперемешку, печатаются каждая своим куском.
+func TestManager_GroupedOutput(t *testing.T) {
+	requireIntegration(t)
+
+	var buf bytes.Buffer
+
+	out := ui.NewOutput(&buf, ui.WithoutColor(), ui.WithOutputMode(ui.OutputGrouped))
+	mgr := NewManager(out.Logger(), out.Formatter(), WithTimeouts(testTimeouts))
+
+	a, _ := shCommand("a", "for i in 1 2 3; do echo a-line; sleep 0.05; done", true)
+	b, _ := shCommand("b", "for i in 1 2 3; do echo b-line; sleep 0.05; done", true)
+
+	if err := mgr.ExecuteParallel(t.Context(), []*flow.CommandChain{a, b}); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	_ = out.Close()
+
+	var order []string
+
+	for line := range strings.SplitSeq(buf.String(), "\n") {
+		for _, mark := range []string{"a-line", "b-line"} {
+			if strings.HasSuffix(strings.TrimSpace(line), "> "+mark) {
+				order = append(order, mark)
+			}
+		}
+	}
+
+	if len(order) != 6 || order[0] != order[1] || order[1] != order[2] || order[3] != order[4] ||
+		order[4] != order[5] || order[2] == order[3] {
+		t.Fatalf("строки цепочек перемешаны: %v\n%s", order, buf.String())
+	}
+}
+
+// TestManager_MutedChainStillReady — заглушённая цепочка не видна в выводе,
+// но её logLine срабатывает и журнал пишется: фильтр только про экран.
+func TestManager_MutedChainStillReady(t *testing.T) {
+	requireIntegration(t)
+
+	var buf bytes.Buffer
+
+	rec := &eventRecorder{}
+	out := ui.NewOutput(&buf, ui.WithoutColor())
+	mgr := NewManager(out.Logger(), out.Formatter(), WithTimeouts(testTimeouts), WithEvents(rec))
+
+	path := t.TempDir() + "/db.log"
+	db := &flow.CommandChain{Name: "db", Log: &flow.LogFile{Path: path}}
+	db.Add(flow.Command{
+		Name: "db", Cmd: "sh", Args: []string{"-c", "printf 'db-%s\\n' up; sleep 5"}, Pipe: true,
+		Ready: &flow.ReadyCondition{LogLine: "db-up", Timeout: 5 * time.Second},
+	})
+
+	mgr.filter.update(func(r *filterRules) { r.muted = toggleSet(r.muted, "db", true) })
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- mgr.ExecuteParallel(ctx, []*flow.CommandChain{db}) }()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !slices.Contains(rec.kinds("db"), EventChainReady) {
+		if time.Now().After(deadline) {
+			t.Fatal("logLine заглушённой цепочки не сработал")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	_ = out.Close()
+
+	if strings.Contains(buf.String(), "db-up") {
+		t.Fatalf("вывод заглушённой цепочки попал на экран:\n%s", buf.String())
+	}
+
+	if got := readLog(t, path); got != "db-up\n" {
+		t.Fatalf("журнал цепочки = %q, ожидалась её строка", got)
+	}
+}
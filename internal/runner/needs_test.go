@@ -0,0 +1,194 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+// needChain собирает цепочку, ждущую предшественников до заданных условий.
+func needChain(name string, needs map[string]flow.NeedCondition, order ...string) *flow.CommandChain {
+	chain := depChain(name, order, nil)
+	chain.NeedConditions = needs
+
+	return chain
+}
+
+// TestExecuteParallel_NeedStarted — started не ждёт ни готовности, ни
+// завершения: зависимый стартует, пока предшественник ещё работает.
+func TestExecuteParallel_NeedStarted(t *testing.T) {
+	runner := newRecordingRunner()
+	runner.hold["db"] = 300 * time.Millisecond
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil)
+
+	chains := []*flow.CommandChain{
+		depChain("db", nil, nil),
+		needChain("logs", map[string]flow.NeedCondition{"db": flow.NeedStarted}, "db"),
+	}
+
+	if err := exec.ExecuteParallel(t.Context(), chains); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	if delay := runner.startedAt("logs").Sub(runner.startedAt("db")); delay > 150*time.Millisecond {
+		t.Errorf("logs ждал %s — похоже, ждали завершения db", delay)
+	}
+}
+
+// TestExecuteParallel_NeedCompletedAfterFailure — уборка идёт после
+// предшественника с любым исходом, а completed_successfully после отказа
+// пропускается.
+func TestExecuteParallel_NeedCompletedAfterFailure(t *testing.T) {
+	runner := newRecordingRunner()
+	runner.failing["test"] = errFakeA
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withKeepGoing())
+
+	chains := []*flow.CommandChain{
+		depChain("test", nil, nil),
+		needChain("cleanup", map[string]flow.NeedCondition{"test": flow.NeedCompleted}, "test"),
+		needChain("report", map[string]flow.NeedCondition{"test": flow.NeedCompletedSuccessfully}, "test"),
+	}
+
+	_ = exec.ExecuteParallel(t.Context(), chains)
+
+	if runner.startedAt("cleanup").IsZero() || exec.results[1].Skipped {
+		t.Errorf("cleanup не запустился после упавшего test: %+v", exec.results[1])
+	}
+
+	if !runner.startedAt("report").IsZero() || !exec.results[2].Skipped {
+		t.Errorf("report запустился после упавшего test: %+v", exec.results[2])
+	}
+}
+
+// TestExecuteParallel_NeedCompletedAfterSkip — пропущенная цепочка уже не
+// запустится, и уборке после неё ждать нечего.
+func TestExecuteParallel_NeedCompletedAfterSkip(t *testing.T) {
+	runner := newRecordingRunner()
+	runner.failing["db"] = errFakeA
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withKeepGoing())
+
+	chains := []*flow.CommandChain{
+		depChain("db", nil, nil),
+		depChain("api", []string{"db"}, nil),
+		needChain("cleanup", map[string]flow.NeedCondition{"api": flow.NeedCompleted}, "api"),
+	}
+
+	_ = exec.ExecuteParallel(t.Context(), chains)
+
+	if !exec.results[1].Skipped || runner.startedAt("cleanup").IsZero() {
+		t.Errorf("api = %+v, cleanup запущен: %v", exec.results[1], !runner.startedAt("cleanup").IsZero())
+	}
+}
+
+// TestExecuteParallel_NeedCompletedSuccessfullyIgnoresReady — у сервера
+// есть условие готовности, но зависимый просил дождаться завершения.
+func TestExecuteParallel_NeedCompletedSuccessfullyIgnoresReady(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	defer ln.Close()
+
+	runner := newRecordingRunner()
+	runner.hold["db"] = 200 * time.Millisecond
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil)
+
+	ready := &flow.ReadyCondition{TCP: ln.Addr().String(), Timeout: 5 * time.Second}
+	chains := []*flow.CommandChain{
+		depChain("db", nil, ready),
+		needChain("api", nil, "db"),
+		needChain("seed", map[string]flow.NeedCondition{"db": flow.NeedCompletedSuccessfully}, "db"),
+	}
+
+	if err := exec.ExecuteParallel(t.Context(), chains); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	dbDone := runner.startedAt("db").Add(200 * time.Millisecond)
+
+	if apiStart := runner.startedAt("api"); !apiStart.Before(dbDone) {
+		t.Errorf("api ждал завершения db, хотя ему нужна только готовность")
+	}
+
+	if seedStart := runner.startedAt("seed"); seedStart.Before(dbDone) {
+		t.Errorf("seed стартовал раньше завершения db: %s против %s", seedStart, dbDone)
+	}
+}
+
+// commandRunner держит, роняет и запоминает команды под именем chain.command:
+// recordingRunner различает только цепочки, а здесь важна каждая команда.
+type commandRunner struct {
+	*recordingRunner
+}
+
+func (r commandRunner) Execute(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	return r.recordingRunner.Execute(ctx, &flow.CommandChain{Name: flow.CommandNode(chain.Name, cmd.Name)}, cmd)
+}
+
+func (r commandRunner) ExecuteWithPipe(ctx context.Context, chain *flow.CommandChain, cmd flow.Command) error {
+	return r.Execute(ctx, chain, cmd)
+}
+
+// migrateThenServe — цепочка db: короткая миграция и долгий сервер за ней.
+func migrateThenServe() *flow.CommandChain {
+	db := &flow.CommandChain{Name: "db"}
+	db.Add(flow.Command{Name: "migrate", Cmd: "echo"})
+	db.Add(flow.Command{Name: "serve", Cmd: "echo"})
+
+	return db
+}
+
+// TestExecuteParallel_NeedCommand — ради этого задача и делалась: api ждёт
+// миграцию, а не всю цепочку db с долгоживущим сервером.
+func TestExecuteParallel_NeedCommand(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.hold["db.serve"] = 300 * time.Millisecond
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil)
+
+	chains := []*flow.CommandChain{migrateThenServe(), depChain("api", []string{"db.migrate"}, nil)}
+
+	if err := exec.ExecuteParallel(t.Context(), chains); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	apiStart, serveStart := runner.startedAt("api.api-cmd"), runner.startedAt("db.serve")
+	if apiStart.Before(runner.startedAt("db.migrate")) || !apiStart.Before(serveStart.Add(200*time.Millisecond)) {
+		t.Errorf("api стартовал не после миграции: api %s, serve %s", apiStart, serveStart)
+	}
+}
+
+// TestExecuteParallel_NeedCommandFailed — упавшая миграция пропускает
+// зависимых, а команда, до которой цепочка не дошла, считается завершённой.
+func TestExecuteParallel_NeedCommandFailed(t *testing.T) {
+	runner := commandRunner{newRecordingRunner()}
+	runner.failing["db.migrate"] = errFakeA
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), runner, nil, withKeepGoing())
+
+	chains := []*flow.CommandChain{
+		migrateThenServe(),
+		depChain("api", []string{"db.migrate"}, nil),
+		needChain("cleanup", map[string]flow.NeedCondition{"db.serve": flow.NeedCompleted}, "db.serve"),
+	}
+
+	_ = exec.ExecuteParallel(t.Context(), chains)
+
+	if !exec.results[1].Skipped || !errors.Is(exec.results[1].Err, ErrDependencyFailed) {
+		t.Errorf("api = %+v", exec.results[1])
+	}
+
+	if !runner.startedAt("db.serve").IsZero() || runner.startedAt("cleanup.cleanup-cmd").IsZero() {
+		t.Error("serve должен быть пропущен, а cleanup — запущен")
+	}
+}
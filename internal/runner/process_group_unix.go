@@ -61,3 +61,15 @@ func killProcessGroup(cmd *exec.Cmd) error {
 
 	return unix.Kill(-pgid, unix.SIGKILL)
 }
+
+// signalName возвращает имя сигнала в привычном виде — SIGTERM, а не
+// «terminated», как его описывает String.
+func signalName(sig os.Signal) string {
+	if s, ok := sig.(syscall.Signal); ok {
+		if name := unix.SignalName(s); name != "" {
+			return name
+		}
+	}
+
+	return sig.String()
+}
@@ -61,3 +61,7 @@ func killProcessGroup(cmd *exec.Cmd) error {
 
 	return cmd.Process.Kill()
 }
+
+// signalName возвращает то, что на деле доставляется группе: сам сигнал на
+// Windows игнорируется, см. sendSignalToGroup.
+func signalName(_ os.Signal) string { return "CTRL_BREAK" }
@@ -1,11 +1,19 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
+	"net/http"
+	"os"
 	"os/exec"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -29,6 +37,12 @@ const (
 	// readyDialTimeout — сколько ждать одного соединения, чтобы не залипнуть
 	// на неотвечающем адресе дольше самого интервала опроса.
 	readyDialTimeout = time.Second
+	// readyRequestTimeout — сколько ждать одного ответа HTTP. Больше, чем
+	// соединения: проверка здоровья нередко сама ходит в базу.
+	readyRequestTimeout = 2 * time.Second
+	// readyBodyLimit — сколько тела ответа читать ради сравнения. Страница
+	// здоровья короткая, а читать целиком отданный по ошибке файл незачем.
+	readyBodyLimit = 1 << 20
 )
 
 // gate — состояние готовности одной цепочки.
@@ -66,56 +80,218 @@ func (g *gate) wait(ctx context.Context) error {
 	}
 }
 
-// lineMatcher следит за появлением подстроки в выводе цепочки.
+// chainGates — гейты одной цепочки, по одному на условие needs.
+//
+// Зависимые ждут разного: сервер — до готовности, разовое задание — до
+// завершения, уборка — до завершения с любым исходом. Гейт у каждого
+// условия свой, и цепочка открывает их по мере того, как доходит до этапа.
+type chainGates struct {
+	started   *gate
+	ready     *gate
+	completed *gate
+	succeeded *gate
+}
+
+func newChainGates() *chainGates {
+	return &chainGates{started: newGate(), ready: newGate(), completed: newGate(), succeeded: newGate()}
+}
+
+// of возвращает гейт условия.
+func (g *chainGates) of(cond flow.NeedCondition) *gate {
+	switch cond {
+	case flow.NeedStarted:
+		return g.started
+	case flow.NeedCompleted:
+		return g.completed
+	case flow.NeedCompletedSuccessfully:
+		return g.succeeded
+	case flow.NeedReady:
+		return g.ready
+	default:
+		return g.ready
+	}
+}
+
+// openAll переводит все гейты цепочки в одно состояние.
+func (g *chainGates) openAll(err error) {
+	for _, one := range []*gate{g.started, g.ready, g.completed, g.succeeded} {
+		one.open(err)
+	}
+}
+
+// skip закрывает гейты цепочки, которая уже не запустится. completed
+// открывается успехом: цепочка больше не работает, и уборка после неё
+// должна пройти. Остальным ждать нечего.
+func (g *chainGates) skip(err error) {
+	g.completed.open(nil)
+	g.openAll(err)
+}
+
+// lineMatcher следит за появлением подстроки либо строки, подходящей под
+// выражение, в выводе цепочки.
 type lineMatcher struct {
 	substr string
+	re     *regexp.Regexp
 	found  chan struct{}
 	once   sync.Once
+	// export принимает именованные группы совпавшей строки.
+	export func(vars map[string]string)
 }
 
 func (m *lineMatcher) observe(line string) {
-	if strings.Contains(line, m.substr) {
-		m.once.Do(func() { close(m.found) })
+	if m.re == nil {
+		if strings.Contains(line, m.substr) {
+			m.once.Do(func() { close(m.found) })
+		}
+
+		return
+	}
+
+	match := m.re.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+
+	// Переменные выставляются до сигнала: зависимый, дождавшийся гейта,
+	// должен уже видеть их значения.
+	m.once.Do(func() {
+		m.export(captures(m.re, match))
+		close(m.found)
+	})
+}
+
+// captures собирает именованные группы совпадения.
+func captures(re *regexp.Regexp, match []string) map[string]string {
+	vars := make(map[string]string)
+
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			vars[name] = match[i]
+		}
 	}
+
+	return vars
 }
 
 // readySet хранит ожидания готовности всех цепочек запуска.
 //
-// Наблюдатели строк держатся отдельно: их дёргает слой вывода на каждой
-// строке, и делать это под общим замком означало бы сериализовать весь вывод.
+// Гейты и наблюдатели строк живут под одним RWMutex: на каждой строке вывода
+// берётся только чтение, а запись нужна лишь при добавлении цепочки во время
+// запуска, то есть при перечитывании конфигурации.
+//
+// Там же — переменные цепочек: именованные группы logLine, которые зависимые
+// подставляют как ${chain.var}.
 type readySet struct {
-	gates map[string]*gate
-
 	mu       sync.RWMutex
+	gates    map[string]*chainGates
+	commands map[string]*chainGates
 	matchers map[string][]*lineMatcher
+	exports  map[string]map[string]string
+
+	// watching сообщает о каждом зарегистрированном наблюдателе строк.
+	watching func(chainName string)
 }
 
 func newReadySet(chains []*flow.CommandChain) *readySet {
 	set := &readySet{
-		gates:    make(map[string]*gate, len(chains)),
+		gates:    make(map[string]*chainGates, len(chains)),
+		commands: make(map[string]*chainGates),
 		matchers: make(map[string][]*lineMatcher),
+		exports:  make(map[string]map[string]string),
 	}
 
 	for _, chain := range chains {
-		set.gates[chain.Name] = newGate()
+		set.gates[chain.Name] = newChainGates()
+		set.addCommands(chain)
 	}
 
 	return set
 }
 
-// gateOf возвращает гейт цепочки; для неизвестного имени — уже открытый,
-// чтобы отбор подмножества не приводил к вечному ожиданию.
-func (s *readySet) gateOf(name string) *gate {
-	if g, ok := s.gates[name]; ok {
+// gatesOf возвращает гейты цепочки либо команды chain.command; для
+// неизвестного имени — уже открытые, чтобы отбор подмножества не приводил к
+// вечному ожиданию. Имя цепочки побеждает, как и в flow.ValidateDeps.
+func (s *readySet) gatesOf(name string) *chainGates {
+	s.mu.RLock()
+	g, ok := s.gates[name]
+
+	if !ok {
+		g, ok = s.commands[name]
+	}
+	s.mu.RUnlock()
+
+	if ok {
 		return g
 	}
 
-	g := newGate()
-	g.open(nil)
+	g = newChainGates()
+	g.openAll(nil)
 
 	return g
 }
 
+// addChain заводит гейты цепочке, появившейся во время запуска.
+//
+// Гейты всегда новые, даже если цепочка с таким именем уже была: вернувшаяся
+// после удаления цепочка — это новый сервис, и его готовность надо дождаться
+// заново.
+func (s *readySet) addChain(chain *flow.CommandChain) {
+	s.mu.Lock()
+	s.gates[chain.Name] = newChainGates()
+	delete(s.exports, chain.Name)
+	s.mu.Unlock()
+
+	s.addCommands(chain)
+}
+
+// addCommands заводит гейты именованным командам цепочки. Отключённой
+// команде гейты не нужны: в запуске её нет, и ждущий её не должен стоять.
+func (s *readySet) addCommands(chain *flow.CommandChain) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cmd := range chain.Commands() {
+		if cmd.Name == "" {
+			continue
+		}
+
+		// Команду могли отключить при перечитывании: старый гейт не
+		// открылся бы никогда.
+		node := flow.CommandNode(chain.Name, cmd.Name)
+		if cmd.Disable {
+			delete(s.commands, node)
+		} else {
+			s.commands[node] = newChainGates()
+		}
+	}
+}
+
+// commandGates возвращает гейты команды. Без набора — вне параллельного
+// запуска — ждать команду некому, и гейты просто открыты.
+func commandGates(set *readySet, chain *flow.CommandChain, cmd flow.Command) *chainGates {
+	if set == nil || cmd.Name == "" {
+		g := newChainGates()
+		g.openAll(nil)
+
+		return g
+	}
+
+	return set.gatesOf(flow.CommandNode(chain.Name, cmd.Name))
+}
+
+// settleCommands закрывает гейты команд, которые не открылись сами: команда
+// не дошла до запуска или её условие готовности так и не выполнилось.
+// Причина — исход цепочки; completed открывается успехом, как у пропущенной.
+func (s *readySet) settleCommands(chain *flow.CommandChain, cause error) {
+	if chain == nil {
+		return
+	}
+
+	for _, cmd := range chain.Commands() {
+		commandGates(s, chain, cmd).skip(cause)
+	}
+}
+
 // observeLine раздаёт строку вывода наблюдателям цепочки.
 func (s *readySet) observeLine(chainName, line string) {
 	s.mu.RLock()
@@ -127,15 +303,93 @@ func (s *readySet) observeLine(chainName, line string) {
 	}
 }
 
-// addMatcher регистрирует ожидание строки и возвращает канал её появления.
-func (s *readySet) addMatcher(chainName, substr string) <-chan struct{} {
-	m := &lineMatcher{substr: substr, found: make(chan struct{})}
+// addMatcher регистрирует ожидание строки. Появление строки сообщает канал
+// found наблюдателя; снимает его removeMatcher.
+func (s *readySet) addMatcher(chainName string, ready *flow.ReadyCondition) *lineMatcher {
+	m := &lineMatcher{substr: ready.LogLine, found: make(chan struct{})}
+
+	// Выражение проверено при сборке конфигурации, так что ошибка здесь
+	// невозможна; если всё же случилась, строка просто не дождётся.
+	re, err := ready.LinePattern()
+	if err != nil {
+		return m
+	}
+
+	if re != nil {
+		m.re = re
+		m.export = func(vars map[string]string) { s.setExports(chainName, vars) }
+	}
 
 	s.mu.Lock()
 	s.matchers[chainName] = append(s.matchers[chainName], m)
 	s.mu.Unlock()
 
-	return m.found
+	if s.watching != nil {
+		s.watching(chainName)
+	}
+
+	return m
+}
+
+// removeMatcher снимает наблюдателя, когда его ожидание закончилось.
+//
+// Без этого наблюдатели копились бы с каждым перезапуском цепочки и
+// перечитыванием конфигурации, и каждая строка вывода проходила бы по всем.
+// Срез собирается заново, а не правится на месте: observeLine перебирает
+// прежний вне блокировки.
+func (s *readySet) removeMatcher(chainName string, m *lineMatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rest := slices.DeleteFunc(slices.Clone(s.matchers[chainName]), func(other *lineMatcher) bool {
+		return other == m
+	})
+
+	if len(rest) == 0 {
+		delete(s.matchers, chainName)
+
+		return
+	}
+
+	s.matchers[chainName] = rest
+}
+
+// setExports запоминает переменные цепочки. Значения повторного запуска
+// заменяют прежние: перезапущенный сервер мог выбрать другой порт.
+func (s *readySet) setExports(chainName string, vars map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exports[chainName] == nil {
+		s.exports[chainName] = make(map[string]string, len(vars))
+	}
+
+	maps.Copy(s.exports[chainName], vars)
+}
+
+// exported возвращает значение переменной цепочки.
+func (s *readySet) exported(ref flow.ExportRef) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.exports[ref.Chain][ref.Name]
+
+	return value, ok
+}
+
+// withExports подставляет в команду цепочки переменные её зависимостей. Без
+// набора — вне запуска — подставлять нечего, и ссылка останется
+// неразрешённой.
+//
+// Ссылкой считается только то, что называет цепочку из needs: проверка
+// конфигурации уже отказала всем прочим ссылкам на цепочки запуска, а
+// остальные ${a.b} — литералы.
+func withExports(set *readySet, chain *flow.CommandChain, cmd flow.Command) (flow.Command, error) {
+	if set == nil {
+		return cmd.ExpandExports(chain.NeedsChain, func(flow.ExportRef) (string, bool) { return "", false })
+	}
+
+	return cmd.ExpandExports(chain.NeedsChain, set.exported)
 }
 
 // awaitChain ждёт выполнения всех условий готовности цепочки.
@@ -157,9 +411,18 @@ func (s *readySet) awaitChain(ctx context.Context, chain *flow.CommandChain) err
 	}
 
 	for _, cmd := range conditions {
+		// Условие может ссылаться на переменные зависимостей: `tcp:
+		// 127.0.0.1:${db.port}` проверяет тот порт, что выбрал сервис.
+		cmd, err := withExports(s, chain, cmd)
+		if err != nil {
+			return fmt.Errorf("chain %q, ready: %w", chain.Name, err)
+		}
+
 		if err := s.awaitOne(ctx, chain.Name, cmd); err != nil {
 			return err
 		}
+
+		commandGates(s, chain, cmd).ready.open(nil)
 	}
 
 	return nil
@@ -170,17 +433,15 @@ func (s *readySet) awaitOne(ctx context.Context, chainName string, cmd flow.Comm
 	ready := cmd.Ready
 	limit := ready.Limit()
 
-	// Регистрировать наблюдателя надо до ожидания: строка может появиться
+	// Регистрировать наблюдателей надо до ожидания: строка может появиться
 	// раньше, чем мы дойдём до select.
-	var lines <-chan struct{}
-	if ready.LogLine != "" {
-		lines = s.addMatcher(chainName, ready.LogLine)
-	}
+	check := s.checkOf(chainName, ready)
+	defer s.release(chainName, check)
 
 	deadline, cancel := context.WithTimeout(ctx, limit)
 	defer cancel()
 
-	err := waitCondition(deadline, ready, lines)
+	err := waitCondition(deadline, check)
 	if err == nil {
 		return nil
 	}
@@ -193,13 +454,87 @@ func (s *readySet) awaitOne(ctx context.Context, chainName string, cmd flow.Comm
 	return err
 }
 
+// readyCheck — условие готовности вместе с состоянием ожидания.
+//
+// Выполнившееся условие запоминается: в all сигналы приходят в разное время,
+// и строка журнала, увиденная раньше открытия порта, не должна забываться,
+// пока порт ещё закрыт.
+type readyCheck struct {
+	cond    *flow.ReadyCondition
+	matcher *lineMatcher
+	line    <-chan struct{}
+	parts   []*readyCheck
+	met     bool
+}
+
+// checkOf собирает проверку условия и регистрирует наблюдателей строк всех
+// вложенных logLine.
+func (s *readySet) checkOf(chainName string, ready *flow.ReadyCondition) *readyCheck {
+	check := &readyCheck{cond: ready}
+
+	if ready.LogLine != "" || ready.LogRegex != "" {
+		check.matcher = s.addMatcher(chainName, ready)
+		check.line = check.matcher.found
+	}
+
+	for _, part := range slices.Concat(ready.All, ready.Any) {
+		check.parts = append(check.parts, s.checkOf(chainName, part))
+	}
+
+	return check
+}
+
+// release снимает наблюдателей, которых зарегистрировал checkOf.
+func (s *readySet) release(chainName string, check *readyCheck) {
+	if check.matcher != nil {
+		s.removeMatcher(chainName, check.matcher)
+	}
+
+	for _, part := range check.parts {
+		s.release(chainName, part)
+	}
+}
+
+// holds однократно проверяет условие.
+//
+// Части all опрашиваются все, а не до первой невыполненной: иначе поздняя
+// часть не успела бы запомнить свой сигнал, пока ранняя ещё ждёт.
+func (c *readyCheck) holds(ctx context.Context) bool {
+	if c.met {
+		return true
+	}
+
+	switch {
+	case len(c.cond.All) > 0:
+		c.met = true
+
+		for _, part := range c.parts {
+			c.met = part.holds(ctx) && c.met
+		}
+	case len(c.cond.Any) > 0:
+		c.met = slices.ContainsFunc(c.parts, func(part *readyCheck) bool { return part.holds(ctx) })
+	case c.line != nil:
+		select {
+		case <-c.line:
+			c.met = true
+		default:
+		}
+	default:
+		c.met = probe(ctx, c.cond)
+	}
+
+	return c.met
+}
+
 // waitCondition опрашивает условие до выполнения либо до отмены.
-func waitCondition(ctx context.Context, ready *flow.ReadyCondition, lines <-chan struct{}) error {
-	if lines != nil {
+func waitCondition(ctx context.Context, check *readyCheck) error {
+	// Одиночная строка журнала ждётся без опроса: наблюдатель сам сообщит о
+	// ней, и сотня миллисекунд задержки тут ни к чему.
+	if check.line != nil {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-lines:
+		case <-check.line:
 			return nil
 		}
 	}
@@ -208,7 +543,7 @@ func waitCondition(ctx context.Context, ready *flow.ReadyCondition, lines <-chan
 	defer ticker.Stop()
 
 	for {
-		if probe(ctx, ready) {
+		if check.holds(ctx) {
 			return nil
 		}
 
@@ -233,6 +568,14 @@ func probe(ctx context.Context, ready *flow.ReadyCondition) bool {
 
 		return true
 
+	case ready.HTTP != nil:
+		return probeHTTP(ctx, ready.HTTP)
+
+	case ready.File != nil:
+		info, err := os.Stat(ready.File.Path)
+
+		return err == nil && (!ready.File.NonEmpty || info.Size() > 0)
+
 	case len(ready.Exec) > 0:
 		//nolint:gosec // команда проверки приходит из доверенной конфигурации
 		cmd := exec.CommandContext(ctx, ready.Exec[0], ready.Exec[1:]...)
@@ -243,3 +586,81 @@ func probe(ctx context.Context, ready *flow.ReadyCondition) bool {
 		return false
 	}
 }
+
+// probeHTTP однократно запрашивает адрес и сверяет код и тело ответа.
+//
+// Соединение не переиспользуется: между пробами сервис может перезапуститься,
+// а keep-alive к умершему процессу дал бы ложный отказ.
+func probeHTTP(ctx context.Context, probe *flow.HTTPProbe) bool {
+	ctx, cancel := context.WithTimeout(ctx, readyRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probe.URL, nil)
+	if err != nil {
+		return false
+	}
+
+	for key, value := range probe.Headers {
+		// Host — не заголовок для net/http, а поле запроса: через Header он
+		// молча игнорируется.
+		if strings.EqualFold(key, "Host") {
+			req.Host = value
+
+			continue
+		}
+
+		req.Header.Set(key, value)
+	}
+
+	transport := &http.Transport{DisableKeepAlives: true}
+	if probe.Insecure {
+		//nolint:gosec // проверку сертификата отключают явно, для локальных самоподписанных
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	// Перенаправления не выполняются: 302 на страницу входа — не готовность,
+	// и решать, подходит ли 3xx, должен диапазон кодов.
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	low, high := probe.Statuses()
+	if resp.StatusCode < low || resp.StatusCode > high {
+		return false
+	}
+
+	if probe.Body == "" && probe.BodyRegex == "" {
+		return true
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, readyBodyLimit))
+	if err != nil {
+		return false
+	}
+
+	return bodyMatches(probe, body)
+}
+
+// bodyMatches сверяет тело ответа с подстрокой и выражением пробы.
+func bodyMatches(probe *flow.HTTPProbe, body []byte) bool {
+	if probe.Body != "" && !bytes.Contains(body, []byte(probe.Body)) {
+		return false
+	}
+
+	// Выражение проверено при сборке конфигурации, так что ошибка здесь
+	// невозможна; разбор на каждую пробу дешевле, чем кэш ради раза в 100 мс.
+	re, err := probe.BodyPattern()
+	if err != nil {
+		return false
+	}
+
+	return re == nil || re.Match(body)
+}
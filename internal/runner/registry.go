@@ -15,6 +15,11 @@ import (
 type trackedProcess struct {
 	cmd  *exec.Cmd
 	done <-chan struct{}
+
+	// chain и command — чей это процесс. Нужны только снимку состояния:
+	// остановка и убийство обходятся ключом.
+	chain   string
+	command string
 }
 
 // processRegistry отвечает за учёт и остановку запущенных процессов.
@@ -30,10 +35,15 @@ func newProcessRegistry() *processRegistry {
 // add регистрирует процесс. done — канал, который закрывается владельцем процесса
 // после завершения cmd.Wait(); registry лишь дожидается его, но сам Wait не вызывает.
 func (r *processRegistry) add(key string, cmd *exec.Cmd, done <-chan struct{}) {
+	r.addOwned(key, "", "", cmd, done)
+}
+
+// addOwned регистрирует процесс вместе с именами его цепочки и команды.
+func (r *processRegistry) addOwned(key, chain, command string, cmd *exec.Cmd, done <-chan struct{}) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.procs[key] = &trackedProcess{cmd: cmd, done: done}
+	r.procs[key] = &trackedProcess{cmd: cmd, done: done, chain: chain, command: command}
 }
 
 func (r *processRegistry) remove(key string) {
@@ -56,6 +66,19 @@ func (r *processRegistry) snapshot() map[string]*trackedProcess {
 	return res
 }
 
+// list возвращает копии записей о процессах — для снимка состояния.
+func (r *processRegistry) list() []trackedProcess {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]trackedProcess, 0, len(r.procs))
+	for _, p := range r.procs {
+		out = append(out, *p)
+	}
+
+	return out
+}
+
 // stopAll останавливает все зарегистрированные процессы, отправляя им заданный сигнал,
 // ожидая завершения и при необходимости выполняя принудительное убийство.
 func (r *processRegistry) stopAll(lgr ui.Logger, sig os.Signal, forceKill time.Duration) {
@@ -15,6 +15,24 @@ type ChainResult struct {
 	// Skipped — цепочка не начиналась: не выполнилось условие предшественника.
 	// Отличать от Stopped обязательно: «оборвали» и «не начинали» — разное.
 	Skipped bool
+	// Commands — итог каждой команды в последнем запуске цепочки, в порядке
+	// объявления. Пуст, если цепочка не запускалась.
+	Commands []CommandResult
+}
+
+// CommandResult — итог одной команды цепочки.
+//
+// Вывод команды в stderr сюда не копируется: он уже едет в Err — в
+// ExitError и TimeoutError, — и второй экземпляр был бы лишней памятью.
+type CommandResult struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+	// Stopped — команду остановили, не дав доработать.
+	Stopped bool
+	// Skipped — команда не запускалась: отключена либо цепочка оборвалась
+	// раньше, чем до неё дошла очередь.
+	Skipped bool
 }
 
 // Failed сообщает, завершилась ли цепочка отказом.
@@ -0,0 +1,52 @@
+package runner
+
+import "sync"
+
+// stderrTailLimit — сколько последних байт stderr команды хранится для
+// отчёта. Причина отказа почти всегда в конце вывода, а dev-сервер, пишущий
+// журнал в stderr часами, не должен копить его в памяти целиком.
+const stderrTailLimit = 64 * 1024
+
+// tailBuffer хранит хвост потоковых строк не длиннее limit байт.
+//
+// Обрезка идёт с запасом, по достижении двойного предела: иначе каждая
+// строка болтливого процесса сдвигала бы весь буфер.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   []byte
+	limit int
+}
+
+func newTailBuffer(limit int) *tailBuffer {
+	return &tailBuffer{limit: limit}
+}
+
+// writeLine дописывает строку вместе с переводом строки.
+func (t *tailBuffer) writeLine(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, line...)
+	t.buf = append(t.buf, '\n')
+
+	if len(t.buf) > 2*t.limit {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.limit:]...)
+	}
+}
+
+// String возвращает накопленный хвост.
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return tailOf(t.buf, t.limit)
+}
+
+// tailOf возвращает последние limit байт.
+func tailOf(b []byte, limit int) string {
+	if len(b) > limit {
+		b = b[len(b)-limit:]
+	}
+
+	return string(b)
+}
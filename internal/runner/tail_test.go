@@ -0,0 +1,26 @@
+package runner
+
+import "testing"
+
+func TestTailBuffer_KeepsLastBytes(t *testing.T) {
+	tail := newTailBuffer(8)
+
+	for _, line := range []string{"first", "second", "third", "last"} {
+		tail.writeLine(line)
+	}
+
+	// Хвост режется по байтам, а не по строкам: начало строки может пропасть.
+	if got := tail.String(); got != "rd\nlast\n" {
+		t.Fatalf("ожидался хвост в 8 байт, получено %q", got)
+	}
+}
+
+func TestTailOf(t *testing.T) {
+	if got := tailOf([]byte("abc"), 8); got != "abc" {
+		t.Fatalf("короткий вывод должен остаться целым, получено %q", got)
+	}
+
+	if got := tailOf([]byte("abcdef"), 2); got != "ef" {
+		t.Fatalf("ожидался хвост %q, получено %q", "ef", got)
+	}
+}
@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// defaultWatchPoll — как часто обходится дерево в поисках изменений.
+//
+// Опрос, а не inotify: он одинаково работает на Linux, macOS, Windows и в
+// смонтированных в контейнер каталогах, где события ядра не доходят вовсе,
+// и не требует ни cgo, ни сторонних утилит. Полсекунды незаметны на фоне
+// перезапуска сервера и почти не стоят процессора на дереве исходников.
+const defaultWatchPoll = 500 * time.Millisecond
+
+// errFilesChanged — причина отмены запуска команды, когда изменились файлы.
+// Отличает перезапуск по слежению от остановки по сигналу или по запросу.
+var errFilesChanged = errors.New("watched files changed")
+
+// fileStamp — то, по чему видно, что файл изменился.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// fileWatcher следит за файлами команды опросом.
+type fileWatcher struct {
+	watch *flow.Watch
+	root  string
+	poll  time.Duration
+}
+
+func newFileWatcher(w *flow.Watch, poll time.Duration) fileWatcher {
+	root := w.Root
+	if root == "" {
+		root = "."
+	}
+
+	if poll <= 0 {
+		poll = defaultWatchPoll
+	}
+
+	return fileWatcher{watch: w, root: root, poll: poll}
+}
+
+// run сообщает в changes об изменениях относительно снимка prev, выждав
+// тишину в течение Debounce.
+//
+// Первый снимок снимает вызывающий, до запуска команды: иначе правка,
+// сделанная между запуском и первым опросом, попала бы в исходное состояние
+// и перезапуска не вызвала.
+//
+// Канал с буфером в одно событие и неблокирующей записью: пока команда
+// перезапускается, новые изменения сливаются в одно, а не копятся очередью
+// перезапусков.
+func (w fileWatcher) run(ctx context.Context, prev map[string]fileStamp, changes chan<- string) {
+	ticker := time.NewTicker(w.poll)
+	defer ticker.Stop()
+
+	var (
+		pending string
+		quietAt time.Time
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur := w.snapshot()
+		if changed := diffSnapshots(prev, cur); changed != "" {
+			pending, quietAt = changed, time.Now().Add(w.watch.Delay())
+		}
+
+		prev = cur
+
+		if pending != "" && !time.Now().Before(quietAt) {
+			select {
+			case changes <- pending:
+			default:
+			}
+
+			pending = ""
+		}
+	}
+}
+
+// snapshot обходит дерево и запоминает отметки отслеживаемых файлов.
+//
+// Ошибки обхода не прерывают слежение: файл, удалённый между чтением каталога
+// и stat, — обычное дело во время сборки.
+func (w fileWatcher) snapshot() map[string]fileStamp {
+	out := make(map[string]fileStamp)
+
+	_ = filepath.WalkDir(w.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // см. комментарий к snapshot
+		}
+
+		rel, relErr := filepath.Rel(w.root, p)
+		if relErr != nil {
+			return nil //nolint:nilerr // путь вне корня не отслеживается
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel != "." && !w.descend(rel) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !w.matches(rel) {
+			return nil
+		}
+
+		if info, infoErr := d.Info(); infoErr == nil {
+			out[rel] = fileStamp{mod: info.ModTime(), size: info.Size()}
+		}
+
+		return nil
+	})
+
+	return out
+}
+
+// descend решает, стоит ли заходить в каталог.
+//
+// Это не только экономия: `src/**/*.ts` не должен обходить node_modules при
+// каждом опросе. .git не обходится никогда — коммит не повод перезапускать
+// сервер, а шаблон `**` иначе ловил бы его.
+func (w fileWatcher) descend(dir string) bool {
+	if path.Base(dir) == ".git" {
+		return false
+	}
+
+	for _, pattern := range w.watch.Ignore {
+		if matchGlob(pattern, dir) || matchGlob(strings.TrimSuffix(pattern, "/**"), dir) {
+			return false
+		}
+	}
+
+	for _, pattern := range w.watch.Paths {
+		if mayContain(strings.Split(pattern, "/"), strings.Split(dir, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches сообщает, отслеживается ли файл.
+func (w fileWatcher) matches(rel string) bool {
+	for _, pattern := range w.watch.Ignore {
+		if matchGlob(pattern, rel) {
+			return false
+		}
+	}
+
+	for _, pattern := range w.watch.Paths {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diffSnapshots возвращает один из изменившихся путей или пустую строку.
+//
+// Из всех изменений берётся наименьший путь: для перезапуска достаточно
+// одного, а в журнале он не должен меняться от прогона к прогону.
+func diffSnapshots(prev, cur map[string]fileStamp) string {
+	var first string
+
+	note := func(p string) {
+		if first == "" || p < first {
+			first = p
+		}
+	}
+
+	for p, stamp := range cur {
+		if old, ok := prev[p]; !ok || old != stamp {
+			note(p)
+		}
+	}
+
+	for p := range prev {
+		if _, ok := cur[p]; !ok {
+			note(p)
+		}
+	}
+
+	return first
+}
+
+// matchGlob сопоставляет путь с шаблоном: `*` — в пределах сегмента,
+// `**` — любое число сегментов, включая ноль.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// mayContain сообщает, может ли под каталогом dir найтись путь, подходящий
+// под шаблон.
+func mayContain(pattern, dir []string) bool {
+	for _, seg := range dir {
+		if len(pattern) == 0 {
+			return false
+		}
+
+		if pattern[0] == "**" {
+			return true
+		}
+
+		if ok, _ := path.Match(pattern[0], seg); !ok {
+			return false
+		}
+
+		pattern = pattern[1:]
+	}
+
+	// Каталог кончился, а шаблон нет: дальше ещё есть что сопоставлять.
+	return len(pattern) > 0
+}
@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+	"github.com/efureev/parallel/internal/ui"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/api/main.go", true},
+		{"src/**", "src/a/b.ts", true},
+		{"src/**", "web/src/a.ts", false},
+		{"src/**/*.ts", "src/index.ts", true},
+		{"**/*_test.go", "pkg/x_test.go", true},
+		{"web/*.{js}", "web/a.js", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, ожидалось %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestFileWatcher_Descend — обход не заходит туда, где ничего не найдётся.
+func TestFileWatcher_Descend(t *testing.T) {
+	w := newFileWatcher(&flow.Watch{
+		Paths:  []string{"src/**/*.ts", "*.json"},
+		Ignore: []string{"src/gen/**"},
+	}, 0)
+
+	tests := map[string]bool{
+		"src":          true,
+		"src/app":      true,
+		"src/gen":      false,
+		"node_modules": false,
+		"src/.git":     false,
+	}
+
+	for dir, want := range tests {
+		if got := w.descend(dir); got != want {
+			t.Errorf("descend(%q) = %v, ожидалось %v", dir, got, want)
+		}
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+	prev := map[string]fileStamp{"a.go": {mod: now, size: 1}, "b.go": {mod: now, size: 1}}
+
+	if got := diffSnapshots(prev, prev); got != "" {
+		t.Errorf("без изменений получено %q", got)
+	}
+
+	cur := map[string]fileStamp{"a.go": {mod: now, size: 2}, "c.go": {mod: now, size: 1}}
+	if got := diffSnapshots(prev, cur); got != "a.go" {
+		t.Errorf("из изменённого, удалённого и нового ожидался наименьший путь, получено %q", got)
+	}
+
+	if got := diffSnapshots(prev, map[string]fileStamp{"a.go": prev["a.go"]}); got != "b.go" {
+		t.Errorf("удаление не замечено: %q", got)
+	}
+}
+
+// writeFile пишет файл со сдвигом времени изменения: у части файловых систем
+// гранулярность mtime — секунда, и два быстрых сохранения были бы неразличимы.
+func writeFile(t *testing.T, path, content string, age time.Duration) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	stamp := time.Now().Add(-age)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+// TestRunWithRestart_RestartsOnChange — изменение файла останавливает запуск
+// и поднимает команду снова, без политики restart и без задержки.
+func TestRunWithRestart_RestartsOnChange(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "main.go"), "package main", time.Hour)
+	writeFile(t, filepath.Join(root, "notes.txt"), "x", time.Hour)
+
+	exec := newChainExecutor(ui.NewDiscardLogger(), &fakeRunner{}, nil, withWatchPoll(5*time.Millisecond))
+	cmd := flow.Command{
+		Name:  "serve",
+		Cmd:   "go",
+		Watch: &flow.Watch{Root: root, Paths: []string{"**/*.go"}, Debounce: time.Millisecond},
+	}
+
+	var runs atomic.Int32
+
+	started := make(chan struct{}, 4)
+	ctx, cancel := context.WithCancel(t.Context())
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- exec.runWithRestart(ctx, &flow.CommandChain{Name: "api"}, cmd, func(runCtx context.Context) error {
+			runs.Add(1)
+			started <- struct{}{}
+			<-runCtx.Done()
+
+			return runCtx.Err()
+		})
+	}()
+
+	<-started
+
+	// Неотслеживаемый файл перезапуска не вызывает — следующим событием
+	// должен оказаться перезапуск именно от main.go.
+	writeFile(t, filepath.Join(root, "notes.txt"), "changed", 0)
+	writeFile(t, filepath.Join(root, "main.go"), "package main // changed", 0)
+
+	select {
+	case <-started:
+	case <-time.After(testTimeouts.ForceKill * 10):
+		t.Fatal("изменение файла не перезапустило команду")
+	}
+
+	cancel()
+
+	if err := <-done; err == nil {
+		t.Error("отмена должна вернуть ошибку последнего запуска")
+	}
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("команда запускалась %d раз, ожидалось 2", got)
+	}
+}
+
+// TestRunWithRestart_WatchedCommandExitsNormally — команда, вышедшая сама,
+// подчиняется обычной политике: слежение не держит цепочку вечно.
+func TestRunWithRestart_WatchedCommandExitsNormally(t *testing.T) {
+	exec := newChainExecutor(ui.NewDiscardLogger(), &fakeRunner{}, nil, withWatchPoll(5*time.Millisecond))
+	cmd := flow.Command{Name: "build", Cmd: "go", Watch: &flow.Watch{Root: t.TempDir(), Paths: []string{"*"}}}
+
+	err := exec.runWithRestart(t.Context(), &flow.CommandChain{Name: "c"}, cmd, func(context.Context) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runWithRestart: %v", err)
+	}
+}
@@ -35,12 +35,17 @@ func (f *FlowReader) Out(fl *flow.Flow) {
 	var b strings.Builder
 
 	b.WriteString("Flow structure:" + "\n")
+	writeHooks(&b, "  Before:\n", fl.Before)
 
 	for i, chain := range fl.Chains {
 		b.WriteString(fmt.Sprintf("  Chain %d: %s\n", i+1, chain.Name))
 
 		if len(chain.Needs) > 0 {
-			b.WriteString(fmt.Sprintf("    Needs: %s\n", strings.Join(chain.Needs, ", ")))
+			b.WriteString(fmt.Sprintf("    Needs: %s\n", chain.DescribeNeeds()))
+		}
+
+		if chain.Log != nil {
+			b.WriteString(fmt.Sprintf("    Log  : %s\n", chain.Log.Describe()))
 		}
 
 		commands := chain.Commands()
@@ -53,8 +58,11 @@ func (f *FlowReader) Out(fl *flow.Flow) {
 		for j, cmd := range commands {
 			writeCommand(&b, j+1, cmd)
 		}
+
+		writeHooks(&b, "    Finally:\n", chain.Finally)
 	}
 
+	writeHooks(&b, "  On exit:\n", fl.OnExit)
 	writeStartOrder(&b, fl)
 
 	f.lgr.Info(b.String())
@@ -85,6 +93,14 @@ func writeCommand(b *strings.Builder, num int, cmd flow.Command) {
 		b.WriteString(fmt.Sprintf("        Ready: %s, within %s\n", cmd.Ready.Describe(), cmd.Ready.Limit()))
 	}
 
+	if cmd.Watch != nil {
+		b.WriteString(fmt.Sprintf("        Watch: %s, debounce %s\n", cmd.Watch.Describe(), cmd.Watch.Delay()))
+	}
+
+	if cmd.Health != nil {
+		b.WriteString(fmt.Sprintf("        Probe: %s\n", cmd.Health.Describe()))
+	}
+
 	if cmd.Disable {
 		b.WriteString("        Disabled\n")
 	}
@@ -92,6 +108,23 @@ func writeCommand(b *strings.Builder, num int, cmd flow.Command) {
 	if cmd.Format.CmdName != "" {
 		b.WriteString(fmt.Sprintf("        Name : %s\n", cmd.Format.CmdName))
 	}
+
+	if cmd.Format.Timestamp != "" {
+		b.WriteString(fmt.Sprintf("        Time : %s\n", cmd.Format.Timestamp))
+	}
+}
+
+// writeHooks печатает команды подготовки или уборки под заголовком, если они есть.
+func writeHooks(b *strings.Builder, title string, cmds []flow.Command) {
+	if len(cmds) == 0 {
+		return
+	}
+
+	b.WriteString(title)
+
+	for j, cmd := range cmds {
+		writeCommand(b, j+1, cmd)
+	}
 }
 
 // writeStartOrder дописывает порядок запуска, если зависимости заданы.
@@ -164,8 +197,12 @@ func (f *FlowReader) List(fl *flow.Flow) {
 			b.WriteString(fmt.Sprintf(", %d disabled", disabled))
 		}
 
+		if len(chain.Tags) > 0 {
+			b.WriteString(" " + flow.TagPrefix + strings.Join(chain.Tags, " "+flow.TagPrefix))
+		}
+
 		if len(chain.Needs) > 0 {
-			b.WriteString(fmt.Sprintf(", needs %s", strings.Join(chain.Needs, ", ")))
+			b.WriteString(fmt.Sprintf(", needs %s", chain.DescribeNeeds()))
 		}
 
 		b.WriteString("\n")
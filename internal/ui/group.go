@@ -0,0 +1,239 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// groupMemoryLimit — сколько вывода группы держать в памяти. Дальше группа
+// уходит во временный файл: тестовый прогон, напечатавший сотню мегабайт,
+// не должен стоить сотни мегабайт памяти, пока его соседи не закончили.
+const groupMemoryLimit = 1 << 20
+
+// GroupLogger — логгер, умеющий копить вывод цепочки отдельно от общего
+// потока (режим -output grouped).
+type GroupLogger interface {
+	Logger
+	// Group открывает группу для цепочки name.
+	Group(name string) LogGroup
+}
+
+// LogGroup копит записи одной цепочки и печатает их одним куском по Close.
+//
+// Записи после Close идут в общий вывод сразу: опоздавшая строка важнее
+// порядка.
+type LogGroup interface {
+	Logger
+	Close() error
+}
+
+// groupStyle — разметка групп для интерфейса CI, в котором идёт запуск.
+type groupStyle int
+
+const (
+	// groupPlain — без разметки: строки группы и так несут имя цепочки.
+	groupPlain groupStyle = iota
+	// groupGitHub — ::group::/::endgroup:: GitHub Actions.
+	groupGitHub
+	// groupGitLab — section_start/section_end GitLab CI.
+	groupGitLab
+)
+
+// detectGroupStyle узнаёт CI по переменным, которые он выставляет сам.
+func detectGroupStyle(getenv func(string) string) groupStyle {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return groupGitHub
+	case getenv("GITLAB_CI") == "true":
+		return groupGitLab
+	default:
+		return groupPlain
+	}
+}
+
+// begin и end возвращают строки разметки вокруг группы.
+func (s groupStyle) begin(name string, at time.Time) string {
+	switch s {
+	case groupGitHub:
+		return "::group::" + name + NewlineChar
+	case groupGitLab:
+		return fmt.Sprintf("\x1b[0Ksection_start:%d:%s\r\x1b[0K%s\n", at.Unix(), sectionName(name), name)
+	case groupPlain:
+		return ""
+	default:
+		return ""
+	}
+}
+
+func (s groupStyle) end(name string, at time.Time) string {
+	switch s {
+	case groupGitHub:
+		return "::endgroup::" + NewlineChar
+	case groupGitLab:
+		return fmt.Sprintf("\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", at.Unix(), sectionName(name))
+	case groupPlain:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// sectionName приводит имя цепочки к алфавиту имён секций GitLab: прочие
+// символы он молча обрезает, и начало с концом секции перестают совпадать.
+func sectionName(name string) string {
+	return "parallel_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// groupedLogger — текстовый логгер, вывод цепочек которого копится по группам.
+// Собственные сообщения parallel печатаются сразу: о старте и отказах
+// узнавать по окончании соседей поздно.
+type groupedLogger struct {
+	Logger
+
+	sink    *Sink
+	colored bool
+	level   Level
+	style   groupStyle
+}
+
+// Group открывает группу, пишущую тем же оформлением, что и общий вывод.
+func (g *groupedLogger) Group(name string) LogGroup {
+	buf := &spillBuffer{limit: groupMemoryLimit}
+
+	return &logGroup{
+		// SyncWriter не нужен: spillBuffer сериализует записи сам.
+		Logger: newLoggerOver(buf, g.colored, g.level),
+		name:   name,
+		opened: time.Now(),
+		buf:    buf,
+		sink:   g.sink,
+		style:  g.style,
+	}
+}
+
+type logGroup struct {
+	Logger
+
+	name   string
+	opened time.Time
+	buf    *spillBuffer
+	sink   *Sink
+	style  groupStyle
+}
+
+// Close печатает группу целиком, не пуская между её строками чужие записи.
+func (g *logGroup) Close() error {
+	body, size, release := g.buf.seal(g.sink)
+	defer release()
+
+	if size == 0 {
+		return nil
+	}
+
+	return g.sink.WriteGroup(g.style.begin(g.name, g.opened), body, g.style.end(g.name, time.Now()))
+}
+
+// spillBuffer держит вывод в памяти до предела, а дальше — во временном файле.
+type spillBuffer struct {
+	limit int
+
+	mu   sync.Mutex
+	mem  bytes.Buffer
+	file *os.File
+	size int64
+	// out — куда писать после seal; nil, пока группа копится.
+	out io.Writer
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.out != nil {
+		return b.out.Write(p)
+	}
+
+	if b.file == nil && b.mem.Len()+len(p) > b.limit {
+		b.spill()
+	}
+
+	var (
+		n   int
+		err error
+	)
+
+	if b.file != nil {
+		n, err = b.file.Write(p)
+	} else {
+		n, err = b.mem.Write(p)
+	}
+
+	b.size += int64(n)
+
+	if err != nil {
+		return n, fmt.Errorf("group buffer: %w", err)
+	}
+
+	return n, nil
+}
+
+// spill переносит накопленное во временный файл. Не вышло — группа остаётся
+// в памяти: потерять вывод хуже, чем занять её больше, чем хотелось.
+func (b *spillBuffer) spill() {
+	f, err := os.CreateTemp("", "parallel-group-*.log")
+	if err != nil {
+		return
+	}
+
+	if _, err := b.mem.WriteTo(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+
+		return
+	}
+
+	b.file = f
+}
+
+// seal закрывает группу для накопления: дальнейшие записи идут в out. Возвращает
+// накопленное и функцию, освобождающую временный файл.
+func (b *spillBuffer) seal(out io.Writer) (io.Reader, int64, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.out = out
+
+	if b.file == nil {
+		return &b.mem, b.size, func() {}
+	}
+
+	f := b.file
+	release := func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return errReader{fmt.Errorf("group buffer: %w", err)}, b.size, release
+	}
+
+	return f, b.size, release
+}
+
+// errReader отдаёт ошибку вместо данных: так отказ перемотки файла доходит до
+// Close тем же путём, что и отказ чтения.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGroupedLogger_PrintsGroupOnClose — строки группы появляются только по
+// Close и одним куском, а собственные сообщения идут сразу.
+func TestGroupedLogger_PrintsGroupOnClose(t *testing.T) {
+	var buf bytes.Buffer
+
+	out := NewOutput(&buf, WithoutColor(), WithOutputMode(OutputGrouped))
+
+	grouped, ok := out.Logger().(GroupLogger)
+	if !ok {
+		t.Fatal("в режиме grouped логгер должен уметь группы")
+	}
+
+	group := grouped.Group("api")
+	group.Blocks("API>", "first")
+	grouped.Info("live message")
+	group.Blocks("API>", "second")
+
+	if err := out.sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "first") {
+		t.Fatalf("строка группы напечатана до её закрытия:\n%s", buf.String())
+	}
+
+	if err := group.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	group.Blocks("API>", "late")
+	_ = out.Close()
+
+	text := buf.String()
+
+	live, first, second, late := strings.Index(text, "live message"), strings.Index(text, "first"),
+		strings.Index(text, "second"), strings.Index(text, "late")
+	if live < 0 || !(live < first && first < second && second < late) {
+		t.Fatalf("неверный порядок вывода:\n%s", text)
+	}
+}
+
+func TestGroupStyle(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	if s := detectGroupStyle(env(nil)); s != groupPlain || s.begin("api", time.Now()) != "" {
+		t.Fatal("вне CI разметки быть не должно")
+	}
+
+	github := detectGroupStyle(env(map[string]string{"GITHUB_ACTIONS": "true"}))
+	if github.begin("api", time.Now()) != "::group::api\n" || github.end("api", time.Now()) != "::endgroup::\n" {
+		t.Fatal("неверная разметка GitHub Actions")
+	}
+
+	at := time.Unix(1700000000, 0)
+	gitlab := detectGroupStyle(env(map[string]string{"GITLAB_CI": "true"}))
+
+	want := "\x1b[0Ksection_start:1700000000:parallel_db_migrate\r\x1b[0Kdb:migrate\n"
+	if got := gitlab.begin("db:migrate", at); got != want {
+		t.Fatalf("неверное начало секции GitLab: %q", got)
+	}
+
+	if got := gitlab.end("db:migrate", at); got != "\x1b[0Ksection_end:1700000000:parallel_db_migrate\r\x1b[0K\n" {
+		t.Fatalf("неверный конец секции GitLab: %q", got)
+	}
+}
+
+// TestSpillBuffer_SpillsToFile — сверх предела вывод уходит во временный файл,
+// ничего не теряя, а файл удаляется после печати.
+func TestSpillBuffer_SpillsToFile(t *testing.T) {
+	buf := &spillBuffer{limit: 8}
+
+	for _, chunk := range []string{"12345", "67890", "abc"} {
+		if _, err := buf.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if buf.file == nil {
+		t.Fatal("сверх предела вывод должен уйти в файл")
+	}
+
+	path := buf.file.Name()
+
+	var after bytes.Buffer
+
+	body, size, release := buf.seal(&after)
+
+	data, err := io.ReadAll(body)
+	if err != nil || string(data) != "1234567890abc" || size != int64(len(data)) {
+		t.Fatalf("получено %q (%d), %v", data, size, err)
+	}
+
+	release()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("временный файл должен быть удалён: %v", err)
+	}
+
+	if _, err := buf.Write([]byte("late")); err != nil || after.String() != "late" {
+		t.Fatalf("запись после seal должна уйти дальше, получено %q, %v", after.String(), err)
+	}
+}
@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// Потоки вывода команды в OutputLine.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// OutputLine — строка вывода команды в разобранном виде.
+type OutputLine struct {
+	Chain   string
+	Command string
+	// Stream — StreamStdout либо StreamStderr.
+	Stream string
+	// Seq — номер строки в выводе одного запуска команды, общий для обоих
+	// потоков: по нему восстанавливается порядок, которого ts не гарантирует.
+	Seq  int
+	Line string
+}
+
+// LineLogger — логгер, которому строки вывода команд нужны по отдельности и
+// с полями, а не готовыми блоками.
+//
+// Отдельный интерфейс, а не метод Logger: блочному логгеру разобранная строка
+// ни к чему, а заставлять его её принимать значило бы тащить раскраску из
+// runner в ui. Runner проверяет логгер на этот интерфейс один раз.
+type LineLogger interface {
+	Logger
+	Line(line OutputLine)
+}
+
+// jsonLogger пишет каждую запись одним JSON-объектом на строку.
+//
+// Writer обязан быть потокобезопасным: запись делается одним вызовом Write,
+// и строки разных горутин не перемешиваются, только если их сериализует он.
+type jsonLogger struct {
+	out   io.Writer
+	level Level
+}
+
+func newJSONLogger(out io.Writer, level Level) *jsonLogger {
+	return &jsonLogger{out: out, level: level}
+}
+
+// lineRecord — формат строки вывода команды. Порядок полей задан структурой,
+// а не картой: сборщик журналов читает его и глазами тоже.
+type lineRecord struct {
+	TS      string `json:"ts"`
+	Chain   string `json:"chain"`
+	Command string `json:"command"`
+	Stream  string `json:"stream"`
+	Seq     int    `json:"seq"`
+	Line    string `json:"line"`
+}
+
+func (j *jsonLogger) Line(line OutputLine) {
+	j.write(lineRecord{
+		TS:      timestamp(),
+		Chain:   line.Chain,
+		Command: line.Command,
+		Stream:  line.Stream,
+		Seq:     line.Seq,
+		Line:    line.Line,
+	})
+}
+
+func (j *jsonLogger) Debug(msg string, fields ...Field) { j.log(LevelDebug, msg, nil, fields) }
+
+func (j *jsonLogger) Info(msg string, fields ...Field) { j.log(LevelInfo, msg, nil, fields) }
+
+func (j *jsonLogger) Warn(msg string, fields ...Field) { j.log(LevelWarn, msg, nil, fields) }
+
+func (j *jsonLogger) Error(err error, msg string, fields ...Field) {
+	j.log(LevelError, msg, err, fields)
+}
+
+// Blocks и ErrorBlocks в этом режиме до вывода команд не доходят — его
+// runner отдаёт через Line. Остаются редкие сообщения, которые кладутся в msg.
+func (j *jsonLogger) Blocks(blocks ...string) {
+	j.log(LevelInfo, joinBlocks(blocks), nil, nil)
+}
+
+func (j *jsonLogger) ErrorBlocks(err error, blocks ...string) {
+	j.log(LevelError, joinBlocks(blocks), err, nil)
+}
+
+// log пишет сообщение журнала: ts, level и msg, затем поля и ошибка.
+//
+// Карта, а не структура: набор полей у каждого сообщения свой. Ключи
+// encoding/json сортирует, так что запись стабильна.
+func (j *jsonLogger) log(level Level, msg string, err error, fields []Field) {
+	if level < j.level {
+		return
+	}
+
+	rec := make(map[string]any, len(fields)+4) //nolint:mnd // ts, level, msg и error
+	for _, f := range fields {
+		if e, ok := f.Val.(error); ok {
+			rec[f.Key] = e.Error()
+
+			continue
+		}
+
+		rec[f.Key] = f.Val
+	}
+
+	rec["ts"], rec["level"], rec["msg"] = timestamp(), level.String(), msg
+
+	if err != nil {
+		rec["error"] = err.Error()
+	}
+
+	j.write(rec)
+}
+
+// write кодирует запись и отдаёт её одним Write вместе с переводом строки.
+// Незакодируемое поле — ошибка программы, а не повод ронять вывод: запись
+// пропускается.
+//
+// HTML-экранирование выключено: вывод команд полон `<`, `>` и `&`, и в виде
+// \u003e он нечитаем для человека, заглянувшего в журнал.
+func (j *jsonLogger) write(rec any) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(rec); err != nil {
+		return
+	}
+
+	_, _ = j.out.Write(buf.Bytes())
+}
+
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func joinBlocks(blocks []string) string {
+	parts := make([]string, 0, len(blocks))
+
+	for _, b := range blocks {
+		if b = strings.TrimSpace(b); b != "" {
+			parts = append(parts, b)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
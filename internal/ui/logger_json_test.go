@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+func TestJSONLogger_Line(t *testing.T) {
+	var buf bytes.Buffer
+
+	newJSONLogger(&buf, LevelInfo).Line(OutputLine{
+		Chain: "api", Command: "serve", Stream: StreamStderr, Seq: 3, Line: "listening on <:8080> & ready",
+	})
+
+	out := buf.String()
+
+	// Порядок полей — часть формата: его обещает документация.
+	order := []string{`"ts"`, `"chain":"api"`, `"command":"serve"`, `"stream":"stderr"`, `"seq":3`, `"line"`}
+	last := -1
+
+	for _, key := range order {
+		idx := strings.Index(out, key)
+		if idx <= last {
+			t.Fatalf("поле %s не на своём месте:\n%s", key, out)
+		}
+
+		last = idx
+	}
+
+	if !strings.Contains(out, `"line":"listening on <:8080> & ready"`) || !strings.HasSuffix(out, "\n") {
+		t.Fatalf("строка должна идти без HTML-экранирования и заканчиваться переводом строки:\n%s", out)
+	}
+}
+
+func TestJSONLogger_Messages(t *testing.T) {
+	var buf bytes.Buffer
+
+	lgr := newJSONLogger(&buf, LevelInfo)
+	lgr.Debug("hidden")
+	lgr.Info("started", F("chain", "api"), F("attempt", 2))
+	lgr.Error(errors.New("boom"), "failed", F("cause", errors.New("disk full")))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("debug ниже порога и печататься не должен:\n%s", buf.String())
+	}
+
+	var info, failure map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &info); err != nil {
+		t.Fatalf("запись должна быть JSON: %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(lines[1]), &failure); err != nil {
+		t.Fatalf("запись должна быть JSON: %v", err)
+	}
+
+	if info["level"] != "info" || info["msg"] != "started" || info["chain"] != "api" || info["attempt"] != 2.0 {
+		t.Fatalf("неверная запись: %v", info)
+	}
+
+	if failure["level"] != "error" || failure["error"] != "boom" || failure["cause"] != "disk full" {
+		t.Fatalf("ошибки должны попадать в запись текстом: %v", failure)
+	}
+}
+
+func TestNewOutput_JSONMode(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "1")
+
+	var buf bytes.Buffer
+
+	out := NewOutput(&buf, WithOutputMode(OutputJSON))
+
+	if _, ok := out.Logger().(LineLogger); !ok {
+		t.Fatal("в режиме json логгер должен принимать строки вывода разобранными")
+	}
+
+	chain := &flow.CommandChain{Name: "api"}
+
+	header := out.Formatter().FormatChainInfo(chain, flow.Command{Cmd: "echo"}).Header
+	if strings.Contains(header, "\x1b[") {
+		t.Fatalf("в режиме json раскраска недопустима: %q", header)
+	}
+
+	out.Logger().Info("hello")
+
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if !json.Valid(bytes.TrimSpace(buf.Bytes())) {
+		t.Fatalf("вывод должен быть JSON:\n%s", buf.String())
+	}
+}
+
+func TestParseOutputMode(t *testing.T) {
+	for _, s := range []string{"text", "json", "grouped"} {
+		if mode, err := ParseOutputMode(s); err != nil || string(mode) != s {
+			t.Fatalf("%q: получено %q, %v", s, mode, err)
+		}
+	}
+
+	if _, err := ParseOutputMode("xml"); err == nil {
+		t.Fatal("ожидалась ошибка для неизвестного режима")
+	}
+}
@@ -37,10 +37,12 @@ type config struct {
 	level Level
 	// forceNoColor выставляется флагом --no-color и перевешивает всё остальное.
 	forceNoColor bool
+	// mode выбирает реализацию логгера в NewOutput.
+	mode OutputMode
 }
 
 func newConfig(opts []Option) config {
-	cfg := config{level: LevelInfo}
+	cfg := config{level: LevelInfo, mode: OutputText}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
@@ -58,6 +60,12 @@ func WithoutColor() Option {
 	return func(c *config) { c.forceNoColor = true }
 }
 
+// WithOutputMode выбирает форму вывода: цветные блоки, JSON-строки или блоки,
+// сгруппированные по цепочкам.
+func WithOutputMode(m OutputMode) Option {
+	return func(c *config) { c.mode = m }
+}
+
 // toReggolLevel переводит уровень порта в уровень библиотеки.
 func toReggolLevel(l Level) reggol.Level {
 	switch l {
@@ -135,7 +143,27 @@ func NewOutput(out io.Writer, opts ...Option) *Output {
 	colored := colorEnabled(out, cfg.forceNoColor)
 
 	// SyncWriter поверх Sink не нужен: Sink сериализует записи сам.
-	lgr := newLoggerOver(sink, colored, cfg.level)
+	var lgr Logger
+
+	switch cfg.mode {
+	case OutputJSON:
+		// ANSI-последовательности внутри JSON-строк — мусор для разбора, так
+		// что форматтер в этом режиме не раскрашивает ничего.
+		colored = false
+		lgr = newJSONLogger(sink, cfg.level)
+	case OutputGrouped:
+		lgr = &groupedLogger{
+			Logger:  newLoggerOver(sink, colored, cfg.level),
+			sink:    sink,
+			colored: colored,
+			level:   cfg.level,
+			style:   detectGroupStyle(os.Getenv),
+		}
+	case OutputText:
+		lgr = newLoggerOver(sink, colored, cfg.level)
+	default:
+		lgr = newLoggerOver(sink, colored, cfg.level)
+	}
 
 	return &Output{
 		logger:    lgr,
@@ -0,0 +1,53 @@
+package ui
+
+import "fmt"
+
+// OutputMode — форма, в которой печатается вывод запуска.
+type OutputMode string
+
+// Режимы вывода.
+const (
+	// OutputText — человекочитаемые цветные блоки; режим по умолчанию.
+	OutputText OutputMode = "text"
+	// OutputJSON — по JSON-объекту на строку: для сборщиков журналов, которым
+	// блочная раскладка не по зубам.
+	OutputJSON OutputMode = "json"
+	// OutputGrouped — текстовые блоки, но вывод каждой цепочки копится и
+	// печатается одним куском, когда она закончилась: для CI, где живой
+	// поток нескольких наборов тестов вперемешку не прочесть.
+	OutputGrouped OutputMode = "grouped"
+)
+
+// ParseOutputMode разбирает имя режима вывода.
+func ParseOutputMode(s string) (OutputMode, error) {
+	switch mode := OutputMode(s); mode {
+	case OutputText, OutputJSON, OutputGrouped:
+		return mode, nil
+	default:
+		return OutputText, fmt.Errorf("unknown output mode %q: expected text, json or grouped", s)
+	}
+}
+
+// ViewMode — как запуск показывается человеку за терминалом.
+//
+// Отдельно от OutputMode: режим вывода решает, в какой форме строки уходят
+// в поток, а вид — будет ли потоком вообще или экраном с панелями.
+type ViewMode string
+
+// Виды.
+const (
+	// ViewPlain — обычный поток строк; вид по умолчанию.
+	ViewPlain ViewMode = "plain"
+	// ViewTUI — полноэкранный вид: по вкладке на цепочку и строка статуса.
+	ViewTUI ViewMode = "tui"
+)
+
+// ParseViewMode разбирает имя вида.
+func ParseViewMode(s string) (ViewMode, error) {
+	switch mode := ViewMode(s); mode {
+	case ViewPlain, ViewTUI:
+		return mode, nil
+	default:
+		return ViewPlain, fmt.Errorf("unknown ui %q: expected plain or tui", s)
+	}
+}
@@ -0,0 +1,244 @@
+package ui
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ReportCommand — строка отчёта об одной команде цепочки.
+type ReportCommand struct {
+	SummaryRow
+	// Output — хвост stderr упавшей команды: в CI именно он объясняет отказ.
+	Output string
+}
+
+// ReportChain — строка отчёта о цепочке вместе с её командами.
+//
+// Как и SummaryRow, приходит уже решённой: ui только записывает отчёт в
+// нужном формате и не разбирает ошибки исполнения.
+type ReportChain struct {
+	SummaryRow
+	Commands []ReportCommand
+}
+
+// jsonReport — формат отчёта -report json. Отдельные типы с тегами, а не теги
+// на ReportChain: формат — обещание внешним потребителям, как и у потока событий.
+type jsonReport struct {
+	Status     string      `json:"status"`
+	DurationMS int64       `json:"durationMs"`
+	Chains     []jsonChain `json:"chains"`
+}
+
+type jsonChain struct {
+	Name       string        `json:"name"`
+	Status     string        `json:"status"`
+	DurationMS int64         `json:"durationMs"`
+	Reason     string        `json:"reason,omitempty"`
+	Commands   []jsonCommand `json:"commands"`
+}
+
+type jsonCommand struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"durationMs"`
+	Reason     string `json:"reason,omitempty"`
+	Output     string `json:"output,omitempty"`
+}
+
+// WriteJSONReport пишет итог запуска одним JSON-документом.
+func WriteJSONReport(w io.Writer, chains []ReportChain) error {
+	report := jsonReport{Status: runStatus(chains), Chains: make([]jsonChain, 0, len(chains))}
+
+	for _, ch := range chains {
+		report.DurationMS = max(report.DurationMS, ch.Duration.Milliseconds())
+
+		out := jsonChain{
+			Name: ch.Name, Status: ch.Status, DurationMS: ch.Duration.Milliseconds(), Reason: ch.Reason,
+			Commands: make([]jsonCommand, 0, len(ch.Commands)),
+		}
+
+		for _, cmd := range ch.Commands {
+			out.Commands = append(out.Commands, jsonCommand{
+				Name: cmd.Name, Status: cmd.Status, DurationMS: cmd.Duration.Milliseconds(),
+				Reason: cmd.Reason, Output: cmd.Output,
+			})
+		}
+
+		report.Chains = append(report.Chains, out)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("writing JSON report: %w", err)
+	}
+
+	return nil
+}
+
+// runStatus — итог запуска по итогам цепочек: любой отказ делает запуск
+// отказавшим, остановка без отказа — остановленным.
+func runStatus(chains []ReportChain) string {
+	status := StatusOK
+
+	for _, ch := range chains {
+		switch {
+		case isFailure(ch.Status):
+			return StatusFailed
+		case ch.Status == StatusStopped:
+			status = StatusStopped
+		}
+	}
+
+	return status
+}
+
+// Элементы JUnit XML в том подмножестве, которое читают GitHub, GitLab и
+// Jenkins: общего стандарта у формата нет, и лишние атрибуты кто-то да не поймёт.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// junitReportName — имя набора наборов: под ним отчёт виден в интерфейсе CI.
+const junitReportName = "parallel"
+
+// WriteJUnitReport пишет итог запуска в формате JUnit XML: цепочка — набор
+// тестов, команда — тест.
+func WriteJUnitReport(w io.Writer, chains []ReportChain) error {
+	report := junitSuites{Name: junitReportName}
+
+	var total time.Duration
+
+	for _, ch := range chains {
+		suite := junitSuite{Name: ch.Name, Time: junitTime(ch.Duration)}
+
+		for _, tc := range junitCases(ch) {
+			suite.Tests++
+
+			switch {
+			case tc.Failure != nil:
+				suite.Failures++
+			case tc.Skipped != nil:
+				suite.Skipped++
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+		total = max(total, ch.Duration)
+	}
+
+	report.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("writing JUnit report: %w", err)
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("writing JUnit report: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("writing JUnit report: %w", err)
+	}
+
+	return nil
+}
+
+// junitCases раскладывает цепочку на тесты.
+//
+// Отказ самой цепочки, за которым не стоит отказ команды — например, не
+// дождались готовности, — получает отдельный тест с именем цепочки. Иначе
+// интерфейс CI показал бы зелёный набор у цепочки, уронившей запуск.
+func junitCases(ch ReportChain) []junitCase {
+	cases := make([]junitCase, 0, len(ch.Commands)+1)
+	failedCommand := false
+
+	for _, cmd := range ch.Commands {
+		tc := junitCaseOf(ch.Name, cmd.SummaryRow, cmd.Output)
+		failedCommand = failedCommand || tc.Failure != nil
+
+		cases = append(cases, tc)
+	}
+
+	if len(cases) == 0 || (!failedCommand && isFailure(ch.Status)) {
+		cases = append(cases, junitCaseOf(ch.Name, ch.SummaryRow, ""))
+	}
+
+	return cases
+}
+
+func junitCaseOf(suite string, row SummaryRow, output string) junitCase {
+	tc := junitCase{Name: row.Name, ClassName: suite, Time: junitTime(row.Duration)}
+
+	switch row.Status {
+	case StatusFailed:
+		tc.Failure = &junitFailure{Message: row.Reason, Type: "failure", Text: output}
+	case StatusTimedOut:
+		tc.Failure = &junitFailure{Message: row.Reason, Type: "timeout", Text: output}
+	case StatusStopped, StatusSkipped:
+		message := row.Status
+		if row.Reason != "" {
+			message += ": " + row.Reason
+		}
+
+		tc.Skipped = &junitSkipped{Message: message}
+	}
+
+	return tc
+}
+
+func isFailure(status string) bool {
+	return status == StatusFailed || status == StatusTimedOut
+}
+
+// junitTime — секунды с миллисекундами: так время пишут все известные
+// генераторы JUnit XML, и только так его понимают все читатели.
+func junitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
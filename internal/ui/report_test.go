@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reportFixture() []ReportChain {
+	return []ReportChain{
+		{
+			SummaryRow: SummaryRow{Name: "lint", Status: StatusFailed, Duration: time.Second, Reason: "exit 2"},
+			Commands: []ReportCommand{
+				{SummaryRow: SummaryRow{Name: "vet", Status: StatusOK, Duration: 300 * time.Millisecond}},
+				{
+					SummaryRow: SummaryRow{
+						Name: "golangci", Status: StatusFailed, Duration: 700 * time.Millisecond, Reason: "exit 2",
+					},
+					Output: "main.go:1: <bad> & worse\n",
+				},
+				{SummaryRow: SummaryRow{Name: "after", Status: StatusSkipped}},
+			},
+		},
+		{
+			SummaryRow: SummaryRow{Name: "api", Status: StatusFailed, Duration: 2 * time.Second, Reason: "not ready"},
+			Commands: []ReportCommand{
+				{SummaryRow: SummaryRow{Name: "serve", Status: StatusStopped, Duration: 2 * time.Second}},
+			},
+		},
+		{SummaryRow: SummaryRow{Name: "docs", Status: StatusSkipped, Reason: "lint failed"}},
+	}
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONReport(&buf, reportFixture()); err != nil {
+		t.Fatalf("WriteJSONReport: %v", err)
+	}
+
+	var got jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("отчёт должен быть корректным JSON: %v\n%s", err, buf.String())
+	}
+
+	if got.Status != StatusFailed || got.DurationMS != 2000 || len(got.Chains) != 3 {
+		t.Fatalf("неверная шапка отчёта: %+v", got)
+	}
+
+	cmd := got.Chains[0].Commands[1]
+	if cmd.Name != "golangci" || cmd.Status != StatusFailed || cmd.Output != "main.go:1: <bad> & worse\n" {
+		t.Fatalf("неверная строка упавшей команды: %+v", cmd)
+	}
+}
+
+func TestWriteJUnitReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnitReport(&buf, reportFixture()); err != nil {
+		t.Fatalf("WriteJUnitReport: %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Fatalf("отчёт должен начинаться с XML-заголовка:\n%s", buf.String())
+	}
+
+	var got junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("отчёт должен быть корректным XML: %v\n%s", err, buf.String())
+	}
+
+	// lint: три команды; api: остановленная команда и отдельный тест на
+	// отказ самой цепочки; docs: не запускалась и представлена собой.
+	if got.Tests != 6 || got.Failures != 2 || got.Skipped != 3 {
+		t.Fatalf("неверные итоги: tests=%d failures=%d skipped=%d\n%s",
+			got.Tests, got.Failures, got.Skipped, buf.String())
+	}
+
+	failure := got.Suites[0].Cases[1].Failure
+	if failure == nil || failure.Text != "main.go:1: <bad> & worse\n" || failure.Message != "exit 2" {
+		t.Fatalf("текст отказа должен быть stderr команды: %+v", failure)
+	}
+
+	chainCase := got.Suites[1].Cases[1]
+	if chainCase.Name != "api" || chainCase.Failure == nil || chainCase.Failure.Message != "not ready" {
+		t.Fatalf("отказ цепочки без отказа команды должен стать отдельным тестом: %+v", chainCase)
+	}
+
+	if skipped := got.Suites[2].Cases[0].Skipped; skipped == nil || skipped.Message != "skipped: lint failed" {
+		t.Fatalf("незапущенная цепочка должна быть пропущенным тестом: %+v", got.Suites[2].Cases)
+	}
+}
+
+func TestWriteJUnitReport_Timeout(t *testing.T) {
+	chains := []ReportChain{{
+		SummaryRow: SummaryRow{Name: "e2e", Status: StatusTimedOut, Reason: "limit"},
+		Commands: []ReportCommand{
+			{SummaryRow: SummaryRow{Name: "run", Status: StatusTimedOut, Reason: "limit"}, Output: "waiting\n"},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteJUnitReport(&buf, chains); err != nil {
+		t.Fatalf("WriteJUnitReport: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `type="timeout"`) {
+		t.Fatalf("таймаут должен отличаться типом отказа:\n%s", buf.String())
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"sync"
 	"time"
@@ -94,6 +95,37 @@ func (s *Sink) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// WriteGroup пишет заголовок, тело и хвост подряд, не пуская между ними другие
+// записи: группа вывода цепочки должна дойти до читателя одним куском.
+//
+// Пока тело копируется, остальные пишущие ждут. Это и есть цена группировки:
+// они и так ждали бы, только их строки оказались бы внутри чужой группы.
+func (s *Sink) WriteGroup(head string, body io.Reader, tail string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var w io.Writer = s.buf
+	if s.closed {
+		w = s.out
+	}
+
+	s.dirty = true
+
+	if _, err := io.WriteString(w, head); err != nil {
+		return fmt.Errorf("writing output group: %w", err)
+	}
+
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("writing output group: %w", err)
+	}
+
+	if _, err := io.WriteString(w, tail); err != nil {
+		return fmt.Errorf("writing output group: %w", err)
+	}
+
+	return nil
+}
+
 // Flush немедленно сбрасывает накопленное.
 func (s *Sink) Flush() error {
 	s.mu.Lock()
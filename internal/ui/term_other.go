@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package ui
+
+import (
+	"errors"
+	"os"
+)
+
+// errNoTerminalControl — на этой платформе режим терминала не переключить.
+var errNoTerminalControl = errors.New("tui: terminal control is not supported on this platform")
+
+func enterCbreak(_, _ *os.File) (func() error, error) {
+	return nil, errNoTerminalControl
+}
+
+func terminalSize(_ *os.File) (int, int, error) {
+	return 0, 0, errNoTerminalControl
+}
@@ -0,0 +1,43 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// enterCbreak выключает построчный ввод и эхо терминала и возвращает функцию,
+// которая восстановит прежний режим. ISIG не трогается: Ctrl+C остаётся
+// сигналом.
+func enterCbreak(in, _ *os.File) (func() error, error) {
+	fd := int(in.Fd()) //nolint:gosec // дескриптор открытого файла помещается в int
+
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotTerminal, err)
+	}
+
+	cbreak := *old
+	cbreak.Lflag &^= unix.ICANON | unix.ECHO
+	cbreak.Cc[unix.VMIN], cbreak.Cc[unix.VTIME] = 1, 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &cbreak); err != nil {
+		return nil, fmt.Errorf("tui: %w", err)
+	}
+
+	return func() error { return unix.IoctlSetTermios(fd, ioctlSetTermios, old) }, nil
+}
+
+// terminalSize возвращает ширину и высоту терминала в символах.
+func terminalSize(out *os.File) (int, int, error) {
+	//nolint:gosec // дескриптор открытого файла помещается в int
+	ws, err := unix.IoctlGetWinsize(int(out.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0, fmt.Errorf("tui: %w", err)
+	}
+
+	return int(ws.Col), int(ws.Row), nil
+}
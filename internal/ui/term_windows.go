@@ -0,0 +1,53 @@
+//go:build windows
+
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enterCbreak выключает построчный ввод и эхо консоли и включает разбор
+// escape-последовательностей на выводе и их выдачу на вводе: стрелки тогда
+// приходят так же, как в терминалах Unix. ENABLE_PROCESSED_INPUT остаётся:
+// Ctrl+C по-прежнему сигнал.
+func enterCbreak(in, out *os.File) (func() error, error) {
+	inHandle, outHandle := windows.Handle(in.Fd()), windows.Handle(out.Fd())
+
+	var inMode, outMode uint32
+	if err := windows.GetConsoleMode(inHandle, &inMode); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotTerminal, err)
+	}
+
+	if err := windows.GetConsoleMode(outHandle, &outMode); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotTerminal, err)
+	}
+
+	cbreak := inMode&^(windows.ENABLE_LINE_INPUT|windows.ENABLE_ECHO_INPUT) | windows.ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := windows.SetConsoleMode(inHandle, cbreak); err != nil {
+		return nil, fmt.Errorf("tui: %w", err)
+	}
+
+	if err := windows.SetConsoleMode(outHandle, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		_ = windows.SetConsoleMode(inHandle, inMode)
+
+		return nil, fmt.Errorf("tui: %w", err)
+	}
+
+	return func() error {
+		return errors.Join(windows.SetConsoleMode(inHandle, inMode), windows.SetConsoleMode(outHandle, outMode))
+	}, nil
+}
+
+// terminalSize возвращает ширину и высоту видимой части консоли.
+func terminalSize(out *os.File) (int, int, error) {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(out.Fd()), &info); err != nil {
+		return 0, 0, fmt.Errorf("tui: %w", err)
+	}
+
+	return int(info.Window.Right-info.Window.Left) + 1, int(info.Window.Bottom-info.Window.Top) + 1, nil
+}
@@ -0,0 +1,9 @@
+package ui
+
+import "golang.org/x/sys/unix"
+
+// Запросы ioctl к режиму терминала называются на Linux и BSD по-разному.
+const (
+	ioctlGetTermios = unix.TCGETS
+	ioctlSetTermios = unix.TCSETS
+)
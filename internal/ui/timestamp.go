@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+// Форматы меток времени.
+const (
+	wallLayout    = "15:04:05.000"
+	rfc3339Layout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// Timestamp форматирует метку времени строки вывода: now — момент получения
+// строки, start — начало цепочки, от которого считается режим elapsed.
+// Пустая строка означает, что метка не ставится.
+func Timestamp(mode flow.TimestampMode, now, start time.Time) string {
+	switch mode {
+	case flow.TimestampWall:
+		return now.Format(wallLayout)
+	case flow.TimestampRFC3339:
+		return now.Format(rfc3339Layout)
+	case flow.TimestampElapsed:
+		return elapsed(now.Sub(start))
+	case flow.TimestampNone:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// elapsed печатает длительность с постоянной шириной — «+01:02.345», а с часа
+// работы «+1:01:02.345»: метки одной ширины выравнивают строки в столбец,
+// чего time.Duration.String с его «1m2.345s» не даёт.
+func elapsed(d time.Duration) string {
+	d = max(d, 0)
+
+	ms := d.Milliseconds()
+	hours, ms := ms/int64(time.Hour/time.Millisecond), ms%int64(time.Hour/time.Millisecond)
+	minutes, ms := ms/int64(time.Minute/time.Millisecond), ms%int64(time.Minute/time.Millisecond)
+	seconds, ms := ms/int64(time.Second/time.Millisecond), ms%int64(time.Second/time.Millisecond)
+
+	if hours > 0 {
+		return fmt.Sprintf("+%d:%02d:%02d.%03d", hours, minutes, seconds, ms)
+	}
+
+	return fmt.Sprintf("+%02d:%02d.%03d", minutes, seconds, ms)
+}
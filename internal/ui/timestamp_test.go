@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/efureev/parallel/internal/flow"
+)
+
+func TestTimestamp(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond)
+
+	tests := map[flow.TimestampMode]string{
+		"":                    "",
+		flow.TimestampNone:    "",
+		flow.TimestampWall:    "11:02:03.045",
+		flow.TimestampRFC3339: "2026-03-01T11:02:03.045Z",
+		flow.TimestampElapsed: "+1:02:03.045",
+	}
+
+	for mode, want := range tests {
+		if got := Timestamp(mode, now, start); got != want {
+			t.Errorf("%q: получено %q, ожидалось %q", mode, got, want)
+		}
+	}
+}
+
+// TestTimestamp_ElapsedWidth — до часа ширина метки постоянна, и строки
+// выравниваются в столбец.
+func TestTimestamp_ElapsedWidth(t *testing.T) {
+	start := time.Now()
+
+	for d, want := range map[time.Duration]string{
+		0:                                 "+00:00.000",
+		1500 * time.Millisecond:           "+00:01.500",
+		59*time.Minute + 59*time.Second:   "+59:59.000",
+		-time.Second:                      "+00:00.000",
+		10*time.Hour + 7*time.Millisecond: "+10:00:00.007",
+	} {
+		if got := Timestamp(flow.TimestampElapsed, start.Add(d), start); got != want {
+			t.Errorf("%s: получено %q, ожидалось %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotTerminal — полноэкранный вид запрошен, а ввод или вывод не терминал.
+var ErrNotTerminal = errors.New("full-screen ui needs a terminal on stdin and stdout")
+
+// Параметры полноэкранного вида.
+const (
+	// tuiHistory — сколько строк помнит панель. Болтливый сервис за ночь
+	// печатает миллионы строк, и держать их все ради прокрутки незачем:
+	// полная история — дело файла журнала цепочки.
+	tuiHistory = 5000
+
+	// tuiFrameInterval — как часто экран перерисовывается, если что-то
+	// изменилось. Десять кадров в секунду глаз не отличит от живого потока,
+	// а поток в сотни тысяч строк не превращается в сотни тысяч перерисовок.
+	tuiFrameInterval = 100 * time.Millisecond
+
+	// tuiClockInterval — как часто экран перерисовывается без изменений:
+	// время работы в строке статуса идёт и тогда, когда цепочка молчит.
+	tuiClockInterval = time.Second
+
+	// SystemPane — имя панели собственных сообщений parallel.
+	SystemPane = "parallel"
+)
+
+// PaneState — состояние цепочки в строке статуса полноэкранного вида.
+type PaneState string
+
+// Состояния панели.
+const (
+	PaneWaiting    PaneState = "waiting"
+	PaneRunning    PaneState = "running"
+	PaneReady      PaneState = "ready"
+	PaneRestarting PaneState = "restarting"
+	PaneDone       PaneState = "done"
+	PaneFailed     PaneState = "failed"
+	PaneStopped    PaneState = "stopped"
+	PaneSkipped    PaneState = "skipped"
+)
+
+// PaneStatus — то, что строка статуса говорит о цепочке.
+type PaneStatus struct {
+	State PaneState
+	// PID — процесс текущей команды; ноль — процесса сейчас нет.
+	PID int
+	// Since — начало текущего запуска цепочки: от него считается время работы.
+	Since time.Time
+	// Restarts — сколько раз команды цепочки перезапускались.
+	Restarts int
+}
+
+// PaneSpec описывает панель цепочки при запуске вида.
+type PaneSpec struct {
+	Name string
+	// Needs — предшественники: пока цепочка ждёт, строка статуса называет их.
+	Needs []string
+}
+
+// paneLine — строка панели. Поток помнится, чтобы stderr выделить цветом.
+type paneLine struct {
+	text   string
+	stderr bool
+	// marker — служебная строка: смена команды внутри цепочки.
+	marker bool
+}
+
+// pane — вкладка одной цепочки.
+type pane struct {
+	spec   PaneSpec
+	status PaneStatus
+	lines  []paneLine
+	// offset — на сколько строк панель прокручена вверх; ноль — панель
+	// следует за выводом.
+	offset int
+	// command — команда последней строки: её смена отмечается разделителем.
+	command string
+}
+
+func (p *pane) push(line paneLine) {
+	p.lines = append(p.lines, line)
+
+	// Прокрученная панель стоит на месте, пока вывод идёт ниже.
+	if p.offset > 0 {
+		p.offset++
+	}
+
+	// История обрезается пачкой, а не по строке: копировать пять тысяч строк
+	// на каждую новую значило бы платить за прокрутку на горячем пути.
+	if len(p.lines) >= tuiHistory+tuiHistory/4 {
+		p.lines = append([]paneLine(nil), p.lines[len(p.lines)-tuiHistory:]...)
+		p.offset = min(p.offset, len(p.lines))
+	}
+}
+
+// TUI — полноэкранный вид: вкладка на цепочку, строка статуса и подсказка.
+//
+// TUI — это Logger: runner отдаёт ему разобранные строки через LineLogger,
+// как JSON-логгеру, и раскладывать их по панелям не приходится. Собственные
+// сообщения parallel попадают на отдельную вкладку. После Close записи идут
+// в обычный логгер: сводка и опоздавшие сообщения печатаются как всегда.
+type TUI struct {
+	fallback Logger
+	level    Level
+	colored  bool
+	quit     func()
+
+	screen io.Writer
+	size   func() (int, int, error)
+
+	mu      sync.Mutex
+	panes   []*pane
+	active  int
+	dirty   bool
+	closed  bool
+	drawn   time.Time
+	restore func() error
+
+	closeOnce sync.Once
+	closeErr  error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// TUIOption донастраивает полноэкранный вид.
+type TUIOption func(*TUI)
+
+// WithTUILevel задаёт уровень сообщений на вкладке parallel.
+func WithTUILevel(l Level) TUIOption {
+	return func(t *TUI) { t.level = l }
+}
+
+// WithTUIQuit задаёт реакцию на `q`: обычно ту же отмену, что и по Ctrl+C.
+func WithTUIQuit(fn func()) TUIOption {
+	return func(t *TUI) { t.quit = fn }
+}
+
+// WithTUINoColor отключает цвета, если noColor; иначе решают NO_COLOR и
+// FORCE_COLOR, как для обычного вывода. Инверсия активной вкладки остаётся
+// и без цвета — без неё не видно, где находишься.
+func WithTUINoColor(noColor bool) TUIOption {
+	return func(t *TUI) { t.colored = t.colored && !noColor }
+}
+
+// NewTUI готовит вид с панелями цепочек; экран занимает Start.
+func NewTUI(specs []PaneSpec, fallback Logger, opts ...TUIOption) *TUI {
+	t := &TUI{
+		fallback: fallback,
+		level:    LevelInfo,
+		colored:  colorEnabled(os.Stdout, false),
+		screen:   os.Stdout,
+		size:     func() (int, int, error) { return terminalSize(os.Stdout) },
+		panes:    []*pane{{spec: PaneSpec{Name: SystemPane}, status: PaneStatus{State: PaneRunning}}},
+		dirty:    true,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, spec := range specs {
+		t.panes = append(t.panes, &pane{spec: spec, status: PaneStatus{State: PaneWaiting}})
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Start переводит терминал в посимвольный режим, занимает альтернативный
+// экран и начинает перерисовку и чтение клавиш.
+//
+// Посимвольный, а не сырой режим: Ctrl+C остаётся сигналом, и лестница
+// завершения работает так же, как без полноэкранного вида.
+func (t *TUI) Start() error {
+	if !isTerminal(t.screen) {
+		return ErrNotTerminal
+	}
+
+	restore, err := enterCbreak(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	t.restore = restore
+
+	// Альтернативный экран и скрытый курсор: по выходе терминал
+	// возвращается к тому, что было на нём до запуска.
+	_, _ = io.WriteString(t.screen, "\x1b[?1049h\x1b[?25l")
+
+	go t.drawLoop()
+	go t.readKeys(os.Stdin)
+
+	return nil
+}
+
+// Close освобождает экран и возвращает терминал в прежний режим.
+//
+// Повторный и одновременный вызовы ждут первого: сводка, напечатанная до
+// выхода с альтернативного экрана, пропала бы вместе с ним. Nil-безопасен:
+// вызывающему не нужно помнить, был ли вид запрошен.
+func (t *TUI) Close() error {
+	if t == nil {
+		return nil
+	}
+
+	t.closeOnce.Do(func() { t.closeErr = t.release() })
+
+	return t.closeErr
+}
+
+func (t *TUI) release() error {
+	var err error
+
+	if t.restore != nil {
+		close(t.stop)
+		<-t.done
+
+		_, _ = io.WriteString(t.screen, "\x1b[?25h\x1b[?1049l")
+
+		if restoreErr := t.restore(); restoreErr != nil {
+			err = fmt.Errorf("tui: restoring terminal: %w", restoreErr)
+		}
+	}
+
+	// Записи переключаются на обычный логгер только теперь, когда экран
+	// освобождён: раньше они легли бы на альтернативный и пропали с ним.
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	return err
+}
+
+// Update меняет статус панели цепочки. Незнакомая цепочка получает новую
+// панель: перечитанная конфигурация могла её добавить.
+func (t *TUI) Update(chain string, fn func(*PaneStatus)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn(&t.paneOf(chain).status)
+	t.dirty = true
+}
+
+// Status возвращает статус панели цепочки.
+func (t *TUI) Status(chain string) (PaneStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range t.panes {
+		if p.spec.Name == chain {
+			return p.status, true
+		}
+	}
+
+	return PaneStatus{}, false
+}
+
+// Line кладёт строку вывода команды на панель её цепочки.
+func (t *TUI) Line(line OutputLine) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		t.fallback.Blocks(line.Chain, line.Command, line.Line)
+
+		return
+	}
+
+	p := t.paneOf(line.Chain)
+	if line.Command != p.command {
+		p.command = line.Command
+		p.push(paneLine{text: "── " + line.Command, marker: true})
+	}
+
+	p.push(paneLine{text: sanitize(line.Line), stderr: line.Stream == StreamStderr})
+	t.dirty = true
+}
+
+func (t *TUI) Debug(msg string, fields ...Field) { t.log(LevelDebug, msg, nil, fields) }
+
+func (t *TUI) Info(msg string, fields ...Field) { t.log(LevelInfo, msg, nil, fields) }
+
+func (t *TUI) Warn(msg string, fields ...Field) { t.log(LevelWarn, msg, nil, fields) }
+
+func (t *TUI) Error(err error, msg string, fields ...Field) { t.log(LevelError, msg, err, fields) }
+
+// Blocks и ErrorBlocks до вывода команд не доходят — его runner отдаёт через
+// Line. Остаются редкие сообщения, которые ложатся на вкладку parallel.
+func (t *TUI) Blocks(blocks ...string) { t.log(LevelInfo, joinBlocks(blocks), nil, nil) }
+
+func (t *TUI) ErrorBlocks(err error, blocks ...string) {
+	t.log(LevelError, joinBlocks(blocks), err, nil)
+}
+
+// log пишет сообщение на вкладку parallel строкой «уровень сообщение поля».
+func (t *TUI) log(level Level, msg string, err error, fields []Field) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		t.forward(level, msg, err, fields)
+
+		return
+	}
+
+	if level < t.level {
+		return
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%-5s %s", strings.ToUpper(level.String()), msg)
+
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Val)
+	}
+
+	if err != nil {
+		fmt.Fprintf(&b, " error=%q", err.Error())
+	}
+
+	t.panes[0].push(paneLine{text: sanitize(b.String()), stderr: level >= LevelWarn})
+	t.dirty = true
+}
+
+// forward отдаёт сообщение обычному логгеру, когда экран уже освобождён.
+func (t *TUI) forward(level Level, msg string, err error, fields []Field) {
+	switch level {
+	case LevelDebug:
+		t.fallback.Debug(msg, fields...)
+	case LevelWarn:
+		t.fallback.Warn(msg, fields...)
+	case LevelError:
+		t.fallback.Error(err, msg, fields...)
+	case LevelInfo:
+		t.fallback.Info(msg, fields...)
+	default:
+		t.fallback.Info(msg, fields...)
+	}
+}
+
+// paneOf находит панель цепочки, заводя её при необходимости.
+func (t *TUI) paneOf(chain string) *pane {
+	for _, p := range t.panes {
+		if p.spec.Name == chain {
+			return p
+		}
+	}
+
+	p := &pane{spec: PaneSpec{Name: chain}, status: PaneStatus{State: PaneWaiting}}
+	t.panes = append(t.panes, p)
+
+	return p
+}
+
+// drawLoop перерисовывает экран, пока вид не закрыт.
+func (t *TUI) drawLoop() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(tuiFrameInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-ticker.C:
+			t.draw(now)
+		}
+	}
+}
+
+// draw выводит кадр, если что-то изменилось или пора сдвинуть часы.
+func (t *TUI) draw(now time.Time) {
+	width, height, err := t.size()
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+
+	if !t.dirty && now.Sub(t.drawn) < tuiClockInterval {
+		t.mu.Unlock()
+
+		return
+	}
+
+	frame := t.frame(width, height, now)
+	t.dirty, t.drawn = false, now
+	t.mu.Unlock()
+
+	_, _ = t.screen.Write(frame)
+}
+
+// sanitize готовит строку к панели: escape-последовательности и управляющие
+// символы вырезаются — чужой цвет или перевод курсора сломали бы раскладку
+// экрана, — табуляция разворачивается в пробелы.
+func sanitize(s string) string {
+	var b strings.Builder
+
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\x1b':
+			i = skipEscape(s, i)
+		case c == '\t':
+			b.WriteString("    ")
+		case c < ' ' || c == '\x7f':
+			continue
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// skipEscape возвращает индекс последнего байта escape-последовательности,
+// начатой в позиции i: CSI (`ESC [ … буква`), OSC (`ESC ] … BEL`), SS3
+// (`ESC O буква` — так часть терминалов шлёт стрелки) или двухбайтовой.
+func skipEscape(s string, i int) int {
+	if i+1 >= len(s) {
+		return i
+	}
+
+	switch s[i+1] {
+	case 'O':
+		return min(i+2, len(s)-1)
+	case '[':
+		for j := i + 2; j < len(s); j++ {
+			if s[j] >= '@' && s[j] <= '~' {
+				return j
+			}
+		}
+	case ']':
+		for j := i + 2; j < len(s); j++ {
+			if s[j] == '\a' {
+				return j
+			}
+
+			if s[j] == '\x1b' && j+1 < len(s) && s[j+1] == '\\' {
+				return j + 1
+			}
+		}
+	default:
+		return i + 1
+	}
+
+	return len(s) - 1
+}
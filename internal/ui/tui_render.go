@@ -0,0 +1,398 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// tuiHelp — подсказка в нижней строке экрана.
+const tuiHelp = "←/→ tab · 0-9 jump · ↑/↓ PgUp/PgDn scroll · End follow · q quit"
+
+// tuiChrome — строки экрана, не занятые выводом: вкладки, статус и подсказка.
+const tuiChrome = 3
+
+// ANSI-последовательности кадра.
+const (
+	ansiHome     = "\x1b[H"
+	ansiClearEOL = "\x1b[K"
+	ansiReset    = "\x1b[0m"
+	ansiInverse  = "\x1b[7m"
+	ansiDim      = "\x1b[2m"
+	ansiRed      = "\x1b[31m"
+	ansiGreen    = "\x1b[32m"
+	ansiYellow   = "\x1b[33m"
+)
+
+// frame собирает кадр целиком. Вызывается под t.mu.
+//
+// Кадр перерисовывает каждую строку экрана поверх прежней, а не очищает
+// экран: очистка между кадрами мигала бы на каждом обновлении.
+func (t *TUI) frame(width, height int, now time.Time) []byte {
+	var b bytes.Buffer
+
+	b.WriteString(ansiHome)
+
+	rows := make([]string, 0, height)
+	rows = append(rows, t.tabs(width))
+
+	body := max(height-tuiChrome, 0)
+	rows = append(rows, t.body(width, body)...)
+	rows = append(rows,
+		t.paint(ansiInverse, fit(t.statusLine(now), width, true)),
+		t.paint(ansiDim, fit(tuiHelp, width, false)))
+
+	if len(rows) > height {
+		rows = rows[len(rows)-height:]
+	}
+
+	for i, row := range rows {
+		b.WriteString(row)
+		b.WriteString(ansiClearEOL)
+
+		// Последняя строка без перевода: иначе терминал прокрутит экран.
+		if i < len(rows)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+
+	return b.Bytes()
+}
+
+// tabs — строка вкладок: номер, имя и цвет состояния; активная инвертирована.
+func (t *TUI) tabs(width int) string {
+	var (
+		b    strings.Builder
+		used int
+	)
+
+	for i, p := range t.panes {
+		label := " " + p.spec.Name + " "
+		if i <= 9 { //nolint:mnd // на цифровые клавиши ложатся первые десять вкладок
+			label = " " + strconv.Itoa(i) + label
+		}
+
+		n := utf8.RuneCountInString(label)
+		if used+n > width {
+			break
+		}
+
+		used += n
+
+		switch {
+		case i == t.active:
+			b.WriteString(ansiInverse + label + ansiReset)
+		case t.colored:
+			b.WriteString(stateColor(p.status.State) + label + ansiReset)
+		default:
+			b.WriteString(label)
+		}
+	}
+
+	return b.String()
+}
+
+// body — видимые строки активной панели с учётом прокрутки.
+func (t *TUI) body(width, height int) []string {
+	p := t.panes[t.active]
+
+	end := len(p.lines) - min(p.offset, len(p.lines))
+	start := max(end-height, 0)
+
+	rows := make([]string, 0, height)
+
+	for _, line := range p.lines[start:end] {
+		text := fit(line.text, width, false)
+
+		switch {
+		case line.marker:
+			rows = append(rows, t.paint(ansiDim, text))
+		case line.stderr:
+			rows = append(rows, t.paint(ansiRed, text))
+		default:
+			rows = append(rows, text)
+		}
+	}
+
+	for len(rows) < height {
+		rows = append(rows, "")
+	}
+
+	return rows
+}
+
+// statusLine описывает активную панель.
+func (t *TUI) statusLine(now time.Time) string {
+	p := t.panes[t.active]
+
+	var parts []string
+	if t.active == 0 {
+		parts = append([]string{p.spec.Name}, t.counts()...)
+	} else {
+		parts = append([]string{p.spec.Name}, describeStatus(p, now)...)
+	}
+
+	if p.offset > 0 {
+		parts = append(parts, fmt.Sprintf("scrolled %d up", p.offset))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// describeStatus — состояние, процесс, время работы и перезапуски цепочки.
+func describeStatus(p *pane, now time.Time) []string {
+	s := p.status
+
+	if s.State == PaneWaiting {
+		if len(p.spec.Needs) > 0 {
+			return []string{"waiting on " + strings.Join(p.spec.Needs, ", ")}
+		}
+
+		return []string{string(s.State)}
+	}
+
+	parts := []string{string(s.State)}
+
+	if s.PID > 0 {
+		parts = append(parts, "pid "+strconv.Itoa(s.PID))
+	}
+
+	if live(s.State) && !s.Since.IsZero() {
+		parts = append(parts, "up "+now.Sub(s.Since).Truncate(time.Second).String())
+	}
+
+	if s.Restarts > 0 {
+		parts = append(parts, "restarts "+strconv.Itoa(s.Restarts))
+	}
+
+	return parts
+}
+
+// counts — сводка по цепочкам для вкладки parallel: «2 running · 1 failed».
+func (t *TUI) counts() []string {
+	order := []PaneState{
+		PaneWaiting, PaneRunning, PaneReady, PaneRestarting,
+		PaneDone, PaneFailed, PaneStopped, PaneSkipped,
+	}
+
+	byState := make(map[PaneState]int, len(order))
+	for _, p := range t.panes[1:] {
+		byState[p.status.State]++
+	}
+
+	parts := make([]string, 0, len(order))
+
+	for _, state := range order {
+		if n := byState[state]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, state))
+		}
+	}
+
+	return parts
+}
+
+// live сообщает, идёт ли у цепочки запуск, время которого стоит показывать.
+func live(state PaneState) bool {
+	switch state {
+	case PaneRunning, PaneReady, PaneRestarting:
+		return true
+	case PaneWaiting, PaneDone, PaneFailed, PaneStopped, PaneSkipped:
+		return false
+	default:
+		return false
+	}
+}
+
+func stateColor(state PaneState) string {
+	switch state {
+	case PaneReady, PaneDone:
+		return ansiGreen
+	case PaneRunning, PaneRestarting:
+		return ansiYellow
+	case PaneFailed:
+		return ansiRed
+	case PaneWaiting, PaneStopped, PaneSkipped:
+		return ansiDim
+	default:
+		return ""
+	}
+}
+
+// paint окрашивает строку; инверсия остаётся и без цвета.
+func (t *TUI) paint(style, s string) string {
+	if !t.colored && style != ansiInverse {
+		return s
+	}
+
+	return style + s + ansiReset
+}
+
+// fit обрезает строку по ширине экрана в рунах; pad добивает её пробелами,
+// чтобы инверсная строка статуса тянулась на всю ширину.
+func fit(s string, width int, pad bool) string {
+	n := utf8.RuneCountInString(s)
+
+	if n > width {
+		runes := []rune(s)
+
+		return string(runes[:max(width, 0)])
+	}
+
+	if pad {
+		return s + strings.Repeat(" ", width-n)
+	}
+
+	return s
+}
+
+// Клавиши, которые понимает вид.
+const (
+	keyLeft     = "left"
+	keyRight    = "right"
+	keyUp       = "up"
+	keyDown     = "down"
+	keyPageUp   = "pgup"
+	keyPageDown = "pgdn"
+	keyHome     = "home"
+	keyEnd      = "end"
+	keyQuit     = "quit"
+)
+
+// keySequences — escape-последовательности клавиш. Терминалы шлют одну и ту
+// же клавишу по-разному, поэтому у End и Home по несколько записей.
+//
+//nolint:gochecknoglobals // неизменяемая таблица, константой объявить нельзя
+var keySequences = map[string]string{
+	"\x1b[A": keyUp, "\x1b[B": keyDown, "\x1b[C": keyRight, "\x1b[D": keyLeft,
+	"\x1bOA": keyUp, "\x1bOB": keyDown, "\x1bOC": keyRight, "\x1bOD": keyLeft,
+	"\x1b[5~": keyPageUp, "\x1b[6~": keyPageDown,
+	"\x1b[H": keyHome, "\x1b[1~": keyHome, "\x1bOH": keyHome,
+	"\x1b[F": keyEnd, "\x1b[4~": keyEnd, "\x1bOF": keyEnd,
+}
+
+// keyLetters — одиночные символы: стрелки в духе vi и Tab.
+//
+//nolint:gochecknoglobals // неизменяемая таблица, константой объявить нельзя
+var keyLetters = map[byte]string{
+	'\t': keyRight, 'l': keyRight, 'h': keyLeft,
+	'k': keyUp, 'j': keyDown, 'b': keyPageUp, ' ': keyPageDown,
+	'g': keyHome, 'G': keyEnd, 'q': keyQuit,
+}
+
+// decodeKeys переводит прочитанные байты в клавиши. Цифра остаётся самой
+// собой: это номер вкладки. Незнакомое пропускается.
+func decodeKeys(buf []byte) []string {
+	var keys []string
+
+	for i := 0; i < len(buf); i++ {
+		if buf[i] == '\x1b' {
+			end := skipEscape(string(buf), i)
+			if key, ok := keySequences[string(buf[i:end+1])]; ok {
+				keys = append(keys, key)
+			}
+
+			i = end
+
+			continue
+		}
+
+		if buf[i] >= '0' && buf[i] <= '9' {
+			keys = append(keys, string(buf[i]))
+
+			continue
+		}
+
+		if key, ok := keyLetters[buf[i]]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// readKeys читает клавиши, пока вид не закрыт.
+//
+// Чтение из терминала не прерывается, поэтому горутина остаётся на нём до
+// выхода процесса — как и у построчного клавиатурного режима.
+func (t *TUI) readKeys(in interface{ Read([]byte) (int, error) }) {
+	buf := make([]byte, 64) //nolint:mnd // одна клавиша — несколько байт
+
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for _, key := range decodeKeys(buf[:n]) {
+			if !t.press(key) {
+				return
+			}
+		}
+	}
+}
+
+// press исполняет клавишу; false — вид закрыт, читать дальше незачем.
+func (t *TUI) press(key string) bool {
+	t.mu.Lock()
+
+	if t.closed {
+		t.mu.Unlock()
+
+		return false
+	}
+
+	if key == keyQuit {
+		t.mu.Unlock()
+
+		if t.quit != nil {
+			t.quit()
+		}
+
+		return true
+	}
+
+	t.navigate(key)
+	t.dirty = true
+	t.mu.Unlock()
+
+	return true
+}
+
+// navigate переключает вкладки и прокручивает панель. Вызывается под t.mu.
+func (t *TUI) navigate(key string) {
+	p := t.panes[t.active]
+
+	_, height, err := t.size()
+	if err != nil {
+		height = 24 //nolint:mnd // высота терминала по умолчанию
+	}
+
+	page := max(height-tuiChrome-1, 1)
+	top := max(len(p.lines)-(height-tuiChrome), 0)
+
+	switch key {
+	case keyLeft:
+		t.active = (t.active + len(t.panes) - 1) % len(t.panes)
+	case keyRight:
+		t.active = (t.active + 1) % len(t.panes)
+	case keyUp:
+		p.offset = min(p.offset+1, top)
+	case keyDown:
+		p.offset = max(p.offset-1, 0)
+	case keyPageUp:
+		p.offset = min(p.offset+page, top)
+	case keyPageDown:
+		p.offset = max(p.offset-page, 0)
+	case keyHome:
+		p.offset = top
+	case keyEnd:
+		p.offset = 0
+	default:
+		if n, err := strconv.Atoi(key); err == nil && n < len(t.panes) {
+			t.active = n
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTUI — вид с экраном заданного размера, без терминала и цвета.
+func newTestTUI(width, height int, specs ...PaneSpec) *TUI {
+	t := NewTUI(specs, NewDiscardLogger(), WithTUINoColor(true))
+	t.size = func() (int, int, error) { return width, height, nil }
+
+	return t
+}
+
+// TestTUI_StatusLine — строка статуса называет состояние, процесс, время
+// работы и перезапуски, а ждущая цепочка — тех, кого ждёт.
+func TestTUI_StatusLine(t *testing.T) {
+	view := newTestTUI(100, 8, PaneSpec{Name: "api", Needs: []string{"db"}}, PaneSpec{Name: "db"})
+	now := time.Now()
+
+	view.Update("db", func(s *PaneStatus) {
+		*s = PaneStatus{State: PaneRunning, PID: 42, Since: now.Add(-65 * time.Second), Restarts: 2}
+	})
+	view.Line(OutputLine{Chain: "db", Command: "serve", Stream: StreamStdout, Line: "listening on :5432"})
+
+	view.press("2")
+
+	frame := string(view.frame(100, 8, now))
+	for _, want := range []string{"db · running · pid 42 · up 1m5s · restarts 2", "── serve", "listening on :5432"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("в кадре нет %q:\n%s", want, frame)
+		}
+	}
+
+	view.press(keyLeft)
+
+	if frame := string(view.frame(100, 8, now)); !strings.Contains(frame, "api · waiting on db") {
+		t.Fatalf("ждущая цепочка должна называть предшественников:\n%s", frame)
+	}
+}
+
+// TestTUI_ScrollHoldsPosition — прокрученная панель стоит на месте, пока
+// приходят новые строки, а End возвращает её к хвосту.
+func TestTUI_ScrollHoldsPosition(t *testing.T) {
+	view := newTestTUI(80, 8, PaneSpec{Name: "api"})
+	view.press("1")
+
+	for i := range 20 {
+		view.Line(OutputLine{Chain: "api", Command: "serve", Line: strings.Repeat("x", i)})
+	}
+
+	view.press(keyUp)
+	view.Line(OutputLine{Chain: "api", Command: "serve", Line: "late"})
+
+	frame := string(view.frame(80, 8, time.Now()))
+	if strings.Contains(frame, "late") || !strings.Contains(frame, "scrolled 2 up") {
+		t.Fatalf("прокрученная панель не должна уезжать за выводом:\n%s", frame)
+	}
+
+	view.press(keyEnd)
+
+	if frame := string(view.frame(80, 8, time.Now())); !strings.Contains(frame, "late") {
+		t.Fatalf("End должен вернуть панель к хвосту:\n%s", frame)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("\x1b[A\x1bOB\tq3\x1b[6~x\x1b[F"))
+	want := []string{keyUp, keyDown, keyRight, keyQuit, "3", keyPageDown, keyEnd}
+
+	if !slices.Equal(got, want) {
+		t.Fatalf("получено %q, ожидалось %q", got, want)
+	}
+}
+
+// TestSanitize — чужие цвета и управляющие символы не ломают раскладку.
+func TestSanitize(t *testing.T) {
+	got := sanitize("\x1b[31mred\x1b[0m\tx\r\x1b]0;title\a!")
+	if got != "red    x!" {
+		t.Fatalf("получено %q", got)
+	}
+}
+
+// TestTUI_ForwardsAfterClose — до Close сообщения ложатся на вкладку
+// parallel, после — в обычный логгер, где их увидит пользователь.
+func TestTUI_ForwardsAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+
+	view := NewTUI(nil, NewLogger(&buf, WithOutputMode(OutputJSON)))
+	view.size = func() (int, int, error) { return 80, 6, nil }
+
+	view.Warn("Chain log is not writable", F("path", "/x"))
+
+	if buf.Len() != 0 {
+		t.Fatalf("до Close обычный логгер должен молчать: %s", buf.String())
+	}
+
+	want := "WARN  Chain log is not writable path=/x"
+	if frame := string(view.frame(80, 6, time.Now())); !strings.Contains(frame, want) {
+		t.Fatalf("сообщения нет на вкладке parallel:\n%s", frame)
+	}
+
+	if err := view.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	view.Error(errors.New("boom"), "Failed to run parallel execution")
+
+	if !strings.Contains(buf.String(), "Failed to run parallel execution") {
+		t.Fatalf("после Close сообщение должно уйти в обычный логгер: %s", buf.String())
+	}
+}